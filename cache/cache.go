@@ -3,19 +3,54 @@ package cache
 import (
 	"context"
 	"time"
+
+	"socialite/database"
 )
 
 type Cache interface {
 	PutUserOnline(ctx context.Context, userName string) error
 	IsUserOnline(ctx context.Context, userName string) (bool, error)
 
+	// PutUserActive remembers the user was found in the database and not deleted, for UserActiveExpiry
+	PutUserActive(ctx context.Context, userName string) error
+	IsUserActive(ctx context.Context, userName string) (bool, error)
+	// EvictUser drops the presence, active flag, friends list, suggestions and mutual friends kept for the user
+	EvictUser(ctx context.Context, userName string, friends []string) error
+
 	PutUserFriendsList(ctx context.Context, userName string, friends []string) error
 	GetUserFriendsList(ctx context.Context, userName string) ([]string, error)
 
 	PutPartyMembersList(ctx context.Context, partyName string, members []string) error
 	GetPartyMembersList(ctx context.Context, partyName string) ([]string, error)
+	DeletePartyMembersList(ctx context.Context, partyName string) error
+
+	PutMutualFriends(ctx context.Context, userName, otherName string, mutualFriends []*database.User) error
+	GetMutualFriends(ctx context.Context, userName, otherName string) ([]*database.User, error)
+
+	PutFriendSuggestions(ctx context.Context, userName string, suggestions []*database.FriendSuggestion) error
+	GetFriendSuggestions(ctx context.Context, userName string) ([]*database.FriendSuggestion, error)
+
+	// PutUserEvent numbers the event with the user's next sequence number and keeps it for replay
+	PutUserEvent(ctx context.Context, userName string, event *UserEvent) error
+	// GetUserEventsAfter returns the kept events numbered after seq, complete is false when some of them are gone
+	GetUserEventsAfter(ctx context.Context, userName string, seq int64) (events []*UserEvent, complete bool, err error)
+
+	// Close releases the cache once the server is done with it
+	Close()
+}
+
+// UserEvent is a status message pushed to a user, the message is kept as given
+// so the sequence number can be added to it when it is sent
+type UserEvent struct {
+	Seq       int64
+	Type      string
+	Message   any
+	CreatedAt time.Time
 }
 
 const (
-	UserOnlineExpiry = time.Second * 10
+	UserOnlineExpiry        = time.Second * 10
+	UserActiveExpiry        = time.Second * 10
+	MutualFriendsExpiry     = time.Minute * 5
+	FriendSuggestionsExpiry = time.Minute * 5
 )
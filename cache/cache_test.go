@@ -3,6 +3,7 @@ package cache_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"socialite/cache"
 	"socialite/cache/state"
@@ -11,11 +12,15 @@ import (
 
 var cacheConn cache.Cache
 
+const userEventsBufferSize = 100
+
 func init() {
 	cacheConn = state.New(
 		context.Background(),
 		&config.CacheConfig{
-			Type: "state",
+			Type:                 "state",
+			UserEventsExpiry:     300,
+			UserEventsBufferSize: userEventsBufferSize,
 		},
 	)
 }
@@ -25,3 +30,60 @@ func TestNew(t *testing.T) {
 		t.Error("cacheConn is nil")
 	}
 }
+
+func TestUserActive(t *testing.T) {
+	err := cacheConn.PutUserActive(context.Background(), "user1")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	// ristretto applies sets asynchronously
+	time.Sleep(time.Millisecond * 10)
+	isActive, err := cacheConn.IsUserActive(context.Background(), "user1")
+	if err != nil || !isActive {
+		t.Error("active user is not active")
+	}
+
+	err = cacheConn.EvictUser(context.Background(), "user1", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	isActive, err = cacheConn.IsUserActive(context.Background(), "user1")
+	if err != nil || isActive {
+		t.Error("user is still active")
+	}
+}
+
+func TestUserEvents(t *testing.T) {
+	for i := 0; i < userEventsBufferSize+2; i++ {
+		err := cacheConn.PutUserEvent(context.Background(), "user2", &cache.UserEvent{Type: "notification"})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	events, complete, err := cacheConn.GetUserEventsAfter(context.Background(), "user2", userEventsBufferSize)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !complete || len(events) != 2 || events[0].Seq != userEventsBufferSize+1 {
+		t.Errorf("got %d events, complete %t, expected the last 2", len(events), complete)
+	}
+
+	// the first two events were dropped from the buffer
+	_, complete, _ = cacheConn.GetUserEventsAfter(context.Background(), "user2", 1)
+	if complete {
+		t.Error("events after a dropped one are complete")
+	}
+	_, complete, _ = cacheConn.GetUserEventsAfter(context.Background(), "user2", userEventsBufferSize+10)
+	if complete {
+		t.Error("events after an unknown sequence number are complete")
+	}
+	_, complete, _ = cacheConn.GetUserEventsAfter(context.Background(), "user3", 0)
+	if !complete {
+		t.Error("events of a new user are incomplete")
+	}
+}
@@ -11,6 +11,11 @@ func (c *Client) PutPartyMembersList(ctx context.Context, partyName string, memb
 	return nil
 }
 
+func (c *Client) DeletePartyMembersList(ctx context.Context, partyName string) error {
+	c.cache.Del(PatyMembersKey(partyName))
+	return nil
+}
+
 func (c *Client) GetPartyMembersList(ctx context.Context, partyName string) ([]string, error) {
 	membersList, found := c.cache.Get(PatyMembersKey(partyName))
 	if found || membersList != nil {
@@ -4,6 +4,8 @@ import (
 	"context"
 	"log"
 	"strings"
+	"sync"
+	"time"
 
 	"socialite/cache"
 	"socialite/config"
@@ -13,6 +15,12 @@ import (
 
 type Client struct {
 	cache *ristretto.Cache
+
+	userEventsMutex      sync.Mutex
+	userEvents           map[string]*userEventBuffer
+	userEventsSweptAt    time.Time
+	userEventsExpiry     time.Duration
+	userEventsBufferSize int
 }
 
 func New(ctx context.Context, cfg *config.CacheConfig) cache.Cache {
@@ -28,20 +36,45 @@ func New(ctx context.Context, cfg *config.CacheConfig) cache.Cache {
 			NumCounters: 1e5,
 			MaxCost:     104_857_600,
 			BufferItems: 64,
+			Metrics:     true,
 		},
 	)
 	if err != nil {
 		log.Fatal("[ERROR] creating ristretto cache : ", err.Error())
 	}
 	return &Client{
-		cache: cache,
+		cache:                cache,
+		userEvents:           make(map[string]*userEventBuffer),
+		userEventsExpiry:     time.Second * time.Duration(cfg.UserEventsExpiry),
+		userEventsBufferSize: cfg.UserEventsBufferSize,
 	}
 }
 
+func UserActiveKey(username string) string {
+	return "user_active:" + username
+}
+
 func UserFriendsListKey(username string) string {
 	return "user_friends:" + username
 }
 
+func MutualFriendsKey(username, otherName string) string {
+	return "mutual_friends:" + username + ":" + otherName
+}
+
+func FriendSuggestionsKey(username string) string {
+	return "friend_suggestions:" + username
+}
+
 func PatyMembersKey(username string) string {
 	return "party_members:" + username
 }
+
+// Stats returns the lookups of the cache that found a value and the ones that did not
+func (c *Client) Stats() (hits, misses uint64) {
+	return c.cache.Metrics.Hits(), c.cache.Metrics.Misses()
+}
+
+func (c *Client) Close() {
+	c.cache.Close()
+}
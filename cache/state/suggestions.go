@@ -0,0 +1,42 @@
+package state
+
+import (
+	"context"
+
+	"socialite/cache"
+	"socialite/database"
+)
+
+func (c *Client) PutMutualFriends(ctx context.Context, userName, otherName string, mutualFriends []*database.User) error {
+	c.cache.SetWithTTL(
+		MutualFriendsKey(userName, otherName),
+		mutualFriends,
+		1,
+		cache.MutualFriendsExpiry,
+	)
+	return nil
+}
+
+func (c *Client) GetMutualFriends(ctx context.Context, userName, otherName string) ([]*database.User, error) {
+	if mutualFriends, ok := c.cache.Get(MutualFriendsKey(userName, otherName)); ok {
+		return mutualFriends.([]*database.User), nil
+	}
+	return nil, nil
+}
+
+func (c *Client) PutFriendSuggestions(ctx context.Context, userName string, suggestions []*database.FriendSuggestion) error {
+	c.cache.SetWithTTL(
+		FriendSuggestionsKey(userName),
+		suggestions,
+		1,
+		cache.FriendSuggestionsExpiry,
+	)
+	return nil
+}
+
+func (c *Client) GetFriendSuggestions(ctx context.Context, userName string) ([]*database.FriendSuggestion, error) {
+	if suggestions, ok := c.cache.Get(FriendSuggestionsKey(userName)); ok {
+		return suggestions.([]*database.FriendSuggestion), nil
+	}
+	return nil, nil
+}
@@ -23,3 +23,35 @@ func (c *Client) IsUserOnline(ctx context.Context, userName string) (bool, error
 	}
 	return false, nil
 }
+
+// an active user dropped or evicted by ristretto is only looked up in the database again
+func (c *Client) PutUserActive(ctx context.Context, userName string) error {
+	c.cache.SetWithTTL(
+		UserActiveKey(userName),
+		true,
+		1,
+		cache.UserActiveExpiry,
+	)
+	return nil
+}
+
+func (c *Client) IsUserActive(ctx context.Context, userName string) (bool, error) {
+	_, ok := c.cache.Get(UserActiveKey(userName))
+	return ok, nil
+}
+
+func (c *Client) EvictUser(ctx context.Context, userName string, friends []string) error {
+	c.userEventsMutex.Lock()
+	delete(c.userEvents, userName)
+	c.userEventsMutex.Unlock()
+
+	c.cache.Del(userName)
+	c.cache.Del(UserActiveKey(userName))
+	c.cache.Del(UserFriendsListKey(userName))
+	c.cache.Del(FriendSuggestionsKey(userName))
+	for _, eachFriend := range friends {
+		c.cache.Del(MutualFriendsKey(userName, eachFriend))
+		c.cache.Del(MutualFriendsKey(eachFriend, userName))
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"socialite/cache"
+)
+
+// user events are kept out of ristretto as well, an event set right before a replay may not be readable yet
+type userEventBuffer struct {
+	lastSeq int64
+	events  []*cache.UserEvent
+}
+
+// prune drops the events older than expiry, the oldest ones come first
+func (b *userEventBuffer) prune(now time.Time, expiry time.Duration) {
+	expired := 0
+	for expired < len(b.events) && now.Sub(b.events[expired].CreatedAt) > expiry {
+		expired++
+	}
+	if expired > 0 {
+		b.events = append([]*cache.UserEvent(nil), b.events[expired:]...)
+	}
+}
+
+func (c *Client) PutUserEvent(ctx context.Context, userName string, event *cache.UserEvent) error {
+	if event == nil {
+		return errors.New("event input is nil")
+	}
+
+	c.userEventsMutex.Lock()
+	defer c.userEventsMutex.Unlock()
+
+	now := time.Now()
+	c.sweepUserEvents(now)
+
+	buffer := c.userEvents[userName]
+	if buffer == nil {
+		buffer = &userEventBuffer{}
+		c.userEvents[userName] = buffer
+	}
+	buffer.lastSeq++
+	event.Seq = buffer.lastSeq
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = now
+	}
+	buffer.events = append(buffer.events, event)
+	if len(buffer.events) > c.userEventsBufferSize {
+		buffer.events = append([]*cache.UserEvent(nil), buffer.events[len(buffer.events)-c.userEventsBufferSize:]...)
+	}
+	return nil
+}
+
+func (c *Client) GetUserEventsAfter(ctx context.Context, userName string, seq int64) ([]*cache.UserEvent, bool, error) {
+	c.userEventsMutex.Lock()
+	defer c.userEventsMutex.Unlock()
+
+	buffer := c.userEvents[userName]
+	if buffer == nil {
+		// nothing was kept, which is only complete for a client that never received an event
+		return nil, seq == 0, nil
+	}
+	buffer.prune(time.Now(), c.userEventsExpiry)
+
+	// the kept events are numbered from oldestSeq to lastSeq, without gaps
+	oldestSeq := buffer.lastSeq - int64(len(buffer.events)) + 1
+	complete := seq >= oldestSeq-1 && seq <= buffer.lastSeq
+
+	events := make([]*cache.UserEvent, 0)
+	for _, eachEvent := range buffer.events {
+		if eachEvent.Seq > seq {
+			events = append(events, eachEvent)
+		}
+	}
+	return events, complete, nil
+}
+
+// sweepUserEvents forgets the users whose events have all expired, at most once per expiry,
+// their numbering starts over so clients resuming with an older sequence number are told it is incomplete
+func (c *Client) sweepUserEvents(now time.Time) {
+	if now.Sub(c.userEventsSweptAt) < c.userEventsExpiry {
+		return
+	}
+	c.userEventsSweptAt = now
+	for userName, buffer := range c.userEvents {
+		buffer.prune(now, c.userEventsExpiry)
+		if len(buffer.events) == 0 {
+			delete(c.userEvents, userName)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package tracing
+
+import (
+	"context"
+
+	"socialite/cache"
+	"socialite/database"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("socialite/cache")
+
+// Cache wraps a cache with a span for each of its methods, whatever its backend
+type Cache struct {
+	cache cache.Cache
+}
+
+func New(c cache.Cache) cache.Cache {
+	return &Cache{cache: c}
+}
+
+// recordError marks the span failed
+func recordError(span trace.Span, err error) error {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+func (c *Cache) PutUserOnline(ctx context.Context, userName string) error {
+	ctx, span := tracer.Start(ctx, "cache.PutUserOnline")
+	defer span.End()
+	err := c.cache.PutUserOnline(ctx, userName)
+	return recordError(span, err)
+}
+
+func (c *Cache) IsUserOnline(ctx context.Context, userName string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "cache.IsUserOnline")
+	defer span.End()
+	result, err := c.cache.IsUserOnline(ctx, userName)
+	return result, recordError(span, err)
+}
+
+func (c *Cache) PutUserActive(ctx context.Context, userName string) error {
+	ctx, span := tracer.Start(ctx, "cache.PutUserActive")
+	defer span.End()
+	err := c.cache.PutUserActive(ctx, userName)
+	return recordError(span, err)
+}
+
+func (c *Cache) IsUserActive(ctx context.Context, userName string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "cache.IsUserActive")
+	defer span.End()
+	result, err := c.cache.IsUserActive(ctx, userName)
+	return result, recordError(span, err)
+}
+
+func (c *Cache) EvictUser(ctx context.Context, userName string, friends []string) error {
+	ctx, span := tracer.Start(ctx, "cache.EvictUser")
+	defer span.End()
+	err := c.cache.EvictUser(ctx, userName, friends)
+	return recordError(span, err)
+}
+
+func (c *Cache) PutUserFriendsList(ctx context.Context, userName string, friends []string) error {
+	ctx, span := tracer.Start(ctx, "cache.PutUserFriendsList")
+	defer span.End()
+	err := c.cache.PutUserFriendsList(ctx, userName, friends)
+	return recordError(span, err)
+}
+
+func (c *Cache) GetUserFriendsList(ctx context.Context, userName string) ([]string, error) {
+	ctx, span := tracer.Start(ctx, "cache.GetUserFriendsList")
+	defer span.End()
+	result, err := c.cache.GetUserFriendsList(ctx, userName)
+	return result, recordError(span, err)
+}
+
+func (c *Cache) PutPartyMembersList(ctx context.Context, partyName string, members []string) error {
+	ctx, span := tracer.Start(ctx, "cache.PutPartyMembersList")
+	defer span.End()
+	err := c.cache.PutPartyMembersList(ctx, partyName, members)
+	return recordError(span, err)
+}
+
+func (c *Cache) GetPartyMembersList(ctx context.Context, partyName string) ([]string, error) {
+	ctx, span := tracer.Start(ctx, "cache.GetPartyMembersList")
+	defer span.End()
+	result, err := c.cache.GetPartyMembersList(ctx, partyName)
+	return result, recordError(span, err)
+}
+
+func (c *Cache) DeletePartyMembersList(ctx context.Context, partyName string) error {
+	ctx, span := tracer.Start(ctx, "cache.DeletePartyMembersList")
+	defer span.End()
+	err := c.cache.DeletePartyMembersList(ctx, partyName)
+	return recordError(span, err)
+}
+
+func (c *Cache) PutMutualFriends(ctx context.Context, userName, otherName string, mutualFriends []*database.User) error {
+	ctx, span := tracer.Start(ctx, "cache.PutMutualFriends")
+	defer span.End()
+	err := c.cache.PutMutualFriends(ctx, userName, otherName, mutualFriends)
+	return recordError(span, err)
+}
+
+func (c *Cache) GetMutualFriends(ctx context.Context, userName, otherName string) ([]*database.User, error) {
+	ctx, span := tracer.Start(ctx, "cache.GetMutualFriends")
+	defer span.End()
+	result, err := c.cache.GetMutualFriends(ctx, userName, otherName)
+	return result, recordError(span, err)
+}
+
+func (c *Cache) PutFriendSuggestions(ctx context.Context, userName string, suggestions []*database.FriendSuggestion) error {
+	ctx, span := tracer.Start(ctx, "cache.PutFriendSuggestions")
+	defer span.End()
+	err := c.cache.PutFriendSuggestions(ctx, userName, suggestions)
+	return recordError(span, err)
+}
+
+func (c *Cache) GetFriendSuggestions(ctx context.Context, userName string) ([]*database.FriendSuggestion, error) {
+	ctx, span := tracer.Start(ctx, "cache.GetFriendSuggestions")
+	defer span.End()
+	result, err := c.cache.GetFriendSuggestions(ctx, userName)
+	return result, recordError(span, err)
+}
+
+func (c *Cache) PutUserEvent(ctx context.Context, userName string, event *cache.UserEvent) error {
+	ctx, span := tracer.Start(ctx, "cache.PutUserEvent")
+	defer span.End()
+	err := c.cache.PutUserEvent(ctx, userName, event)
+	return recordError(span, err)
+}
+
+func (c *Cache) GetUserEventsAfter(ctx context.Context, userName string, seq int64) ([]*cache.UserEvent, bool, error) {
+	ctx, span := tracer.Start(ctx, "cache.GetUserEventsAfter")
+	defer span.End()
+	events, complete, err := c.cache.GetUserEventsAfter(ctx, userName, seq)
+	return events, complete, recordError(span, err)
+}
+
+func (c *Cache) Close() {
+	c.cache.Close()
+}
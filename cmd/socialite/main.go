@@ -5,6 +5,8 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"syscall"
+	"time"
 
 	"socialite/config"
 	"socialite/server"
@@ -20,7 +22,7 @@ func main() {
 	serverInstance := server.New(globalCtx, cfg)
 	serverInstance.AddMiddlewares()
 	serverInstance.AddRoutes()
-	go serverInstance.StartCrons(globalCtx)
+	serverInstance.StartCrons(globalCtx)
 	go func() {
 		err := serverInstance.Start()
 		if err != nil {
@@ -28,10 +30,17 @@ func main() {
 		}
 	}()
 
-	// wait for signal interrupt
+	// wait for signal interrupt, or the termination signal kubernetes sends before killing the pod
 	ch := make(chan os.Signal, 1)
-	signal.Notify(ch, os.Interrupt)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
 	<-ch
 	log.Printf("[INFO] stopping server for %s", cfg.Server.ServiceName)
+
+	shutdownCtx, cancelShutdownCtx := context.WithTimeout(context.Background(), time.Second*time.Duration(cfg.Server.ShutdownTimeout))
+	defer cancelShutdownCtx()
+	err := serverInstance.Shutdown(shutdownCtx)
+	if err != nil {
+		log.Printf("[ERROR] shutting down server for %s : %s", cfg.Server.ServiceName, err.Error())
+	}
 	cancelGlobalCtx()
 }
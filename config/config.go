@@ -1,11 +1,12 @@
 package config
 
 type ServerConfig struct {
-	Port        int    `yaml:"port" env:"port"`
-	TLS         bool   `yaml:"tls" env:"tls"`
-	CertPath    string `yaml:"cert_path" env:"cert_path"`
-	KeyPath     string `yaml:"key_path" env:"key_path"`
-	ServiceName string `yaml:"service_name" env:"service_name"`
+	Port            int    `yaml:"port" env:"port"`
+	TLS             bool   `yaml:"tls" env:"tls"`
+	CertPath        string `yaml:"cert_path" env:"cert_path"`
+	KeyPath         string `yaml:"key_path" env:"key_path"`
+	ServiceName     string `yaml:"service_name" env:"service_name"`
+	ShutdownTimeout int    `yaml:"shutdown_timeout" env:"shutdown_timeout"`
 }
 
 type DatabaseConfig struct {
@@ -15,25 +16,108 @@ type DatabaseConfig struct {
 }
 
 type CacheConfig struct {
-	Type string `yaml:"type" env:"type"`
+	Type                 string `yaml:"type" env:"type"`
+	UserEventsExpiry     int    `yaml:"user_events_expiry" env:"user_events_expiry"`
+	UserEventsBufferSize int    `yaml:"user_events_buffer_size" env:"user_events_buffer_size"`
+}
+
+type PartyConfig struct {
+	InviteExpiry      int `yaml:"invite_expiry" env:"invite_expiry"`
+	ReadyCheckTimeout int `yaml:"ready_check_timeout" env:"ready_check_timeout"`
+	ChatMaxLength     int `yaml:"chat_max_length" env:"chat_max_length"`
+	ChatHistorySize   int `yaml:"chat_history_size" env:"chat_history_size"`
+	ChatRateLimit     int `yaml:"chat_rate_limit" env:"chat_rate_limit"`
+	ChatRateInterval  int `yaml:"chat_rate_interval" env:"chat_rate_interval"`
+}
+
+type UserConfig struct {
+	DeletionGracePeriod int `yaml:"deletion_grace_period" env:"deletion_grace_period"`
+}
+
+type ValidationConfig struct {
+	NameMinLength    int      `yaml:"name_min_length" env:"name_min_length"`
+	NameMaxLength    int      `yaml:"name_max_length" env:"name_max_length"`
+	NameAllowedChars string   `yaml:"name_allowed_chars" env:"name_allowed_chars"`
+	ReservedNames    []string `yaml:"reserved_names" env:"reserved_names"`
+}
+
+type WebhookConfig struct {
+	AdminToken      string `yaml:"admin_token" env:"admin_token"`
+	Timeout         int    `yaml:"timeout" env:"timeout"`
+	MaxAttempts     int    `yaml:"max_attempts" env:"max_attempts"`
+	RetryBackoff    int    `yaml:"retry_backoff" env:"retry_backoff"`
+	RetryMaxBackoff int    `yaml:"retry_max_backoff" env:"retry_max_backoff"`
+}
+
+type WebsocketConfig struct {
+	PingInterval       int    `yaml:"ping_interval" env:"ping_interval"`
+	PongTimeout        int    `yaml:"pong_timeout" env:"pong_timeout"`
+	WriteTimeout       int    `yaml:"write_timeout" env:"write_timeout"`
+	SendQueueSize      int    `yaml:"send_queue_size" env:"send_queue_size"`
+	SlowConsumerPolicy string `yaml:"slow_consumer_policy" env:"slow_consumer_policy"`
+}
+
+type TracingConfig struct {
+	Exporter     string  `yaml:"exporter" env:"exporter"`
+	OtlpEndpoint string  `yaml:"otlp_endpoint" env:"otlp_endpoint"`
+	SampleRatio  float64 `yaml:"sample_ratio" env:"sample_ratio"`
 }
 
 type Config struct {
-	Server   ServerConfig   `yaml:"server" env:"server"`
-	Database DatabaseConfig `yaml:"database" env:"database"`
-	Cache    CacheConfig    `yaml:"cache" env:"cache"`
+	Server     ServerConfig     `yaml:"server" env:"server"`
+	Database   DatabaseConfig   `yaml:"database" env:"database"`
+	Cache      CacheConfig      `yaml:"cache" env:"cache"`
+	Party      PartyConfig      `yaml:"party" env:"party"`
+	User       UserConfig       `yaml:"user" env:"user"`
+	Validation ValidationConfig `yaml:"validation" env:"validation"`
+	Webhook    WebhookConfig    `yaml:"webhook" env:"webhook"`
+	Websocket  WebsocketConfig  `yaml:"websocket" env:"websocket"`
+	Tracing    TracingConfig    `yaml:"tracing" env:"tracing"`
 }
 
 type FlatConfig struct {
-	ServerPort        int    `yaml:"server_port" env:"server_port"`
-	ServerTLS         bool   `yaml:"server_tls" env:"server_tls"`
-	ServerCertPath    string `yaml:"server_cert_path" env:"server_cert_path"`
-	ServerKeyPath     string `yaml:"server_key_path" env:"server_key_path"`
-	ServerServiceName string `yaml:"server_service_name" env:"server_service_name"`
+	ServerPort            int    `yaml:"server_port" env:"server_port"`
+	ServerTLS             bool   `yaml:"server_tls" env:"server_tls"`
+	ServerCertPath        string `yaml:"server_cert_path" env:"server_cert_path"`
+	ServerKeyPath         string `yaml:"server_key_path" env:"server_key_path"`
+	ServerServiceName     string `yaml:"server_service_name" env:"server_service_name"`
+	ServerShutdownTimeout int    `yaml:"server_shutdown_timeout" env:"server_shutdown_timeout"`
 
 	DatabaseType      string `yaml:"database_type" env:"database_type"`
 	DatabaseUriString string `yaml:"database_uri_string" env:"database_uri_string"`
 	DatabaseTimeout   int    `yaml:"database_timeout" env:"database_timeout"`
 
-	CacheType string `yaml:"cache_type" env:"cache_type"`
+	CacheType                 string `yaml:"cache_type" env:"cache_type"`
+	CacheUserEventsExpiry     int    `yaml:"cache_user_events_expiry" env:"cache_user_events_expiry"`
+	CacheUserEventsBufferSize int    `yaml:"cache_user_events_buffer_size" env:"cache_user_events_buffer_size"`
+
+	PartyInviteExpiry      int `yaml:"party_invite_expiry" env:"party_invite_expiry"`
+	PartyReadyCheckTimeout int `yaml:"party_ready_check_timeout" env:"party_ready_check_timeout"`
+	PartyChatMaxLength     int `yaml:"party_chat_max_length" env:"party_chat_max_length"`
+	PartyChatHistorySize   int `yaml:"party_chat_history_size" env:"party_chat_history_size"`
+	PartyChatRateLimit     int `yaml:"party_chat_rate_limit" env:"party_chat_rate_limit"`
+	PartyChatRateInterval  int `yaml:"party_chat_rate_interval" env:"party_chat_rate_interval"`
+
+	UserDeletionGracePeriod int `yaml:"user_deletion_grace_period" env:"user_deletion_grace_period"`
+
+	ValidationNameMinLength    int      `yaml:"validation_name_min_length" env:"validation_name_min_length"`
+	ValidationNameMaxLength    int      `yaml:"validation_name_max_length" env:"validation_name_max_length"`
+	ValidationNameAllowedChars string   `yaml:"validation_name_allowed_chars" env:"validation_name_allowed_chars"`
+	ValidationReservedNames    []string `yaml:"validation_reserved_names" env:"validation_reserved_names"`
+
+	WebhookAdminToken      string `yaml:"webhook_admin_token" env:"webhook_admin_token"`
+	WebhookTimeout         int    `yaml:"webhook_timeout" env:"webhook_timeout"`
+	WebhookMaxAttempts     int    `yaml:"webhook_max_attempts" env:"webhook_max_attempts"`
+	WebhookRetryBackoff    int    `yaml:"webhook_retry_backoff" env:"webhook_retry_backoff"`
+	WebhookRetryMaxBackoff int    `yaml:"webhook_retry_max_backoff" env:"webhook_retry_max_backoff"`
+
+	WebsocketPingInterval       int    `yaml:"websocket_ping_interval" env:"websocket_ping_interval"`
+	WebsocketPongTimeout        int    `yaml:"websocket_pong_timeout" env:"websocket_pong_timeout"`
+	WebsocketWriteTimeout       int    `yaml:"websocket_write_timeout" env:"websocket_write_timeout"`
+	WebsocketSendQueueSize      int    `yaml:"websocket_send_queue_size" env:"websocket_send_queue_size"`
+	WebsocketSlowConsumerPolicy string `yaml:"websocket_slow_consumer_policy" env:"websocket_slow_consumer_policy"`
+
+	TracingExporter     string  `yaml:"tracing_exporter" env:"tracing_exporter"`
+	TracingOtlpEndpoint string  `yaml:"tracing_otlp_endpoint" env:"tracing_otlp_endpoint"`
+	TracingSampleRatio  float64 `yaml:"tracing_sample_ratio" env:"tracing_sample_ratio"`
 }
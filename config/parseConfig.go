@@ -24,11 +24,12 @@ func ParseConfig() *Config {
 
 	return &Config{
 		Server: ServerConfig{
-			Port:        readConfig.ServerPort,
-			TLS:         readConfig.ServerTLS,
-			CertPath:    readConfig.ServerCertPath,
-			KeyPath:     readConfig.ServerKeyPath,
-			ServiceName: readConfig.ServerServiceName,
+			Port:            readConfig.ServerPort,
+			TLS:             readConfig.ServerTLS,
+			CertPath:        readConfig.ServerCertPath,
+			KeyPath:         readConfig.ServerKeyPath,
+			ServiceName:     readConfig.ServerServiceName,
+			ShutdownTimeout: readConfig.ServerShutdownTimeout,
 		},
 		Database: DatabaseConfig{
 			Type:      readConfig.DatabaseType,
@@ -36,7 +37,45 @@ func ParseConfig() *Config {
 			Timeout:   readConfig.DatabaseTimeout,
 		},
 		Cache: CacheConfig{
-			Type: readConfig.CacheType,
+			Type:                 readConfig.CacheType,
+			UserEventsExpiry:     readConfig.CacheUserEventsExpiry,
+			UserEventsBufferSize: readConfig.CacheUserEventsBufferSize,
+		},
+		Party: PartyConfig{
+			InviteExpiry:      readConfig.PartyInviteExpiry,
+			ReadyCheckTimeout: readConfig.PartyReadyCheckTimeout,
+			ChatMaxLength:     readConfig.PartyChatMaxLength,
+			ChatHistorySize:   readConfig.PartyChatHistorySize,
+			ChatRateLimit:     readConfig.PartyChatRateLimit,
+			ChatRateInterval:  readConfig.PartyChatRateInterval,
+		},
+		User: UserConfig{
+			DeletionGracePeriod: readConfig.UserDeletionGracePeriod,
+		},
+		Validation: ValidationConfig{
+			NameMinLength:    readConfig.ValidationNameMinLength,
+			NameMaxLength:    readConfig.ValidationNameMaxLength,
+			NameAllowedChars: readConfig.ValidationNameAllowedChars,
+			ReservedNames:    readConfig.ValidationReservedNames,
+		},
+		Webhook: WebhookConfig{
+			AdminToken:      readConfig.WebhookAdminToken,
+			Timeout:         readConfig.WebhookTimeout,
+			MaxAttempts:     readConfig.WebhookMaxAttempts,
+			RetryBackoff:    readConfig.WebhookRetryBackoff,
+			RetryMaxBackoff: readConfig.WebhookRetryMaxBackoff,
+		},
+		Websocket: WebsocketConfig{
+			PingInterval:       readConfig.WebsocketPingInterval,
+			PongTimeout:        readConfig.WebsocketPongTimeout,
+			WriteTimeout:       readConfig.WebsocketWriteTimeout,
+			SendQueueSize:      readConfig.WebsocketSendQueueSize,
+			SlowConsumerPolicy: readConfig.WebsocketSlowConsumerPolicy,
+		},
+		Tracing: TracingConfig{
+			Exporter:     readConfig.TracingExporter,
+			OtlpEndpoint: readConfig.TracingOtlpEndpoint,
+			SampleRatio:  readConfig.TracingSampleRatio,
 		},
 	}
 }
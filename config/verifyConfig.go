@@ -21,6 +21,9 @@ func Verify(cfg *Config) {
 	if cfg.Server.ServiceName == "" {
 		log.Fatal("[ERROR] server_service_name is empty in config")
 	}
+	if cfg.Server.ShutdownTimeout <= 0 {
+		log.Fatal("[ERROR] server_shutdown_timeout is empty in config")
+	}
 
 	// database checks
 	if cfg.Database.Type == "" {
@@ -37,4 +40,96 @@ func Verify(cfg *Config) {
 	if cfg.Cache.Type == "" {
 		log.Fatal("[ERROR] cache_type is empty in config")
 	}
+	if cfg.Cache.UserEventsExpiry <= 0 {
+		log.Fatal("[ERROR] cache_user_events_expiry is empty in config")
+	}
+	if cfg.Cache.UserEventsBufferSize <= 0 {
+		log.Fatal("[ERROR] cache_user_events_buffer_size is empty in config")
+	}
+
+	// party checks
+	if cfg.Party.InviteExpiry <= 0 {
+		log.Fatal("[ERROR] party_invite_expiry is empty in config")
+	}
+	if cfg.Party.ReadyCheckTimeout <= 0 {
+		log.Fatal("[ERROR] party_ready_check_timeout is empty in config")
+	}
+	if cfg.Party.ChatMaxLength <= 0 {
+		log.Fatal("[ERROR] party_chat_max_length is empty in config")
+	}
+	if cfg.Party.ChatHistorySize <= 0 {
+		log.Fatal("[ERROR] party_chat_history_size is empty in config")
+	}
+	if cfg.Party.ChatRateLimit <= 0 {
+		log.Fatal("[ERROR] party_chat_rate_limit is empty in config")
+	}
+	if cfg.Party.ChatRateInterval <= 0 {
+		log.Fatal("[ERROR] party_chat_rate_interval is empty in config")
+	}
+
+	// user checks, no grace period deletes users right away
+	if cfg.User.DeletionGracePeriod < 0 {
+		log.Fatal("[ERROR] user_deletion_grace_period is negative in config")
+	}
+
+	// validation checks
+	if cfg.Validation.NameMinLength <= 0 {
+		log.Fatal("[ERROR] validation_name_min_length is empty in config")
+	}
+	if cfg.Validation.NameMaxLength < cfg.Validation.NameMinLength {
+		log.Fatal("[ERROR] validation_name_max_length is less than validation_name_min_length in config")
+	}
+	if cfg.Validation.NameAllowedChars == "" {
+		log.Fatal("[ERROR] validation_name_allowed_chars is empty in config")
+	}
+
+	// webhook checks, no admin token leaves the webhook admin routes disabled
+	if cfg.Webhook.Timeout <= 0 {
+		log.Fatal("[ERROR] webhook_timeout is empty in config")
+	}
+	if cfg.Webhook.MaxAttempts <= 0 {
+		log.Fatal("[ERROR] webhook_max_attempts is empty in config")
+	}
+	if cfg.Webhook.RetryBackoff <= 0 {
+		log.Fatal("[ERROR] webhook_retry_backoff is empty in config")
+	}
+	if cfg.Webhook.RetryMaxBackoff < cfg.Webhook.RetryBackoff {
+		log.Fatal("[ERROR] webhook_retry_max_backoff is less than webhook_retry_backoff in config")
+	}
+
+	// websocket checks, pongs keep users online and the online status expires after 10 seconds
+	if cfg.Websocket.PingInterval <= 0 {
+		log.Fatal("[ERROR] websocket_ping_interval is empty in config")
+	}
+	if cfg.Websocket.PingInterval >= 10 {
+		log.Fatal("[ERROR] websocket_ping_interval is not less than 10 in config")
+	}
+	if cfg.Websocket.PongTimeout <= cfg.Websocket.PingInterval {
+		log.Fatal("[ERROR] websocket_pong_timeout is not greater than websocket_ping_interval in config")
+	}
+	if cfg.Websocket.WriteTimeout <= 0 {
+		log.Fatal("[ERROR] websocket_write_timeout is empty in config")
+	}
+	if cfg.Websocket.SendQueueSize <= 0 {
+		log.Fatal("[ERROR] websocket_send_queue_size is empty in config")
+	}
+	switch cfg.Websocket.SlowConsumerPolicy {
+	case "drop_oldest", "coalesce", "disconnect":
+	default:
+		log.Fatal("[ERROR] websocket_slow_consumer_policy is not one of drop_oldest, coalesce or disconnect in config")
+	}
+
+	// tracing checks
+	switch cfg.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		if cfg.Tracing.OtlpEndpoint == "" {
+			log.Fatal("[ERROR] tracing_otlp_endpoint is empty in config")
+		}
+	default:
+		log.Fatal("[ERROR] tracing_exporter is not one of none, stdout or otlp in config")
+	}
+	if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+		log.Fatal("[ERROR] tracing_sample_ratio is not between 0 and 1 in config")
+	}
 }
@@ -1,26 +1,63 @@
 package database
 
-import "context"
+import (
+	"context"
+	"time"
+
+	"socialite/events"
+)
 
 type Database interface {
 	// user methods
 	PutUser(ctx context.Context, user *User) error
 	GetUser(ctx context.Context, name string) (*User, error)
+	DeleteUser(ctx context.Context, name string, softDelete bool) (*UserDeletion, error)
+	PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time) ([]string, error)
+	PutProfile(ctx context.Context, profile *Profile) error
+	GetProfile(ctx context.Context, userName string) (*Profile, error)
+	GetProfileSummaries(ctx context.Context, userNames []string) (map[string]*ProfileSummary, error)
+	SearchUsers(ctx context.Context, userName, query string, opts *ListOptions) (*Page[*UserSearchResult], error)
 
 	// friends methods
-	GetUserFriends(ctx context.Context, name string) ([]*User, error)
+	GetUserFriends(ctx context.Context, name string, opts *ListOptions) (*Page[*Friend], error)
+	GetMutualFriends(ctx context.Context, userName, otherName string) ([]*User, error)
+	GetFriendSuggestions(ctx context.Context, userName string, limit int) ([]*FriendSuggestion, error)
 	PutFriendship(ctx context.Context, friendship *Friendship) error
-	GetPendingFriendRequests(ctx context.Context, userName string) ([]*Friendship, error)
+	GetPendingFriendRequests(ctx context.Context, userName string, opts *ListOptions) (*Page[*Friendship], error)
+	GetSentFriendRequests(ctx context.Context, userName string, opts *ListOptions) (*Page[*Friendship], error)
 	GetUserFriendsList(ctx context.Context) (map[string][]string, error)
 	GetFriendship(ctx context.Context, user1, user2 string) (*Friendship, error)
 	GetFriendshipById(ctx context.Context, friendshipId int32) (*Friendship, error)
 	UpdateFriendship(ctx context.Context, friendship *Friendship) error
 	DeleteFriendship(ctx context.Context, friendshipId int32) error
 
+	// friend metadata methods
+	GetFriendMetadata(ctx context.Context, userName string, friendshipId int32) (*FriendMetadata, error)
+	PutFriendMetadata(ctx context.Context, metadata *FriendMetadata) error
+	GetFavoriteFriendNames(ctx context.Context, userName string) ([]string, error)
+	GetFriendGroups(ctx context.Context, userName string) ([]*FriendGroup, error)
+	GetFriendGroup(ctx context.Context, userName string, groupId int32) (*FriendGroup, error)
+	PutFriendGroup(ctx context.Context, group *FriendGroup) error
+	UpdateFriendGroup(ctx context.Context, group *FriendGroup) error
+	DeleteFriendGroup(ctx context.Context, userName string, groupId int32) error
+	PutFriendGroupMember(ctx context.Context, groupId, friendshipId int32) error
+	DeleteFriendGroupMember(ctx context.Context, groupId, friendshipId int32) error
+
+	// block methods
+	PutBlock(ctx context.Context, block *Block) error
+	DeleteBlock(ctx context.Context, blocker, blocked string) error
+	GetBlocks(ctx context.Context, blocker string) ([]*Block, error)
+	IsBlocked(ctx context.Context, blocker, blocked string) (bool, error)
+	GetBlockedUserNames(ctx context.Context, userName string) ([]string, error)
+
 	// party methods
 	PutParty(ctx context.Context, party *Party) error
 	GetParty(ctx context.Context, partyName string) (*Party, error)
-	GetCreatedParties(ctx context.Context, userName string) ([]*Party, error)
+	GetCreatedParties(ctx context.Context, userName string, opts *ListOptions) (*Page[*Party], error)
+	GetUserParties(ctx context.Context, userName string, status PartyMembership_Status) ([]*UserParty, error)
+	UpdatePartyState(ctx context.Context, partyName string, from, to Party_State) error
+	StartPartyReadyCheck(ctx context.Context, partyName string) error
+	PutPartyReadyResponse(ctx context.Context, partyName, userName string, ready PartyMembership_Ready) error
 
 	// party membership methods
 	PutPartyMembership(ctx context.Context, membership *PartyMembership) error
@@ -28,5 +65,44 @@ type Database interface {
 	UpdatePartyMembership(ctx context.Context, membership *PartyMembership) error
 	DeletePartyMembership(ctx context.Context, membership *PartyMembership) error
 	GetPartyMembers(ctx context.Context, partyName string) ([]string, error)
+	GetPartyMemberships(ctx context.Context, partyName string) ([]*PartyMembership, error)
 	GetAllPartyMembers(ctx context.Context) (map[string][]string, error)
+	DeleteExpiredPartyInvitations(ctx context.Context, expiredBefore time.Time) (int64, error)
+
+	// party message methods
+	PutPartyMessage(ctx context.Context, message *PartyMessage, historySize int) error
+	GetPartyMessages(ctx context.Context, partyName string, beforeId int32, limit int) ([]*PartyMessage, error)
+	GetUserPartyMessages(ctx context.Context, userName string, beforeId int32, limit int) ([]*PartyMessage, error)
+
+	// direct message methods
+	PutDirectMessage(ctx context.Context, message *DirectMessage) error
+	GetDirectMessages(ctx context.Context, user1, user2 string, beforeId int32, limit int) ([]*DirectMessage, error)
+	MarkDirectMessagesRead(ctx context.Context, recipient, sender string, upToId int32) (int64, error)
+	GetUnreadDirectMessageCounts(ctx context.Context, recipient string) (map[string]int, error)
+	GetDirectMessageContacts(ctx context.Context, userName string) ([]string, error)
+
+	// notification methods
+	PutNotification(ctx context.Context, notification *Notification) error
+	GetNotifications(ctx context.Context, userName string, opts *ListOptions) (*Page[*Notification], error)
+	GetUnreadNotificationCount(ctx context.Context, userName string) (int, error)
+	MarkNotificationRead(ctx context.Context, userName string, notificationId int32) error
+	MarkAllNotificationsRead(ctx context.Context, userName string) (int64, error)
+
+	// webhook methods
+	PutWebhookSubscription(ctx context.Context, subscription *WebhookSubscription) error
+	GetWebhookSubscriptions(ctx context.Context) ([]*WebhookSubscription, error)
+	GetWebhookSubscriptionsForEvent(ctx context.Context, eventType string) ([]*WebhookSubscription, error)
+	DeleteWebhookSubscription(ctx context.Context, subscriptionId int32) error
+	PutWebhookDelivery(ctx context.Context, delivery *WebhookDelivery) error
+	IsWebhookDelivered(ctx context.Context, subscriptionId int32, eventId string) (bool, error)
+	GetWebhookDeliveries(ctx context.Context, subscriptionId int32, limit int) ([]*WebhookDelivery, error)
+	PutWebhookDeadLetter(ctx context.Context, deadLetter *WebhookDeadLetter) error
+	GetWebhookDeadLetters(ctx context.Context, limit int) ([]*WebhookDeadLetter, error)
+
+	// outbox methods
+	PublishOutboxEvents(ctx context.Context, limit int, publish func(context.Context, *events.Event) error) (int, error)
+	DeletePublishedOutboxEvents(ctx context.Context, publishedBefore time.Time) (int64, error)
+
+	// Close releases the connections once the server is done with the database
+	Close()
 }
@@ -9,6 +9,7 @@ import (
 	"socialite/config"
 	"socialite/database"
 	"socialite/database/postgres"
+	"socialite/events"
 )
 
 var dbConn database.Database
@@ -66,19 +67,78 @@ func TestGetUser(t *testing.T) {
 	log.Printf("user : %+v", user)
 }
 
+func TestPutProfile(t *testing.T) {
+	profile, err := database.NewProfile("user1")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	profile.DisplayName = "User1"
+	profile.Language = "en-US"
+	err = dbConn.PutProfile(context.Background(), profile)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGetProfile(t *testing.T) {
+	profile, err := dbConn.GetProfile(
+		context.Background(),
+		"user1",
+	)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	log.Printf("profile : %+v", profile)
+}
+
+func TestGetProfileSummaries(t *testing.T) {
+	summaries, err := dbConn.GetProfileSummaries(
+		context.Background(),
+		[]string{"user1", "user2"},
+	)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for userName, summary := range summaries {
+		log.Printf("%s -> %+v", userName, summary)
+	}
+}
+
+func TestSearchUsers(t *testing.T) {
+	resultsPage, err := dbConn.SearchUsers(
+		context.Background(),
+		"user1",
+		"user",
+		nil,
+	)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(resultsPage.Items) == 0 {
+		log.Print("results is empty")
+		return
+	}
+	for _, eachResult := range resultsPage.Items {
+		log.Printf("result : %+v", eachResult)
+	}
+}
+
 func TestGetUserFriends(t *testing.T) {
 	username := "user1"
-	friendsList, err := dbConn.GetUserFriends(
+	friendsPage, err := dbConn.GetUserFriends(
 		context.Background(),
 		username,
+		&database.ListOptions{Sort: database.ListSort_Name},
 	)
 	if err != nil {
 		t.Error(err)
 		return
 	}
-	if friendsList == nil {
-		return
-	}
+	friendsList := friendsPage.Items
 	if len(friendsList) == 0 {
 		log.Print("friendsList is empty")
 		return
@@ -97,6 +157,38 @@ var testFriendship = &database.Friendship{
 	UpdatedAt: time.Now(),
 }
 
+func TestGetMutualFriends(t *testing.T) {
+	mutualFriends, err := dbConn.GetMutualFriends(
+		context.Background(),
+		"user1",
+		"user2",
+	)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for _, eachFriend := range mutualFriends {
+		log.Printf("mutual friend : %+v", eachFriend)
+	}
+	log.Printf("total mutual friends : %d", len(mutualFriends))
+}
+
+func TestGetFriendSuggestions(t *testing.T) {
+	suggestions, err := dbConn.GetFriendSuggestions(
+		context.Background(),
+		"user1",
+		10,
+	)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for _, eachSuggestion := range suggestions {
+		log.Printf("suggestion : %+v", eachSuggestion)
+	}
+	log.Printf("total suggestions : %d", len(suggestions))
+}
+
 func TestPutFriendship(t *testing.T) {
 	err := dbConn.PutFriendship(
 		context.Background(),
@@ -153,6 +245,402 @@ func TestGetFriendshipById(t *testing.T) {
 	log.Printf("friendship : %+v", friendship)
 }
 
+func TestPutFriendMetadata(t *testing.T) {
+	friendship, err := dbConn.GetFriendship(context.Background(), "user1", "user2")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	metadata, err := database.NewFriendMetadata(friendship.Id, "user1")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	metadata.Nickname = "two"
+	metadata.Favorite = true
+	err = dbConn.PutFriendMetadata(context.Background(), metadata)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	favorites, err := dbConn.GetFavoriteFriendNames(context.Background(), "user1")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	log.Printf("favorite friends : %v", favorites)
+}
+
+var testFriendGroup = &database.FriendGroup{
+	UserName:  "user1",
+	Name:      "Raid team",
+	CreatedAt: time.Now(),
+}
+
+func TestPutFriendGroup(t *testing.T) {
+	err := dbConn.PutFriendGroup(
+		context.Background(),
+		testFriendGroup,
+	)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPutFriendGroupMember(t *testing.T) {
+	friendship, err := dbConn.GetFriendship(context.Background(), "user1", "user2")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = dbConn.PutFriendGroupMember(context.Background(), testFriendGroup.Id, friendship.Id)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	group, err := dbConn.GetFriendGroup(context.Background(), "user1", testFriendGroup.Id)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	log.Printf("friend group : %+v", group)
+}
+
+func TestGetFriendGroups(t *testing.T) {
+	groups, err := dbConn.GetFriendGroups(
+		context.Background(),
+		"user1",
+	)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for _, eachGroup := range groups {
+		log.Printf("friend group : %+v", eachGroup)
+	}
+	log.Printf("total friend groups : %d", len(groups))
+}
+
+func TestDeleteFriendGroup(t *testing.T) {
+	err := dbConn.DeleteFriendGroup(
+		context.Background(),
+		"user1",
+		testFriendGroup.Id,
+	)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+var testDirectMessage = &database.DirectMessage{
+	Sender:    "user1",
+	Recipient: "user2",
+	Text:      "hello",
+	CreatedAt: time.Now(),
+}
+
+func TestPutDirectMessage(t *testing.T) {
+	err := dbConn.PutDirectMessage(
+		context.Background(),
+		testDirectMessage,
+	)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if testDirectMessage.Id == 0 {
+		t.Error("message id is not set")
+	}
+}
+
+func TestGetDirectMessages(t *testing.T) {
+	messages, err := dbConn.GetDirectMessages(
+		context.Background(),
+		testDirectMessage.Recipient,
+		testDirectMessage.Sender,
+		0,
+		50,
+	)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(messages) == 0 {
+		log.Print("messages is empty")
+		return
+	}
+	for _, eachMessage := range messages {
+		log.Printf("message : %+v", eachMessage)
+	}
+	log.Printf("total messages : %d", len(messages))
+}
+
+func TestGetUnreadDirectMessageCounts(t *testing.T) {
+	unreadCounts, err := dbConn.GetUnreadDirectMessageCounts(
+		context.Background(),
+		testDirectMessage.Recipient,
+	)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	log.Printf("unread counts : %+v", unreadCounts)
+}
+
+func TestMarkDirectMessagesRead(t *testing.T) {
+	updated, err := dbConn.MarkDirectMessagesRead(
+		context.Background(),
+		testDirectMessage.Recipient,
+		testDirectMessage.Sender,
+		0,
+	)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	log.Printf("messages marked read : %d", updated)
+}
+
+var testNotification = &database.Notification{
+	UserName:  "user2",
+	Type:      database.Notification_Type_FriendRequest,
+	Actor:     "user1",
+	CreatedAt: time.Now(),
+}
+
+func TestPutNotification(t *testing.T) {
+	err := dbConn.PutNotification(
+		context.Background(),
+		testNotification,
+	)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGetNotifications(t *testing.T) {
+	notifications, err := dbConn.GetNotifications(
+		context.Background(),
+		"user2",
+		&database.ListOptions{UnreadOnly: true},
+	)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for _, eachNotification := range notifications.Items {
+		log.Printf("notification : %+v", eachNotification)
+	}
+
+	unreadCount, err := dbConn.GetUnreadNotificationCount(context.Background(), "user2")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	log.Printf("total unread notifications : %d", unreadCount)
+}
+
+func TestMarkNotificationRead(t *testing.T) {
+	err := dbConn.MarkNotificationRead(
+		context.Background(),
+		"user2",
+		testNotification.Id,
+	)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMarkAllNotificationsRead(t *testing.T) {
+	marked, err := dbConn.MarkAllNotificationsRead(
+		context.Background(),
+		"user2",
+	)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	log.Printf("total notifications marked read : %d", marked)
+}
+
+func TestWebhookSubscription(t *testing.T) {
+	subscription, err := database.NewWebhookSubscription("https://example.com/hook", []string{"friend.request_sent"}, "")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = dbConn.PutWebhookSubscription(context.Background(), subscription)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	subscriptions, err := dbConn.GetWebhookSubscriptionsForEvent(context.Background(), "friend.request_sent")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	log.Printf("total webhook subscriptions for event : %d", len(subscriptions))
+
+	err = dbConn.PutWebhookDelivery(context.Background(), &database.WebhookDelivery{
+		SubscriptionId: subscription.Id,
+		EventId:        "event1",
+		EventType:      "friend.request_sent",
+		Attempt:        1,
+		StatusCode:     200,
+		CreatedAt:      time.Now(),
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = dbConn.DeleteWebhookSubscription(context.Background(), subscription.Id)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPublishOutboxEvents(t *testing.T) {
+	published := make([]*events.Event, 0)
+	publish := func(ctx context.Context, event *events.Event) error {
+		published = append(published, event)
+		return nil
+	}
+	// drain the events of the tests before
+	for {
+		total, err := dbConn.PublishOutboxEvents(context.Background(), database.OutboxBatchSize, publish)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if total == 0 {
+			break
+		}
+	}
+
+	for _, eachName := range []string{"outbox1", "outbox2"} {
+		err := dbConn.PutUser(context.Background(), &database.User{Name: eachName, CreatedAt: time.Now()})
+		if err != nil && err != database.Err_DuplicatePrimaryKey {
+			t.Error(err)
+			return
+		}
+	}
+	friendship, err := database.NewFriendship("outbox1", "outbox2")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = dbConn.PutFriendship(context.Background(), friendship)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer dbConn.DeleteFriendship(context.Background(), friendship.Id)
+
+	published = published[:0]
+	total, err := dbConn.PublishOutboxEvents(context.Background(), database.OutboxBatchSize, publish)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if total != 1 || len(published) != 1 {
+		t.Errorf("published %d events, handed %d, expected 1", total, len(published))
+		return
+	}
+	event := published[0]
+	if event.Type != events.Type_FriendRequestSent || event.Actor != "outbox1" || event.UserName != "outbox2" {
+		t.Errorf("unexpected event : %+v", event)
+	}
+
+	total, err = dbConn.PublishOutboxEvents(context.Background(), database.OutboxBatchSize, publish)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if total != 0 || len(published) != 1 {
+		t.Errorf("published events again : %d", total)
+	}
+
+	purged, err := dbConn.DeletePublishedOutboxEvents(context.Background(), time.Now())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	log.Printf("total outbox events purged : %d", purged)
+}
+
+var testBlock = &database.Block{
+	Blocker:   "user1",
+	Blocked:   "user3",
+	CreatedAt: time.Now(),
+}
+
+func TestPutBlock(t *testing.T) {
+	err := dbConn.PutBlock(
+		context.Background(),
+		testBlock,
+	)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestIsBlocked(t *testing.T) {
+	isBlocked, err := dbConn.IsBlocked(
+		context.Background(),
+		testBlock.Blocker,
+		testBlock.Blocked,
+	)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !isBlocked {
+		t.Error("user is not blocked")
+	}
+}
+
+func TestGetBlocks(t *testing.T) {
+	blocks, err := dbConn.GetBlocks(
+		context.Background(),
+		testBlock.Blocker,
+	)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for _, eachBlock := range blocks {
+		log.Printf("block : %+v", eachBlock)
+	}
+	log.Printf("total blocks : %d", len(blocks))
+}
+
+func TestGetBlockedUserNames(t *testing.T) {
+	userNames, err := dbConn.GetBlockedUserNames(
+		context.Background(),
+		testBlock.Blocked,
+	)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	log.Printf("blocked users : %+v", userNames)
+}
+
+func TestDeleteBlock(t *testing.T) {
+	err := dbConn.DeleteBlock(
+		context.Background(),
+		testBlock.Blocker,
+		testBlock.Blocked,
+	)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
 var testParty = &database.Party{
 	Name:      "party1",
 	Creator:   "user1",
@@ -183,14 +671,16 @@ func TestGetParty(t *testing.T) {
 }
 
 func TestGetCreatedParties(t *testing.T) {
-	parties, err := dbConn.GetCreatedParties(
+	partiesPage, err := dbConn.GetCreatedParties(
 		context.Background(),
 		testParty.Creator,
+		&database.ListOptions{Sort: database.ListSort_CreatedAt},
 	)
 	if err != nil {
 		t.Error(err)
 		return
 	}
+	parties := partiesPage.Items
 	if len(parties) == 0 {
 		log.Print("parties is empty")
 		return
@@ -201,6 +691,104 @@ func TestGetCreatedParties(t *testing.T) {
 	log.Printf("total parties created : %d", len(parties))
 }
 
+func TestGetUserParties(t *testing.T) {
+	parties, err := dbConn.GetUserParties(
+		context.Background(),
+		testParty.Creator,
+		database.PartyMembership_Status_Active,
+	)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(parties) == 0 {
+		log.Print("parties is empty")
+		return
+	}
+	for _, eachParty := range parties {
+		if eachParty.Status != database.PartyMembership_Status_Active {
+			t.Errorf("party %s has status %s", eachParty.Name, eachParty.Status)
+		}
+		log.Printf("party : %+v", eachParty)
+	}
+	log.Printf("total parties joined : %d", len(parties))
+}
+
+func TestStartPartyReadyCheck(t *testing.T) {
+	err := dbConn.StartPartyReadyCheck(
+		context.Background(),
+		testParty.Name,
+	)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPutPartyReadyResponse(t *testing.T) {
+	err := dbConn.PutPartyReadyResponse(
+		context.Background(),
+		testParty.Name,
+		testParty.Creator,
+		database.PartyMembership_Ready_Ready,
+	)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUpdatePartyState(t *testing.T) {
+	err := dbConn.UpdatePartyState(
+		context.Background(),
+		testParty.Name,
+		database.Party_State_ReadyCheck,
+		database.Party_State_Queued,
+	)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPutPartyMessage(t *testing.T) {
+	message, err := database.NewPartyMessage(testParty.Name, testParty.Creator, "hello")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = dbConn.PutPartyMessage(
+		context.Background(),
+		message,
+		100,
+	)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if message.Id == 0 {
+		t.Error("message id is not set")
+	}
+}
+
+func TestGetPartyMessages(t *testing.T) {
+	messages, err := dbConn.GetPartyMessages(
+		context.Background(),
+		testParty.Name,
+		0,
+		50,
+	)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(messages) == 0 {
+		log.Print("messages is empty")
+		return
+	}
+	for _, eachMessage := range messages {
+		log.Printf("message : %+v", eachMessage)
+	}
+	log.Printf("total messages : %d", len(messages))
+}
+
 var testPartyMembership = &database.PartyMembership{
 	PartyName: "party1",
 	UserName:  "user1",
@@ -253,15 +841,50 @@ func TestGetPartyMembership(t *testing.T) {
 	log.Printf("membership : %+v", membership)
 }
 
+func TestDeleteExpiredPartyInvitations(t *testing.T) {
+	purged, err := dbConn.DeleteExpiredPartyInvitations(
+		context.Background(),
+		time.Now().Add(-time.Hour*24*7),
+	)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	log.Printf("purged invitations : %d", purged)
+}
+
 func TestGetPendingFriendRequests(t *testing.T) {
-	requests, err := dbConn.GetPendingFriendRequests(
+	requestsPage, err := dbConn.GetPendingFriendRequests(
 		context.Background(),
 		"user_2",
+		nil,
+	)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	requests := requestsPage.Items
+	if len(requests) == 0 {
+		log.Print("requests is empty")
+		return
+	}
+	for _, eachRequest := range requests {
+		log.Printf("request : %+v", eachRequest)
+	}
+	log.Printf("total requests : %d", len(requests))
+}
+
+func TestGetSentFriendRequests(t *testing.T) {
+	requestsPage, err := dbConn.GetSentFriendRequests(
+		context.Background(),
+		"user_1",
+		nil,
 	)
 	if err != nil {
 		t.Error(err)
 		return
 	}
+	requests := requestsPage.Items
 	if len(requests) == 0 {
 		log.Print("requests is empty")
 		return
@@ -272,6 +895,29 @@ func TestGetPendingFriendRequests(t *testing.T) {
 	log.Printf("total requests : %d", len(requests))
 }
 
+func TestGetUserFriendsPagination(t *testing.T) {
+	opts := &database.ListOptions{Limit: 1, Sort: database.ListSort_Name}
+	seen := make(map[string]bool)
+	for {
+		friendsPage, err := dbConn.GetUserFriends(context.Background(), "user1", opts)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		for _, eachFriend := range friendsPage.Items {
+			if seen[eachFriend.Name] {
+				t.Errorf("friend %s is returned twice", eachFriend.Name)
+			}
+			seen[eachFriend.Name] = true
+		}
+		if friendsPage.NextCursor == "" {
+			break
+		}
+		opts.Cursor = friendsPage.NextCursor
+	}
+	log.Printf("total friends paged : %d", len(seen))
+}
+
 func TestGetUserFriendsList(t *testing.T) {
 	friendsMap, err := dbConn.GetUserFriendsList(context.Background())
 	if err != nil {
@@ -306,6 +952,25 @@ func TestGetPartyMembers(t *testing.T) {
 	log.Printf("total members : %d", len(members))
 }
 
+func TestGetPartyMemberships(t *testing.T) {
+	memberships, err := dbConn.GetPartyMemberships(
+		context.Background(),
+		testParty.Name,
+	)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(memberships) == 0 {
+		log.Print("memberships is empty")
+		return
+	}
+	for _, eachMembership := range memberships {
+		log.Printf("membership : %+v", eachMembership)
+	}
+	log.Printf("total memberships : %d", len(memberships))
+}
+
 func TestGetAllPartyMembers(t *testing.T) {
 	members, err := dbConn.GetAllPartyMembers(context.Background())
 	if err != nil {
@@ -321,3 +986,70 @@ func TestGetAllPartyMembers(t *testing.T) {
 	}
 	log.Printf("total parties : %d", len(members))
 }
+
+func TestGetDirectMessageContacts(t *testing.T) {
+	contacts, err := dbConn.GetDirectMessageContacts(
+		context.Background(),
+		"user1",
+	)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	log.Printf("contacts : %+v", contacts)
+}
+
+func TestGetUserPartyMessages(t *testing.T) {
+	messages, err := dbConn.GetUserPartyMessages(
+		context.Background(),
+		"user1",
+		0,
+		10,
+	)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	log.Printf("total party messages : %d", len(messages))
+}
+
+func TestDeleteUser(t *testing.T) {
+	err := dbConn.PutUser(
+		context.Background(),
+		&database.User{
+			Name:      "user_deleted",
+			CreatedAt: time.Now(),
+		},
+	)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	deletion, err := dbConn.DeleteUser(
+		context.Background(),
+		"user_deleted",
+		true,
+	)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	log.Printf("deletion : %+v", deletion)
+
+	_, err = dbConn.GetUser(context.Background(), "user_deleted")
+	if err != database.Err_NotFound {
+		t.Error("soft deleted user is still found")
+	}
+}
+
+func TestPurgeDeletedUsers(t *testing.T) {
+	purged, err := dbConn.PurgeDeletedUsers(
+		context.Background(),
+		time.Now(),
+	)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	log.Printf("total purged users : %d", len(purged))
+}
@@ -5,4 +5,5 @@ import "errors"
 var (
 	Err_NotFound            = errors.New("not found")
 	Err_DuplicatePrimaryKey = errors.New("duplicate primary key")
+	Err_StateChanged        = errors.New("state changed")
 )
@@ -0,0 +1,157 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// exportPageSize is the page size used while collecting the lists of an export
+const exportPageSize = ListMaxLimit
+
+// UserDataExport is everything stored about a user
+type UserDataExport struct {
+	ExportedAt             time.Time        `json:"exported_at"`
+	User                   *User            `json:"user"`
+	Profile                *Profile         `json:"profile,omitempty"`
+	Friends                []*Friend        `json:"friends"`
+	FriendGroups           []*FriendGroup   `json:"friend_groups"`
+	ReceivedFriendRequests []*Friendship    `json:"received_friend_requests"`
+	SentFriendRequests     []*Friendship    `json:"sent_friend_requests"`
+	Blocks                 []*Block         `json:"blocks"`
+	CreatedParties         []*Party         `json:"created_parties"`
+	JoinedParties          []*UserParty     `json:"joined_parties"`
+	PartyInvitations       []*UserParty     `json:"party_invitations"`
+	PartyMessages          []*PartyMessage  `json:"party_messages"`
+	DirectMessages         []*DirectMessage `json:"direct_messages"`
+	Notifications          []*Notification  `json:"notifications"`
+}
+
+// ExportUserData assembles the export of a user through the Database interface, so it works with every backend
+func ExportUserData(ctx context.Context, db Database, userName string) (*UserDataExport, error) {
+	user, err := db.GetUser(ctx, userName)
+	if err != nil {
+		return nil, err
+	}
+	export := &UserDataExport{
+		ExportedAt: time.Now(),
+		User:       user,
+	}
+
+	export.Profile, err = db.GetProfile(ctx, userName)
+	if err != nil && err != Err_NotFound {
+		return nil, fmt.Errorf("getting profile: %s", err.Error())
+	}
+
+	export.Friends, err = collectPages(func(cursor string) (*Page[*Friend], error) {
+		return db.GetUserFriends(ctx, userName, &ListOptions{Limit: exportPageSize, Cursor: cursor, Sort: ListSort_CreatedAt})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("getting friends: %s", err.Error())
+	}
+	export.FriendGroups, err = db.GetFriendGroups(ctx, userName)
+	if err != nil {
+		return nil, fmt.Errorf("getting friend groups: %s", err.Error())
+	}
+	export.ReceivedFriendRequests, err = collectPages(func(cursor string) (*Page[*Friendship], error) {
+		return db.GetPendingFriendRequests(ctx, userName, &ListOptions{Limit: exportPageSize, Cursor: cursor, Sort: ListSort_CreatedAt})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("getting received friend requests: %s", err.Error())
+	}
+	export.SentFriendRequests, err = collectPages(func(cursor string) (*Page[*Friendship], error) {
+		return db.GetSentFriendRequests(ctx, userName, &ListOptions{Limit: exportPageSize, Cursor: cursor, Sort: ListSort_CreatedAt})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("getting sent friend requests: %s", err.Error())
+	}
+
+	export.Blocks, err = db.GetBlocks(ctx, userName)
+	if err != nil {
+		return nil, fmt.Errorf("getting blocks: %s", err.Error())
+	}
+
+	export.CreatedParties, err = collectPages(func(cursor string) (*Page[*Party], error) {
+		return db.GetCreatedParties(ctx, userName, &ListOptions{Limit: exportPageSize, Cursor: cursor, Sort: ListSort_CreatedAt})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("getting created parties: %s", err.Error())
+	}
+	export.JoinedParties, err = db.GetUserParties(ctx, userName, PartyMembership_Status_Active)
+	if err != nil {
+		return nil, fmt.Errorf("getting joined parties: %s", err.Error())
+	}
+	export.PartyInvitations, err = db.GetUserParties(ctx, userName, PartyMembership_Status_Invited)
+	if err != nil {
+		return nil, fmt.Errorf("getting party invitations: %s", err.Error())
+	}
+
+	export.PartyMessages, err = collectMessages(func(beforeId int32) ([]*PartyMessage, error) {
+		return db.GetUserPartyMessages(ctx, userName, beforeId, exportPageSize)
+	}, func(message *PartyMessage) int32 {
+		return message.Id
+	})
+	if err != nil {
+		return nil, fmt.Errorf("getting party messages: %s", err.Error())
+	}
+
+	contacts, err := db.GetDirectMessageContacts(ctx, userName)
+	if err != nil {
+		return nil, fmt.Errorf("getting direct message contacts: %s", err.Error())
+	}
+	export.DirectMessages = make([]*DirectMessage, 0)
+	for _, eachContact := range contacts {
+		messages, err := collectMessages(func(beforeId int32) ([]*DirectMessage, error) {
+			return db.GetDirectMessages(ctx, userName, eachContact, beforeId, exportPageSize)
+		}, func(message *DirectMessage) int32 {
+			return message.Id
+		})
+		if err != nil {
+			return nil, fmt.Errorf("getting direct messages with %s: %s", eachContact, err.Error())
+		}
+		export.DirectMessages = append(export.DirectMessages, messages...)
+	}
+
+	export.Notifications, err = collectPages(func(cursor string) (*Page[*Notification], error) {
+		return db.GetNotifications(ctx, userName, &ListOptions{Limit: exportPageSize, Cursor: cursor, Sort: ListSort_CreatedAt})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("getting notifications: %s", err.Error())
+	}
+
+	return export, nil
+}
+
+// collectPages follows the cursors of a paginated list until the last page
+func collectPages[T any](fetch func(cursor string) (*Page[T], error)) ([]T, error) {
+	items := make([]T, 0)
+	cursor := ""
+	for {
+		page, err := fetch(cursor)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page.Items...)
+		if page.NextCursor == "" {
+			return items, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// collectMessages pages backwards through messages, which are returned latest first
+func collectMessages[T any](fetch func(beforeId int32) ([]T, error), id func(T) int32) ([]T, error) {
+	items := make([]T, 0)
+	var beforeId int32
+	for {
+		messages, err := fetch(beforeId)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, messages...)
+		if len(messages) < exportPageSize {
+			return items, nil
+		}
+		beforeId = id(messages[len(messages)-1])
+	}
+}
@@ -0,0 +1,110 @@
+package database_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"socialite/database"
+)
+
+// exportDatabase serves the methods used by an export from memory, the rest are not implemented
+type exportDatabase struct {
+	database.Database
+	friends        []*database.Friend
+	directMessages []*database.DirectMessage
+}
+
+func (db *exportDatabase) GetUser(ctx context.Context, name string) (*database.User, error) {
+	return &database.User{Name: name, CreatedAt: time.Now()}, nil
+}
+
+func (db *exportDatabase) GetProfile(ctx context.Context, userName string) (*database.Profile, error) {
+	return nil, database.Err_NotFound
+}
+
+func (db *exportDatabase) GetUserFriends(ctx context.Context, name string, opts *database.ListOptions) (*database.Page[*database.Friend], error) {
+	start := 0
+	if opts.Cursor != "" {
+		start, _ = strconv.Atoi(opts.Cursor)
+	}
+	end := min(start+opts.Limit, len(db.friends))
+	page := &database.Page[*database.Friend]{Items: db.friends[start:end]}
+	if end < len(db.friends) {
+		page.NextCursor = strconv.Itoa(end)
+	}
+	return page, nil
+}
+
+func (db *exportDatabase) GetFriendGroups(ctx context.Context, userName string) ([]*database.FriendGroup, error) {
+	return nil, nil
+}
+
+func (db *exportDatabase) GetPendingFriendRequests(ctx context.Context, userName string, opts *database.ListOptions) (*database.Page[*database.Friendship], error) {
+	return &database.Page[*database.Friendship]{}, nil
+}
+
+func (db *exportDatabase) GetSentFriendRequests(ctx context.Context, userName string, opts *database.ListOptions) (*database.Page[*database.Friendship], error) {
+	return &database.Page[*database.Friendship]{}, nil
+}
+
+func (db *exportDatabase) GetBlocks(ctx context.Context, blocker string) ([]*database.Block, error) {
+	return nil, nil
+}
+
+func (db *exportDatabase) GetCreatedParties(ctx context.Context, userName string, opts *database.ListOptions) (*database.Page[*database.Party], error) {
+	return &database.Page[*database.Party]{}, nil
+}
+
+func (db *exportDatabase) GetUserParties(ctx context.Context, userName string, status database.PartyMembership_Status) ([]*database.UserParty, error) {
+	return nil, nil
+}
+
+func (db *exportDatabase) GetUserPartyMessages(ctx context.Context, userName string, beforeId int32, limit int) ([]*database.PartyMessage, error) {
+	return nil, nil
+}
+
+func (db *exportDatabase) GetDirectMessageContacts(ctx context.Context, userName string) ([]string, error) {
+	return []string{"user2"}, nil
+}
+
+func (db *exportDatabase) GetDirectMessages(ctx context.Context, user1, user2 string, beforeId int32, limit int) ([]*database.DirectMessage, error) {
+	// messages are stored oldest first and returned latest first
+	messages := make([]*database.DirectMessage, 0, limit)
+	for i := len(db.directMessages) - 1; i >= 0 && len(messages) < limit; i-- {
+		if beforeId == 0 || db.directMessages[i].Id < beforeId {
+			messages = append(messages, db.directMessages[i])
+		}
+	}
+	return messages, nil
+}
+
+func (db *exportDatabase) GetNotifications(ctx context.Context, userName string, opts *database.ListOptions) (*database.Page[*database.Notification], error) {
+	return &database.Page[*database.Notification]{}, nil
+}
+
+func TestExportUserData(t *testing.T) {
+	db := &exportDatabase{}
+	for i := 0; i < database.ListMaxLimit*2+1; i++ {
+		db.friends = append(db.friends, &database.Friend{User: database.User{Name: "friend" + strconv.Itoa(i)}})
+	}
+	for i := 1; i <= database.ListMaxLimit+1; i++ {
+		db.directMessages = append(db.directMessages, &database.DirectMessage{Id: int32(i), Sender: "user1", Recipient: "user2"})
+	}
+
+	export, err := database.ExportUserData(context.Background(), db, "user1")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(export.Friends) != len(db.friends) {
+		t.Errorf("exported %d friends, expected %d", len(export.Friends), len(db.friends))
+	}
+	if len(export.DirectMessages) != len(db.directMessages) {
+		t.Errorf("exported %d direct messages, expected %d", len(export.DirectMessages), len(db.directMessages))
+	}
+	if export.Profile != nil {
+		t.Error("missing profile is exported")
+	}
+}
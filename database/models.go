@@ -1,9 +1,20 @@
 package database
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/url"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"socialite/validation"
+
+	"golang.org/x/text/language"
 )
 
 type Friendship_Status string
@@ -15,20 +26,163 @@ const (
 )
 
 type User struct {
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"created_at"`
+	Name      string          `json:"name"`
+	CreatedAt time.Time       `json:"created_at"`
+	Profile   *ProfileSummary `json:"profile,omitempty"`
 }
 
+// NewUser is for a user being registered, reserved names are rejected
 func NewUser(name string) (*User, error) {
-	if name == "" {
-		return nil, errors.New("user name is empty")
+	name, err := validation.NewName(name)
+	if err != nil {
+		return nil, fmt.Errorf("user %s", err.Error())
+	}
+	return &User{
+		Name:      name,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// NewExistingUser is for a user already registered, e.g. from the auth token, whose name may have been reserved since
+func NewExistingUser(name string) (*User, error) {
+	name, err := validation.Name(name)
+	if err != nil {
+		return nil, fmt.Errorf("user %s", err.Error())
 	}
 	return &User{
-		Name:      strings.ToLower(name),
+		Name:      name,
 		CreatedAt: time.Now(),
 	}, nil
 }
 
+const (
+	ProfileDisplayNameMaxLength = 32
+	ProfileAvatarUrlMaxLength   = 2048
+	ProfileBioMaxLength         = 300
+	ProfileRegionMaxLength      = 32
+)
+
+// ProfileSummary is the part of a profile shown next to the user in lists
+type ProfileSummary struct {
+	DisplayName string `json:"display_name,omitempty"`
+	AvatarUrl   string `json:"avatar_url,omitempty"`
+}
+
+// NewProfileSummary returns nil when there is nothing to show
+func NewProfileSummary(displayName, avatarUrl string) *ProfileSummary {
+	if displayName == "" && avatarUrl == "" {
+		return nil
+	}
+	return &ProfileSummary{
+		DisplayName: displayName,
+		AvatarUrl:   avatarUrl,
+	}
+}
+
+// Profile holds the editable details of a user, the user name itself never changes
+type Profile struct {
+	UserName string `json:"user_name"`
+	ProfileSummary
+	Bio       string    `json:"bio,omitempty"`
+	Region    string    `json:"region,omitempty"`
+	Language  string    `json:"language,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func NewProfile(userName string) (*Profile, error) {
+	if userName == "" {
+		return nil, errors.New("user name is empty")
+	}
+	return &Profile{
+		UserName:  strings.ToLower(userName),
+		UpdatedAt: time.Now(),
+	}, nil
+}
+
+// Validate trims and checks the profile fields, the language is stored as its canonical tag
+func (p *Profile) Validate() error {
+	p.DisplayName = strings.TrimSpace(p.DisplayName)
+	if utf8.RuneCountInString(p.DisplayName) > ProfileDisplayNameMaxLength {
+		return fmt.Errorf("display name must be at most %d characters", ProfileDisplayNameMaxLength)
+	}
+	for _, eachRune := range p.DisplayName {
+		if unicode.IsControl(eachRune) {
+			return errors.New("display name has invalid characters")
+		}
+	}
+
+	p.AvatarUrl = strings.TrimSpace(p.AvatarUrl)
+	if p.AvatarUrl != "" {
+		if len(p.AvatarUrl) > ProfileAvatarUrlMaxLength {
+			return fmt.Errorf("avatar url must be at most %d characters", ProfileAvatarUrlMaxLength)
+		}
+		avatarUrl, err := url.Parse(p.AvatarUrl)
+		if err != nil || (avatarUrl.Scheme != "http" && avatarUrl.Scheme != "https") || avatarUrl.Host == "" {
+			return errors.New("avatar url must be an http or https url")
+		}
+	}
+
+	p.Bio = strings.TrimSpace(p.Bio)
+	if utf8.RuneCountInString(p.Bio) > ProfileBioMaxLength {
+		return fmt.Errorf("bio must be at most %d characters", ProfileBioMaxLength)
+	}
+
+	p.Region = strings.ToLower(strings.TrimSpace(p.Region))
+	if len(p.Region) > ProfileRegionMaxLength {
+		return fmt.Errorf("region must be at most %d characters", ProfileRegionMaxLength)
+	}
+	for _, eachRune := range p.Region {
+		if !(eachRune >= 'a' && eachRune <= 'z') && !(eachRune >= '0' && eachRune <= '9') && eachRune != '-' {
+			return errors.New("region must only have letters, digits and hyphens")
+		}
+	}
+
+	p.Language = strings.TrimSpace(p.Language)
+	if p.Language != "" {
+		tag, err := language.Parse(p.Language)
+		if err != nil {
+			return errors.New("language must be a valid language tag")
+		}
+		p.Language = tag.String()
+	}
+	return nil
+}
+
+// UserDeletion lists what went away along with a deleted user,
+// so the state kept outside the database can be cleaned up too
+type UserDeletion struct {
+	UserName string `json:"user_name"`
+	// users who had a friendship or pending request with the user
+	Friends []string `json:"friends"`
+	// parties the user was a member of, which still exist
+	Parties []string `json:"parties"`
+	// parties created by the user, handed over to their oldest member
+	TransferredParties map[string]string `json:"transferred_parties"`
+	// parties created by the user without any other member
+	DisbandedParties []string `json:"disbanded_parties"`
+}
+
+type User_Relationship string
+
+const (
+	User_Relationship_Friend          User_Relationship = "friend"
+	User_Relationship_RequestSent     User_Relationship = "request_sent"
+	User_Relationship_RequestReceived User_Relationship = "request_received"
+	User_Relationship_None            User_Relationship = "none"
+)
+
+type UserSearchResult struct {
+	User
+	Relationship User_Relationship `json:"relationship"`
+}
+
+// FriendSuggestion is a user who is not a friend yet, ranked by what they have in common with the user
+type FriendSuggestion struct {
+	User
+	MutualFriends int `json:"mutual_friends"`
+	SharedParties int `json:"shared_parties"`
+}
+
 type Friendship struct {
 	Id        int32             `json:"id"`
 	User1     string            `json:"user1"`
@@ -38,6 +192,17 @@ type Friendship struct {
 	UpdatedAt time.Time         `json:"updated_at"`
 }
 
+type Friend struct {
+	User
+	FriendshipId int32     `json:"friendship_id"`
+	FriendsSince time.Time `json:"friends_since"`
+	Online       bool      `json:"online"`
+	// private to the user listing their friends
+	Nickname string  `json:"nickname,omitempty"`
+	Favorite bool    `json:"favorite"`
+	GroupIds []int32 `json:"group_ids"`
+}
+
 func NewFriendship(user1, user2 string) (*Friendship, error) {
 	if user1 == "" || user2 == "" {
 		return nil, errors.New("user name is empty")
@@ -51,25 +216,157 @@ func NewFriendship(user1, user2 string) (*Friendship, error) {
 	}, nil
 }
 
-type Party struct {
+const (
+	FriendNicknameMaxLength  = 32
+	FriendGroupNameMaxLength = 32
+	FriendGroupsMaxCount     = 50
+)
+
+// FriendMetadata is what a user keeps about one of their friends, the friend never sees it
+type FriendMetadata struct {
+	FriendshipId int32     `json:"friendship_id"`
+	UserName     string    `json:"user_name"`
+	Nickname     string    `json:"nickname"`
+	Favorite     bool      `json:"favorite"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func NewFriendMetadata(friendshipId int32, userName string) (*FriendMetadata, error) {
+	if friendshipId == 0 || userName == "" {
+		return nil, errors.New("friendship id or user name is empty")
+	}
+	return &FriendMetadata{
+		FriendshipId: friendshipId,
+		UserName:     strings.ToLower(userName),
+		UpdatedAt:    time.Now(),
+	}, nil
+}
+
+// Validate trims and checks the nickname
+func (m *FriendMetadata) Validate() error {
+	m.Nickname = strings.TrimSpace(m.Nickname)
+	if utf8.RuneCountInString(m.Nickname) > FriendNicknameMaxLength {
+		return fmt.Errorf("nickname must be at most %d characters", FriendNicknameMaxLength)
+	}
+	for _, eachRune := range m.Nickname {
+		if unicode.IsControl(eachRune) {
+			return errors.New("nickname has invalid characters")
+		}
+	}
+	return nil
+}
+
+// FriendGroup is a named set of friends, like "Raid team", private to the user who made it
+type FriendGroup struct {
+	Id        int32     `json:"id"`
+	UserName  string    `json:"user_name"`
 	Name      string    `json:"name"`
-	Creator   string    `json:"creator"`
+	Members   []string  `json:"members"`
 	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func NewFriendGroup(userName, name string) (*FriendGroup, error) {
+	if userName == "" {
+		return nil, errors.New("user name is empty")
+	}
+	group := &FriendGroup{
+		UserName:  strings.ToLower(userName),
+		Name:      name,
+		Members:   []string{},
+		CreatedAt: time.Now(),
+	}
+	err := group.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// Validate trims and checks the group name, names are unique per user regardless of case
+func (g *FriendGroup) Validate() error {
+	g.Name = strings.TrimSpace(g.Name)
+	if g.Name == "" {
+		return errors.New("group name is empty")
+	}
+	if utf8.RuneCountInString(g.Name) > FriendGroupNameMaxLength {
+		return fmt.Errorf("group name must be at most %d characters", FriendGroupNameMaxLength)
+	}
+	for _, eachRune := range g.Name {
+		if unicode.IsControl(eachRune) {
+			return errors.New("group name has invalid characters")
+		}
+	}
+	return nil
+}
+
+type Block struct {
+	Blocker   string    `json:"blocker"`
+	Blocked   string    `json:"blocked"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewBlock(blocker, blocked string) (*Block, error) {
+	if blocker == "" || blocked == "" {
+		return nil, errors.New("user name is empty")
+	}
+	return &Block{
+		Blocker:   strings.ToLower(blocker),
+		Blocked:   strings.ToLower(blocked),
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+type Party_State string
+
+const (
+	Party_State_Idle       Party_State = "idle"
+	Party_State_ReadyCheck Party_State = "ready_check"
+	Party_State_Queued     Party_State = "queued"
+	Party_State_InMatch    Party_State = "in_match"
+)
+
+// partyStateTransitions lists the states each party state can move to
+var partyStateTransitions = map[Party_State][]Party_State{
+	Party_State_Idle:       {Party_State_ReadyCheck},
+	Party_State_ReadyCheck: {Party_State_Idle, Party_State_Queued},
+	Party_State_Queued:     {Party_State_Idle, Party_State_InMatch},
+	Party_State_InMatch:    {Party_State_Idle},
+}
+
+func (s Party_State) CanTransitionTo(next Party_State) bool {
+	for _, eachState := range partyStateTransitions[s] {
+		if eachState == next {
+			return true
+		}
+	}
+	return false
+}
+
+type Party struct {
+	Name           string      `json:"name"`
+	Creator        string      `json:"creator"`
+	State          Party_State `json:"state"`
+	StateUpdatedAt time.Time   `json:"state_updated_at"`
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at"`
 }
 
 func NewParty(name, creator string) (*Party, error) {
-	if name == "" {
-		return nil, errors.New("party name is empty")
+	name, err := validation.NewName(name)
+	if err != nil {
+		return nil, fmt.Errorf("party %s", err.Error())
 	}
-	if creator == "" {
-		return nil, errors.New("creator name is empty")
+	creator, err = validation.Name(creator)
+	if err != nil {
+		return nil, fmt.Errorf("creator %s", err.Error())
 	}
 
 	return &Party{
-		Name:      strings.ToLower(name),
-		Creator:   strings.ToLower(creator),
-		CreatedAt: time.Now(),
+		Name:           name,
+		Creator:        creator,
+		State:          Party_State_Idle,
+		StateUpdatedAt: time.Now(),
+		CreatedAt:      time.Now(),
 	}, nil
 }
 
@@ -80,10 +377,26 @@ const (
 	PartyMembership_Status_Active  PartyMembership_Status = "active"
 )
 
+type PartyMembership_Role string
+
+const (
+	PartyMembership_Role_Creator PartyMembership_Role = "creator"
+	PartyMembership_Role_Member  PartyMembership_Role = "member"
+)
+
+type PartyMembership_Ready string
+
+const (
+	PartyMembership_Ready_Pending  PartyMembership_Ready = "pending"
+	PartyMembership_Ready_Ready    PartyMembership_Ready = "ready"
+	PartyMembership_Ready_NotReady PartyMembership_Ready = "not_ready"
+)
+
 type PartyMembership struct {
 	PartyName string                 `json:"party_name"`
 	UserName  string                 `json:"user_name"`
 	Status    PartyMembership_Status `json:"status"`
+	Ready     PartyMembership_Ready  `json:"ready,omitempty"`
 	CreatedAt time.Time              `json:"created_at"`
 	UpdatedAt time.Time              `json:"updated_at"`
 }
@@ -103,3 +416,177 @@ func NewPartyMembership(partyName, userName string) (*PartyMembership, error) {
 		UpdatedAt: time.Now(),
 	}, nil
 }
+
+type UserParty struct {
+	Party
+	Status              PartyMembership_Status `json:"status"`
+	Role                PartyMembership_Role   `json:"role"`
+	MembershipCreatedAt time.Time              `json:"membership_created_at"`
+}
+
+type PartyMessage struct {
+	Id        int32     `json:"id"`
+	PartyName string    `json:"party_name"`
+	UserName  string    `json:"user_name"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewPartyMessage(partyName, userName, text string) (*PartyMessage, error) {
+	if partyName == "" {
+		return nil, errors.New("party name is empty")
+	}
+	if userName == "" {
+		return nil, errors.New("user name is empty")
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, errors.New("message text is empty")
+	}
+	return &PartyMessage{
+		PartyName: strings.ToLower(partyName),
+		UserName:  strings.ToLower(userName),
+		Text:      text,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+type DirectMessage struct {
+	Id        int32      `json:"id"`
+	Sender    string     `json:"sender"`
+	Recipient string     `json:"recipient"`
+	Text      string     `json:"text"`
+	CreatedAt time.Time  `json:"created_at"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+}
+
+func NewDirectMessage(sender, recipient, text string) (*DirectMessage, error) {
+	if sender == "" || recipient == "" {
+		return nil, errors.New("user name is empty")
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, errors.New("message text is empty")
+	}
+	return &DirectMessage{
+		Sender:    strings.ToLower(sender),
+		Recipient: strings.ToLower(recipient),
+		Text:      text,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+type Notification_Type string
+
+const (
+	Notification_Type_FriendRequest         Notification_Type = "friend_request"
+	Notification_Type_FriendRequestAccepted Notification_Type = "friend_request_accepted"
+	Notification_Type_PartyInvite           Notification_Type = "party_invite"
+	Notification_Type_PartyInviteAccepted   Notification_Type = "party_invite_accepted"
+)
+
+// Notification tells a user about something another user did, it stays in their inbox until purged with the user
+type Notification struct {
+	Id       int32             `json:"id"`
+	UserName string            `json:"user_name"`
+	Type     Notification_Type `json:"type"`
+	Actor    string            `json:"actor"`
+	// the party of party notifications
+	PartyName string `json:"party_name,omitempty"`
+	// the friendship of friend notifications, which is the request to accept
+	FriendshipId int32      `json:"friendship_id,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ReadAt       *time.Time `json:"read_at,omitempty"`
+}
+
+func NewNotification(userName string, notificationType Notification_Type, actor string) (*Notification, error) {
+	if userName == "" || actor == "" {
+		return nil, errors.New("user name is empty")
+	}
+	if notificationType == "" {
+		return nil, errors.New("notification type is empty")
+	}
+	return &Notification{
+		UserName:  strings.ToLower(userName),
+		Type:      notificationType,
+		Actor:     strings.ToLower(actor),
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+const (
+	WebhookUrlMaxLength    = 2048
+	WebhookSecretMinLength = 16
+)
+
+// WebhookSubscription receives signed posts of the events of its types
+type WebhookSubscription struct {
+	Id         int32    `json:"id"`
+	Url        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	// only returned when the subscription is created
+	Secret    string    `json:"secret,omitempty"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NewWebhookSubscription validates the url and secret, a random secret is made when none is given
+func NewWebhookSubscription(webhookUrl string, eventTypes []string, secret string) (*WebhookSubscription, error) {
+	webhookUrl = strings.TrimSpace(webhookUrl)
+	if len(webhookUrl) > WebhookUrlMaxLength {
+		return nil, fmt.Errorf("url must be at most %d characters", WebhookUrlMaxLength)
+	}
+	parsedUrl, err := url.Parse(webhookUrl)
+	if err != nil || (parsedUrl.Scheme != "http" && parsedUrl.Scheme != "https") || parsedUrl.Host == "" {
+		return nil, errors.New("url must be an http or https url")
+	}
+	if len(eventTypes) == 0 {
+		return nil, errors.New("event types are empty")
+	}
+
+	if secret == "" {
+		secretBytes := make([]byte, 32)
+		_, err = rand.Read(secretBytes)
+		if err != nil {
+			return nil, fmt.Errorf("generating secret: %s", err.Error())
+		}
+		secret = hex.EncodeToString(secretBytes)
+	}
+	if len(secret) < WebhookSecretMinLength {
+		return nil, fmt.Errorf("secret must be at least %d characters", WebhookSecretMinLength)
+	}
+
+	return &WebhookSubscription{
+		Url:        webhookUrl,
+		EventTypes: eventTypes,
+		Secret:     secret,
+		Active:     true,
+		CreatedAt:  time.Now(),
+	}, nil
+}
+
+// WebhookDelivery logs one attempt at posting an event to a subscription
+type WebhookDelivery struct {
+	Id             int32  `json:"id"`
+	SubscriptionId int32  `json:"subscription_id"`
+	EventId        string `json:"event_id"`
+	EventType      string `json:"event_type"`
+	Attempt        int    `json:"attempt"`
+	// zero when no response was received
+	StatusCode int       `json:"status_code"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// WebhookDeadLetter keeps an event which could not be delivered to a subscription after every attempt
+type WebhookDeadLetter struct {
+	Id             int32           `json:"id"`
+	SubscriptionId int32           `json:"subscription_id"`
+	EventId        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Attempts       int             `json:"attempts"`
+	Error          string          `json:"error"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
@@ -0,0 +1,129 @@
+package database_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"socialite/database"
+)
+
+func TestProfileValidate(t *testing.T) {
+	profile := &database.Profile{
+		UserName: "user1",
+		ProfileSummary: database.ProfileSummary{
+			DisplayName: "  User One ",
+			AvatarUrl:   "https://example.com/avatar.png",
+		},
+		Region:   "EU-West",
+		Language: "en-us",
+	}
+	err := profile.Validate()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if profile.DisplayName != "User One" {
+		t.Errorf("display name is %q, expected it trimmed", profile.DisplayName)
+	}
+	if profile.Region != "eu-west" {
+		t.Errorf("region is %q, expected it lowercased", profile.Region)
+	}
+	if profile.Language != "en-US" {
+		t.Errorf("language is %q, expected canonical tag", profile.Language)
+	}
+
+	invalidProfiles := map[string]*database.Profile{
+		"long display name": {ProfileSummary: database.ProfileSummary{DisplayName: strings.Repeat("a", database.ProfileDisplayNameMaxLength+1)}},
+		"avatar scheme":     {ProfileSummary: database.ProfileSummary{AvatarUrl: "javascript:alert(1)"}},
+		"region characters": {Region: "eu west"},
+		"language tag":      {Language: "not a language"},
+	}
+	for name, eachProfile := range invalidProfiles {
+		if eachProfile.Validate() == nil {
+			t.Errorf("%s is accepted", name)
+		}
+	}
+}
+
+func TestFriendGroupValidate(t *testing.T) {
+	group, err := database.NewFriendGroup("User1", "  Raid team ")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if group.Name != "Raid team" {
+		t.Errorf("group name is %q, expected it trimmed", group.Name)
+	}
+	if group.UserName != "user1" {
+		t.Errorf("user name is %q, expected it lowercased", group.UserName)
+	}
+
+	for reason, name := range map[string]string{
+		"empty":   "  ",
+		"long":    strings.Repeat("a", database.FriendGroupNameMaxLength+1),
+		"control": "raid\nteam",
+	} {
+		if _, err := database.NewFriendGroup("user1", name); err == nil {
+			t.Errorf("%s group name is accepted", reason)
+		}
+	}
+}
+
+func TestFriendMetadataValidate(t *testing.T) {
+	metadata, err := database.NewFriendMetadata(1, "user1")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	metadata.Nickname = strings.Repeat("a", database.FriendNicknameMaxLength+1)
+	if metadata.Validate() == nil {
+		t.Error("long nickname is accepted")
+	}
+	metadata.Nickname = " Tank "
+	if err := metadata.Validate(); err != nil {
+		t.Error(err)
+	}
+	if metadata.Nickname != "Tank" {
+		t.Errorf("nickname is %q, expected it trimmed", metadata.Nickname)
+	}
+}
+
+func TestNewWebhookSubscription(t *testing.T) {
+	subscription, err := database.NewWebhookSubscription(" https://example.com/hook ", []string{"friend.request_sent"}, "")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if subscription.Url != "https://example.com/hook" {
+		t.Errorf("url is %q, expected it trimmed", subscription.Url)
+	}
+	if len(subscription.Secret) < database.WebhookSecretMinLength {
+		t.Errorf("generated secret %q is too short", subscription.Secret)
+	}
+
+	invalidSubscriptions := map[string][]string{
+		"url scheme":  {"ftp://example.com/hook", "friend.request_sent", "0123456789abcdef"},
+		"no types":    {"https://example.com/hook", "", "0123456789abcdef"},
+		"weak secret": {"https://example.com/hook", "friend.request_sent", "secret"},
+	}
+	for name, fields := range invalidSubscriptions {
+		eventTypes := []string{}
+		if fields[1] != "" {
+			eventTypes = append(eventTypes, fields[1])
+		}
+		if _, err := database.NewWebhookSubscription(fields[0], eventTypes, fields[2]); err == nil {
+			t.Errorf("%s is accepted", name)
+		}
+	}
+}
+
+func TestWithActor(t *testing.T) {
+	if actor := database.ActorFromContext(context.Background()); actor != "" {
+		t.Errorf("actor of a plain context is %q", actor)
+	}
+	ctx := database.WithActor(context.Background(), "user1")
+	if actor := database.ActorFromContext(ctx); actor != "user1" {
+		t.Errorf("actor is %q, expected user1", actor)
+	}
+}
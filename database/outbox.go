@@ -0,0 +1,20 @@
+package database
+
+import "context"
+
+// OutboxBatchSize is how many outbox events are published in one go
+const OutboxBatchSize = 100
+
+type actorKey struct{}
+
+// WithActor tells the mutations run with the context which user caused them,
+// so the events they write to the outbox carry the actor
+func WithActor(ctx context.Context, userName string) context.Context {
+	return context.WithValue(ctx, actorKey{}, userName)
+}
+
+// ActorFromContext returns the user set by WithActor, empty when the server caused the mutation
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type ListSort string
+
+const (
+	ListSort_Name        ListSort = "name"
+	ListSort_CreatedAt   ListSort = "created_at"
+	ListSort_OnlineFirst ListSort = "online"
+	ListSort_Relevance   ListSort = "relevance"
+)
+
+const (
+	ListDefaultLimit = 50
+	ListMaxLimit     = 100
+)
+
+// ListOptions controls pagination, ordering and filtering of the list methods
+type ListOptions struct {
+	Limit      int
+	Cursor     string
+	Sort       ListSort
+	NamePrefix string
+	OnlineOnly bool
+	// filters on the user's own friend metadata, only supported by the friends list
+	FavoritesOnly bool
+	GroupId       int32
+	// only supported by the notifications list
+	UnreadOnly bool
+	// presence lives in the cache, callers pass the online users for online sort and filter
+	OnlineUsers []string
+}
+
+// Normalize fills the defaults and validates the options against the sorts supported by a list
+func (o *ListOptions) Normalize(allowedSorts ...ListSort) error {
+	if o.Limit == 0 {
+		o.Limit = ListDefaultLimit
+	}
+	if o.Limit < 0 || o.Limit > ListMaxLimit {
+		return fmt.Errorf("limit must be between 1 and %d", ListMaxLimit)
+	}
+	if len(allowedSorts) == 0 {
+		return errors.New("list has no sort")
+	}
+	if o.Sort == "" {
+		o.Sort = allowedSorts[0]
+	}
+	sortAllowed := false
+	for _, eachSort := range allowedSorts {
+		if o.Sort == eachSort {
+			sortAllowed = true
+			break
+		}
+	}
+	if !sortAllowed {
+		return fmt.Errorf("sort %s is not supported", o.Sort)
+	}
+	_, err := DecodeCursor(o.Cursor)
+	return err
+}
+
+// Page is the common envelope of paginated lists
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Cursor is the keyset position of the last item of a page
+type Cursor struct {
+	Online    bool      `json:"o,omitempty"`
+	Name      string    `json:"n,omitempty"`
+	CreatedAt time.Time `json:"c,omitempty"`
+	Id        int32     `json:"i,omitempty"`
+	// lists ordered by a computed score page by offset
+	Offset int `json:"f,omitempty"`
+}
+
+func EncodeCursor(cursor *Cursor) string {
+	cursorJson, err := json.Marshal(cursor)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(cursorJson)
+}
+
+// DecodeCursor returns nil for an empty cursor, which starts from the first page
+func DecodeCursor(cursor string) (*Cursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	cursorJson, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors.New("cursor is invalid")
+	}
+	decoded := &Cursor{}
+	err = json.Unmarshal(cursorJson, decoded)
+	if err != nil {
+		return nil, errors.New("cursor is invalid")
+	}
+	return decoded, nil
+}
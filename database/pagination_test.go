@@ -0,0 +1,60 @@
+package database_test
+
+import (
+	"testing"
+	"time"
+
+	"socialite/database"
+)
+
+func TestListOptionsNormalize(t *testing.T) {
+	opts := &database.ListOptions{}
+	err := opts.Normalize(database.ListSort_Name, database.ListSort_CreatedAt)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if opts.Limit != database.ListDefaultLimit {
+		t.Errorf("limit is %d, expected default", opts.Limit)
+	}
+	if opts.Sort != database.ListSort_Name {
+		t.Errorf("sort is %s, expected first allowed sort", opts.Sort)
+	}
+
+	opts = &database.ListOptions{Sort: database.ListSort_OnlineFirst}
+	if opts.Normalize(database.ListSort_Name) == nil {
+		t.Error("unsupported sort is accepted")
+	}
+
+	opts = &database.ListOptions{Limit: database.ListMaxLimit + 1}
+	if opts.Normalize(database.ListSort_Name) == nil {
+		t.Error("limit over maximum is accepted")
+	}
+
+	opts = &database.ListOptions{Cursor: "not a cursor"}
+	if opts.Normalize(database.ListSort_Name) == nil {
+		t.Error("invalid cursor is accepted")
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursor := &database.Cursor{
+		Online:    true,
+		Name:      "user1",
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+		Id:        42,
+	}
+	decoded, err := database.DecodeCursor(database.EncodeCursor(cursor))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if *decoded != *cursor {
+		t.Errorf("decoded cursor %+v does not match %+v", decoded, cursor)
+	}
+
+	decoded, err = database.DecodeCursor("")
+	if err != nil || decoded != nil {
+		t.Error("empty cursor does not start from the first page")
+	}
+}
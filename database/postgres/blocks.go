@@ -0,0 +1,193 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"socialite/database"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func (c *Client) PutBlock(ctx context.Context, block *database.Block) error {
+	if block == nil {
+		return errors.New("block input is nil")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	// start transaction
+	tx, err := c.Pool.Begin(queryCtx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %s", err.Error())
+	}
+	// rollback is a no-op once committed
+	defer tx.Rollback(queryCtx)
+
+	// insert block
+	_, err = tx.Exec(
+		queryCtx,
+		`INSERT INTO blocks
+			(blocker, blocked, created_at)
+		VALUES
+			($1, $2, $3)`,
+		block.Blocker,
+		block.Blocked,
+		block.CreatedAt,
+	)
+	if err != nil {
+		// duplicate entry check
+		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23505" {
+			return database.Err_DuplicatePrimaryKey
+		}
+		return fmt.Errorf("inserting block: %s", err.Error())
+	}
+
+	// sever friendship and pending requests in both directions
+	_, err = tx.Exec(
+		queryCtx,
+		`DELETE FROM friendships
+		WHERE
+			( user1 = $1 AND user2 = $2 )
+			OR
+			( user1 = $2 AND user2 = $1 )`,
+		block.Blocker,
+		block.Blocked,
+	)
+	if err != nil {
+		return fmt.Errorf("deleting friendship: %s", err.Error())
+	}
+
+	// commit transaction
+	err = tx.Commit(queryCtx)
+	if err != nil {
+		return fmt.Errorf("committing transaction: %s", err.Error())
+	}
+	return nil
+}
+
+func (c *Client) DeleteBlock(ctx context.Context, blocker, blocked string) error {
+	if blocker == "" || blocked == "" {
+		return errors.New("user input is empty")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	pgTag, err := c.Pool.Exec(
+		queryCtx,
+		`DELETE FROM blocks
+		WHERE
+			blocker = $1
+			AND blocked = $2`,
+		blocker,
+		blocked,
+	)
+	if err != nil {
+		return fmt.Errorf("deleting block: %s", err.Error())
+	}
+	if pgTag.RowsAffected() == 0 {
+		return database.Err_NotFound
+	}
+	return nil
+}
+
+func (c *Client) GetBlocks(ctx context.Context, blocker string) ([]*database.Block, error) {
+	if blocker == "" {
+		return nil, errors.New("blocker input is empty")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	rows, err := c.Pool.Query(
+		queryCtx,
+		`SELECT
+			blocked, created_at
+		FROM blocks
+		WHERE
+			blocker = $1
+		ORDER BY created_at DESC`,
+		blocker,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying rows: %s", err.Error())
+	}
+	defer rows.Close()
+
+	blocks := make([]*database.Block, 0)
+	for rows.Next() {
+		block := database.Block{Blocker: blocker}
+		err := rows.Scan(
+			&block.Blocked,
+			&block.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scanning row: %s", err.Error())
+		}
+		blocks = append(blocks, &block)
+	}
+	return blocks, nil
+}
+
+func (c *Client) IsBlocked(ctx context.Context, blocker, blocked string) (bool, error) {
+	if blocker == "" || blocked == "" {
+		return false, errors.New("user input is empty")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	var isBlocked bool
+	err := c.Pool.QueryRow(
+		queryCtx,
+		`SELECT EXISTS (
+			SELECT 1
+			FROM blocks
+			WHERE
+				blocker = $1
+				AND blocked = $2
+		)`,
+		blocker,
+		blocked,
+	).Scan(&isBlocked)
+	if err != nil {
+		return false, fmt.Errorf("scanning row: %s", err.Error())
+	}
+	return isBlocked, nil
+}
+
+func (c *Client) GetBlockedUserNames(ctx context.Context, userName string) ([]string, error) {
+	if userName == "" {
+		return nil, errors.New("user name input is empty")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	// users blocked by the user and users who blocked the user
+	rows, err := c.Pool.Query(
+		queryCtx,
+		`SELECT blocked FROM blocks WHERE blocker = $1
+		UNION
+		SELECT blocker FROM blocks WHERE blocked = $1`,
+		userName,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying rows: %s", err.Error())
+	}
+	defer rows.Close()
+
+	userNames := make([]string, 0)
+	for rows.Next() {
+		var eachUserName string
+		err := rows.Scan(&eachUserName)
+		if err != nil {
+			return nil, fmt.Errorf("scanning row: %s", err.Error())
+		}
+		userNames = append(userNames, eachUserName)
+	}
+	return userNames, nil
+}
@@ -0,0 +1,190 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"socialite/database"
+)
+
+func (c *Client) PutDirectMessage(ctx context.Context, message *database.DirectMessage) error {
+	if message == nil {
+		return errors.New("message input is nil")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	err := c.Pool.QueryRow(
+		queryCtx,
+		`INSERT INTO direct_messages
+			(sender, recipient, text, created_at)
+		VALUES
+			($1, $2, $3, $4)
+		RETURNING id`,
+		message.Sender,
+		message.Recipient,
+		message.Text,
+		message.CreatedAt,
+	).Scan(&message.Id)
+	if err != nil {
+		return fmt.Errorf("inserting direct message: %s", err.Error())
+	}
+	return nil
+}
+
+func (c *Client) GetDirectMessages(ctx context.Context, user1, user2 string, beforeId int32, limit int) ([]*database.DirectMessage, error) {
+	if user1 == "" || user2 == "" {
+		return nil, errors.New("user input is empty")
+	}
+	if limit <= 0 {
+		return nil, errors.New("limit is not positive")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	// zero before id starts from the latest message
+	rows, err := c.Pool.Query(
+		queryCtx,
+		`SELECT
+			id, sender, recipient, text, created_at, read_at
+		FROM direct_messages
+		WHERE
+			LEAST(sender, recipient) = LEAST($1::VARCHAR, $2::VARCHAR)
+			AND GREATEST(sender, recipient) = GREATEST($1::VARCHAR, $2::VARCHAR)
+			AND ($3 = 0 OR id < $3)
+		ORDER BY id DESC
+		LIMIT $4`,
+		user1,
+		user2,
+		beforeId,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying rows: %s", err.Error())
+	}
+	defer rows.Close()
+
+	messages := make([]*database.DirectMessage, 0, limit)
+	for rows.Next() {
+		message := database.DirectMessage{}
+		err := rows.Scan(
+			&message.Id,
+			&message.Sender,
+			&message.Recipient,
+			&message.Text,
+			&message.CreatedAt,
+			&message.ReadAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scanning row: %s", err.Error())
+		}
+		messages = append(messages, &message)
+	}
+	return messages, nil
+}
+
+func (c *Client) MarkDirectMessagesRead(ctx context.Context, recipient, sender string, upToId int32) (int64, error) {
+	if recipient == "" || sender == "" {
+		return 0, errors.New("user input is empty")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	// zero up to id marks every message as read
+	pgTag, err := c.Pool.Exec(
+		queryCtx,
+		`UPDATE direct_messages
+		SET
+			read_at = $1
+		WHERE
+			recipient = $2
+			AND sender = $3
+			AND read_at IS NULL
+			AND ($4 = 0 OR id <= $4)`,
+		time.Now(),
+		recipient,
+		sender,
+		upToId,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("updating direct messages: %s", err.Error())
+	}
+	return pgTag.RowsAffected(), nil
+}
+
+func (c *Client) GetUnreadDirectMessageCounts(ctx context.Context, recipient string) (map[string]int, error) {
+	if recipient == "" {
+		return nil, errors.New("recipient input is empty")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	rows, err := c.Pool.Query(
+		queryCtx,
+		`SELECT
+			sender, COUNT(*)
+		FROM direct_messages
+		WHERE
+			recipient = $1
+			AND read_at IS NULL
+		GROUP BY sender`,
+		recipient,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying rows: %s", err.Error())
+	}
+	defer rows.Close()
+
+	unreadCounts := make(map[string]int)
+	for rows.Next() {
+		var sender string
+		var count int
+		err := rows.Scan(&sender, &count)
+		if err != nil {
+			return nil, fmt.Errorf("scanning row: %s", err.Error())
+		}
+		unreadCounts[sender] = count
+	}
+	return unreadCounts, nil
+}
+
+func (c *Client) GetDirectMessageContacts(ctx context.Context, userName string) ([]string, error) {
+	if userName == "" {
+		return nil, errors.New("user input is empty")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	rows, err := c.Pool.Query(
+		queryCtx,
+		`SELECT DISTINCT
+			CASE WHEN sender = $1 THEN recipient ELSE sender END
+		FROM direct_messages
+		WHERE
+			sender = $1
+			OR recipient = $1`,
+		userName,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying rows: %s", err.Error())
+	}
+	defer rows.Close()
+
+	contacts := make([]string, 0)
+	for rows.Next() {
+		var contact string
+		err := rows.Scan(&contact)
+		if err != nil {
+			return nil, fmt.Errorf("scanning row: %s", err.Error())
+		}
+		contacts = append(contacts, contact)
+	}
+	return contacts, nil
+}
@@ -0,0 +1,343 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"socialite/database"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// friendGroupMembersQuery lists the friend names of group g, ordered by name
+const friendGroupMembersQuery = `ARRAY(
+	SELECT
+		CASE WHEN f.user1 = g.user_name THEN f.user2 ELSE f.user1 END AS name
+	FROM friend_group_members gm
+	JOIN friendships f
+		ON f.id = gm.friendship_id
+	WHERE
+		gm.group_id = g.id
+	ORDER BY name
+)`
+
+func (c *Client) GetFriendMetadata(ctx context.Context, userName string, friendshipId int32) (*database.FriendMetadata, error) {
+	if userName == "" || friendshipId == 0 {
+		return nil, errors.New("user name or friendship id input is empty")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	metadata := &database.FriendMetadata{
+		FriendshipId: friendshipId,
+		UserName:     userName,
+	}
+	err := c.Pool.QueryRow(
+		queryCtx,
+		`SELECT
+			nickname, favorite, updated_at
+		FROM friend_metadata
+		WHERE
+			friendship_id = $1
+			AND user_name = $2`,
+		friendshipId,
+		userName,
+	).Scan(
+		&metadata.Nickname,
+		&metadata.Favorite,
+		&metadata.UpdatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, database.Err_NotFound
+		}
+		return nil, fmt.Errorf("scanning row: %s", err.Error())
+	}
+	return metadata, nil
+}
+
+func (c *Client) PutFriendMetadata(ctx context.Context, metadata *database.FriendMetadata) error {
+	if metadata == nil {
+		return errors.New("metadata input is nil")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	_, err := c.Pool.Exec(
+		queryCtx,
+		`INSERT INTO friend_metadata
+			(friendship_id, user_name, nickname, favorite, updated_at)
+		VALUES
+			($1, $2, $3, $4, $5)
+		ON CONFLICT (friendship_id, user_name) DO UPDATE SET
+			nickname = EXCLUDED.nickname,
+			favorite = EXCLUDED.favorite,
+			updated_at = EXCLUDED.updated_at`,
+		metadata.FriendshipId,
+		metadata.UserName,
+		metadata.Nickname,
+		metadata.Favorite,
+		metadata.UpdatedAt,
+	)
+	if err != nil {
+		// friendship or user does not exist
+		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23503" {
+			return database.Err_NotFound
+		}
+		return fmt.Errorf("executing postgres upsert: %s", err.Error())
+	}
+	return nil
+}
+
+func (c *Client) GetFavoriteFriendNames(ctx context.Context, userName string) ([]string, error) {
+	if userName == "" {
+		return nil, errors.New("user name input is empty")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	rows, err := c.Pool.Query(
+		queryCtx,
+		`SELECT
+			CASE WHEN f.user1 = m.user_name THEN f.user2 ELSE f.user1 END
+		FROM friend_metadata m
+		JOIN friendships f
+			ON f.id = m.friendship_id
+		WHERE
+			m.user_name = $1
+			AND m.favorite`,
+		userName,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying rows: %s", err.Error())
+	}
+	defer rows.Close()
+
+	userNames := make([]string, 0)
+	for rows.Next() {
+		var eachUserName string
+		err := rows.Scan(&eachUserName)
+		if err != nil {
+			return nil, fmt.Errorf("scanning row: %s", err.Error())
+		}
+		userNames = append(userNames, eachUserName)
+	}
+	return userNames, nil
+}
+
+func (c *Client) GetFriendGroups(ctx context.Context, userName string) ([]*database.FriendGroup, error) {
+	if userName == "" {
+		return nil, errors.New("user name input is empty")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	rows, err := c.Pool.Query(
+		queryCtx,
+		`SELECT
+			g.id, g.name, g.created_at, `+friendGroupMembersQuery+`
+		FROM friend_groups g
+		WHERE
+			g.user_name = $1
+		ORDER BY LOWER(g.name)`,
+		userName,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying rows: %s", err.Error())
+	}
+	defer rows.Close()
+
+	groups := make([]*database.FriendGroup, 0)
+	for rows.Next() {
+		group := &database.FriendGroup{UserName: userName}
+		err := rows.Scan(&group.Id, &group.Name, &group.CreatedAt, &group.Members)
+		if err != nil {
+			return nil, fmt.Errorf("scanning row: %s", err.Error())
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+func (c *Client) GetFriendGroup(ctx context.Context, userName string, groupId int32) (*database.FriendGroup, error) {
+	if userName == "" || groupId == 0 {
+		return nil, errors.New("user name or group id input is empty")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	// groups of other users are not found
+	group := &database.FriendGroup{UserName: userName}
+	err := c.Pool.QueryRow(
+		queryCtx,
+		`SELECT
+			g.id, g.name, g.created_at, `+friendGroupMembersQuery+`
+		FROM friend_groups g
+		WHERE
+			g.id = $1
+			AND g.user_name = $2`,
+		groupId,
+		userName,
+	).Scan(&group.Id, &group.Name, &group.CreatedAt, &group.Members)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, database.Err_NotFound
+		}
+		return nil, fmt.Errorf("scanning row: %s", err.Error())
+	}
+	return group, nil
+}
+
+func (c *Client) PutFriendGroup(ctx context.Context, group *database.FriendGroup) error {
+	if group == nil {
+		return errors.New("group input is nil")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	err := c.Pool.QueryRow(
+		queryCtx,
+		`INSERT INTO friend_groups
+			(user_name, name, created_at)
+		VALUES
+			($1, $2, $3)
+		RETURNING id`,
+		group.UserName,
+		group.Name,
+		group.CreatedAt,
+	).Scan(&group.Id)
+	if err != nil {
+		// group name is taken
+		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23505" {
+			return database.Err_DuplicatePrimaryKey
+		}
+		return fmt.Errorf("executing postgres insert: %s", err.Error())
+	}
+	return nil
+}
+
+func (c *Client) UpdateFriendGroup(ctx context.Context, group *database.FriendGroup) error {
+	if group == nil {
+		return errors.New("group input is nil")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	pgTag, err := c.Pool.Exec(
+		queryCtx,
+		`UPDATE friend_groups
+		SET
+			name = $1
+		WHERE
+			id = $2
+			AND user_name = $3`,
+		group.Name,
+		group.Id,
+		group.UserName,
+	)
+	if err != nil {
+		// group name is taken
+		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23505" {
+			return database.Err_DuplicatePrimaryKey
+		}
+		return fmt.Errorf("updating friend group: %s", err.Error())
+	}
+	if pgTag.RowsAffected() == 0 {
+		return database.Err_NotFound
+	}
+	return nil
+}
+
+func (c *Client) DeleteFriendGroup(ctx context.Context, userName string, groupId int32) error {
+	if userName == "" || groupId == 0 {
+		return errors.New("user name or group id input is empty")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	pgTag, err := c.Pool.Exec(
+		queryCtx,
+		`DELETE FROM friend_groups
+		WHERE
+			id = $1
+			AND user_name = $2`,
+		groupId,
+		userName,
+	)
+	if err != nil {
+		return fmt.Errorf("deleting friend group: %s", err.Error())
+	}
+	if pgTag.RowsAffected() == 0 {
+		return database.Err_NotFound
+	}
+	return nil
+}
+
+func (c *Client) PutFriendGroupMember(ctx context.Context, groupId, friendshipId int32) error {
+	if groupId == 0 || friendshipId == 0 {
+		return errors.New("group id or friendship id input is empty")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	_, err := c.Pool.Exec(
+		queryCtx,
+		`INSERT INTO friend_group_members
+			(group_id, friendship_id)
+		VALUES
+			($1, $2)`,
+		groupId,
+		friendshipId,
+	)
+	if err != nil {
+		if pgErr, ok := err.(*pgconn.PgError); ok {
+			switch pgErr.Code {
+			// friend is already in the group
+			case "23505":
+				return database.Err_DuplicatePrimaryKey
+			// group or friendship does not exist
+			case "23503":
+				return database.Err_NotFound
+			}
+		}
+		return fmt.Errorf("executing postgres insert: %s", err.Error())
+	}
+	return nil
+}
+
+func (c *Client) DeleteFriendGroupMember(ctx context.Context, groupId, friendshipId int32) error {
+	if groupId == 0 || friendshipId == 0 {
+		return errors.New("group id or friendship id input is empty")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	pgTag, err := c.Pool.Exec(
+		queryCtx,
+		`DELETE FROM friend_group_members
+		WHERE
+			group_id = $1
+			AND friendship_id = $2`,
+		groupId,
+		friendshipId,
+	)
+	if err != nil {
+		return fmt.Errorf("deleting friend group member: %s", err.Error())
+	}
+	if pgTag.RowsAffected() == 0 {
+		return database.Err_NotFound
+	}
+	return nil
+}
@@ -6,62 +6,154 @@ import (
 	"fmt"
 
 	"socialite/database"
+	"socialite/events"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 )
 
-func (c *Client) GetUserFriends(ctx context.Context, name string) ([]*database.User, error) {
+func (c *Client) GetUserFriends(ctx context.Context, name string, opts *database.ListOptions) (*database.Page[*database.Friend], error) {
 	if name == "" {
 		return nil, errors.New("name input is empty")
 	}
+	if opts == nil {
+		opts = &database.ListOptions{}
+	}
+	err := opts.Normalize(database.ListSort_Name, database.ListSort_CreatedAt, database.ListSort_OnlineFirst)
+	if err != nil {
+		return nil, err
+	}
+	cursor, _ := database.DecodeCursor(opts.Cursor)
 
 	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
 	defer cancelQueryCtx()
 
+	// $1 is the user, $2 the online users, $3 the friendship status
+	onlineUsers := opts.OnlineUsers
+	if onlineUsers == nil {
+		onlineUsers = []string{}
+	}
+	query := newListQuery(name, onlineUsers, database.Friendship_Status_Confirmed)
+	if opts.NamePrefix != "" {
+		query.where("f.name LIKE " + query.arg(prefixPattern(opts.NamePrefix)))
+	}
+	if opts.OnlineOnly {
+		query.where("f.name = ANY($2)")
+	}
+	if opts.FavoritesOnly {
+		query.where("COALESCE(m.favorite, FALSE)")
+	}
+	if opts.GroupId != 0 {
+		query.where(fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM friend_group_members gm JOIN friend_groups g ON g.id = gm.group_id WHERE gm.friendship_id = f.id AND g.id = %s AND g.user_name = $1)",
+			query.arg(opts.GroupId),
+		))
+	}
+	var orderBy string
+	switch opts.Sort {
+	case database.ListSort_CreatedAt:
+		if cursor != nil {
+			query.where(fmt.Sprintf("(f.created_at, f.id) < (%s, %s)", query.arg(cursor.CreatedAt), query.arg(cursor.Id)))
+		}
+		orderBy = "f.created_at DESC, f.id DESC"
+	case database.ListSort_OnlineFirst:
+		if cursor != nil {
+			online := query.arg(cursor.Online)
+			query.where(fmt.Sprintf(
+				"((f.name = ANY($2)) < %s OR ((f.name = ANY($2)) = %s AND f.name > %s))",
+				online, online, query.arg(cursor.Name),
+			))
+		}
+		orderBy = "(f.name = ANY($2)) DESC, f.name"
+	default:
+		if cursor != nil {
+			query.where("f.name > " + query.arg(cursor.Name))
+		}
+		orderBy = "f.name"
+	}
+
 	rows, err := c.Pool.Query(
 		queryCtx,
 		`SELECT
-			user1, user2
-		FROM friendships
-		WHERE 
-			status = $1
-			AND (
-				user1 = $2
-				OR user2 = $2
-			)`,
-		database.Friendship_Status_Confirmed,
-		name,
+			f.name, u.created_at, COALESCE(p.display_name, ''), COALESCE(p.avatar_url, ''), f.id, f.created_at, f.name = ANY($2),
+			COALESCE(m.nickname, ''), COALESCE(m.favorite, FALSE),
+			ARRAY(
+				SELECT gm.group_id
+				FROM friend_group_members gm
+				JOIN friend_groups g
+					ON g.id = gm.group_id
+				WHERE
+					gm.friendship_id = f.id
+					AND g.user_name = $1
+				ORDER BY gm.group_id
+			)
+		FROM (
+			SELECT
+				id,
+				CASE WHEN user1 = $1 THEN user2 ELSE user1 END AS name,
+				created_at
+			FROM friendships
+			WHERE
+				status = $3
+				AND (
+					user1 = $1
+					OR user2 = $1
+				)
+		) f
+		JOIN users u
+			ON u.name = f.name
+		LEFT JOIN user_profiles p
+			ON p.user_name = f.name
+		LEFT JOIN friend_metadata m
+			ON m.friendship_id = f.id
+			AND m.user_name = $1
+		WHERE TRUE`+query.whereClause()+`
+		ORDER BY `+orderBy+`
+		LIMIT `+query.arg(opts.Limit+1),
+		query.args...,
 	)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return nil, nil
-		}
 		return nil, fmt.Errorf("querying postgres: %s", err.Error())
 	}
 	defer rows.Close()
 
-	respUsers := make([]*database.User, 0)
-
+	page := &database.Page[*database.Friend]{
+		Items: make([]*database.Friend, 0, opts.Limit),
+	}
 	for rows.Next() {
-		var user1, user2 string
-		err = rows.Scan(&user1, &user2)
+		friend := &database.Friend{}
+		var displayName, avatarUrl string
+		err = rows.Scan(
+			&friend.Name,
+			&friend.CreatedAt,
+			&displayName,
+			&avatarUrl,
+			&friend.FriendshipId,
+			&friend.FriendsSince,
+			&friend.Online,
+			&friend.Nickname,
+			&friend.Favorite,
+			&friend.GroupIds,
+		)
 		if err != nil {
 			return nil, fmt.Errorf("scanning row: %s", err.Error())
 		}
+		friend.Profile = database.NewProfileSummary(displayName, avatarUrl)
+		page.Items = append(page.Items, friend)
+	}
 
-		otherUser := user1
-		if otherUser == name {
-			otherUser = user2
-		}
-
-		newUser, err := database.NewUser(otherUser)
-		if err != nil {
-			return nil, fmt.Errorf("creating new user while scanning each row: %s", err.Error())
-		}
-		respUsers = append(respUsers, newUser)
+	// the extra row only tells that there is a next page
+	if len(page.Items) > opts.Limit {
+		page.Items = page.Items[:opts.Limit]
+		last := page.Items[opts.Limit-1]
+		page.NextCursor = database.EncodeCursor(&database.Cursor{
+			Online:    last.Online,
+			Name:      last.Name,
+			CreatedAt: last.FriendsSince,
+			Id:        last.FriendshipId,
+		})
 	}
-	return respUsers, nil
+	return page, nil
 }
 
 func (c *Client) PutFriendship(ctx context.Context, friendship *database.Friendship) error {
@@ -72,18 +164,27 @@ func (c *Client) PutFriendship(ctx context.Context, friendship *database.Friends
 	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
 	defer cancelQueryCtx()
 
-	_, err := c.Pool.Exec(
+	// start transaction
+	tx, err := c.Pool.Begin(queryCtx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %s", err.Error())
+	}
+	// rollback is a no-op once committed
+	defer tx.Rollback(queryCtx)
+
+	err = tx.QueryRow(
 		queryCtx,
 		`INSERT INTO friendships 
 			(user1, user2, status, created_at, updated_at)
 		VALUES 
-			($1, $2, $3, $4, $5)`,
+			($1, $2, $3, $4, $5)
+		RETURNING id`,
 		friendship.User1,
 		friendship.User2,
 		friendship.Status,
 		friendship.CreatedAt,
 		friendship.UpdatedAt,
-	)
+	).Scan(&friendship.Id)
 	if err != nil {
 		// duplicate entry check
 		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23505" {
@@ -91,6 +192,22 @@ func (c *Client) PutFriendship(ctx context.Context, friendship *database.Friends
 		}
 		return fmt.Errorf("inserting friendship: %s", err.Error())
 	}
+
+	// user1 is the one sending the request
+	if friendship.Status == database.Friendship_Status_Sent {
+		event := events.New(events.Type_FriendRequestSent, friendship.User1)
+		event.UserName = friendship.User2
+		err = putOutboxEvent(queryCtx, tx, event)
+		if err != nil {
+			return err
+		}
+	}
+
+	// commit transaction
+	err = tx.Commit(queryCtx)
+	if err != nil {
+		return fmt.Errorf("committing transaction: %s", err.Error())
+	}
 	return nil
 }
 
@@ -102,7 +219,15 @@ func (c *Client) UpdateFriendship(ctx context.Context, friendship *database.Frie
 	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
 	defer cancelQueryCtx()
 
-	_, err := c.Pool.Exec(
+	// start transaction
+	tx, err := c.Pool.Begin(queryCtx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %s", err.Error())
+	}
+	// rollback is a no-op once committed
+	defer tx.Rollback(queryCtx)
+
+	_, err = tx.Exec(
 		queryCtx,
 		`UPDATE friendships
 		SET
@@ -120,6 +245,21 @@ func (c *Client) UpdateFriendship(ctx context.Context, friendship *database.Frie
 		return fmt.Errorf("updating friendship: %s", err.Error())
 	}
 
+	// user2 is the one accepting the request
+	if friendship.Status == database.Friendship_Status_Confirmed {
+		event := events.New(events.Type_FriendRequestAccepted, friendship.User2)
+		event.UserName = friendship.User1
+		err = putOutboxEvent(queryCtx, tx, event)
+		if err != nil {
+			return err
+		}
+	}
+
+	// commit transaction
+	err = tx.Commit(queryCtx)
+	if err != nil {
+		return fmt.Errorf("committing transaction: %s", err.Error())
+	}
 	return nil
 }
 
@@ -131,17 +271,46 @@ func (c *Client) DeleteFriendship(ctx context.Context, friendshipId int32) error
 	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
 	defer cancelQueryCtx()
 
-	pgTag, err := c.Pool.Exec(
+	// start transaction
+	tx, err := c.Pool.Begin(queryCtx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %s", err.Error())
+	}
+	// rollback is a no-op once committed
+	defer tx.Rollback(queryCtx)
+
+	var user1, user2 string
+	var status database.Friendship_Status
+	err = tx.QueryRow(
 		queryCtx,
 		`DELETE FROM friendships
-		WHERE id = $1`,
+		WHERE id = $1
+		RETURNING user1, user2, status`,
 		friendshipId,
-	)
+	).Scan(&user1, &user2, &status)
 	if err != nil {
+		if err == pgx.ErrNoRows {
+			return database.Err_NotFound
+		}
 		return fmt.Errorf("deleting friendship: %s", err.Error())
 	}
-	if pgTag.RowsAffected() == 0 {
-		return database.Err_NotFound
+
+	// rejected and cancelled requests are no removal, the event is about the friend of whoever removed the friendship
+	if status == database.Friendship_Status_Confirmed {
+		friendName := user1
+		if database.ActorFromContext(ctx) == user1 {
+			friendName = user2
+		}
+		err = putOutboxEvent(queryCtx, tx, newOutboxEvent(ctx, events.Type_FriendRemoved, friendName, ""))
+		if err != nil {
+			return err
+		}
+	}
+
+	// commit transaction
+	err = tx.Commit(queryCtx)
+	if err != nil {
+		return fmt.Errorf("committing transaction: %s", err.Error())
 	}
 	return nil
 }
@@ -217,14 +386,53 @@ func (c *Client) GetFriendshipById(ctx context.Context, friendshipId int32) (*da
 	return friendship, nil
 }
 
-func (c *Client) GetPendingFriendRequests(ctx context.Context, userName string) ([]*database.Friendship, error) {
+func (c *Client) GetPendingFriendRequests(ctx context.Context, userName string, opts *database.ListOptions) (*database.Page[*database.Friendship], error) {
+	if userName == "" {
+		return nil, errors.New("userName input is empty")
+	}
+	return c.getFriendRequests(ctx, "user2", "user1", userName, opts)
+}
+
+func (c *Client) GetSentFriendRequests(ctx context.Context, userName string, opts *database.ListOptions) (*database.Page[*database.Friendship], error) {
 	if userName == "" {
 		return nil, errors.New("userName input is empty")
 	}
+	return c.getFriendRequests(ctx, "user1", "user2", userName, opts)
+}
+
+// getFriendRequests lists pending requests where userColumn is the user,
+// sorting and filtering on the user in otherColumn
+func (c *Client) getFriendRequests(ctx context.Context, userColumn, otherColumn, userName string, opts *database.ListOptions) (*database.Page[*database.Friendship], error) {
+	if opts == nil {
+		opts = &database.ListOptions{}
+	}
+	err := opts.Normalize(database.ListSort_CreatedAt, database.ListSort_Name)
+	if err != nil {
+		return nil, err
+	}
+	cursor, _ := database.DecodeCursor(opts.Cursor)
 
 	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
 	defer cancelQueryCtx()
 
+	query := newListQuery(database.Friendship_Status_Sent, userName)
+	if opts.NamePrefix != "" {
+		query.where(otherColumn + " LIKE " + query.arg(prefixPattern(opts.NamePrefix)))
+	}
+	var orderBy string
+	switch opts.Sort {
+	case database.ListSort_Name:
+		if cursor != nil {
+			query.where(otherColumn + " > " + query.arg(cursor.Name))
+		}
+		orderBy = otherColumn
+	default:
+		if cursor != nil {
+			query.where(fmt.Sprintf("(created_at, id) < (%s, %s)", query.arg(cursor.CreatedAt), query.arg(cursor.Id)))
+		}
+		orderBy = "created_at DESC, id DESC"
+	}
+
 	rows, err := c.Pool.Query(
 		queryCtx,
 		`SELECT
@@ -233,20 +441,19 @@ func (c *Client) GetPendingFriendRequests(ctx context.Context, userName string)
 		WHERE
 			status = $1
 			AND
-			(user2 = $2)`,
-		database.Friendship_Status_Sent,
-		userName,
+			(`+userColumn+` = $2)`+query.whereClause()+`
+		ORDER BY `+orderBy+`
+		LIMIT `+query.arg(opts.Limit+1),
+		query.args...,
 	)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return nil, nil
-		}
 		return nil, fmt.Errorf("querying postgres: %s", err.Error())
 	}
 	defer rows.Close()
 
-	respFriendships := make([]*database.Friendship, 0)
-
+	page := &database.Page[*database.Friendship]{
+		Items: make([]*database.Friendship, 0, opts.Limit),
+	}
 	for rows.Next() {
 		friendship := &database.Friendship{}
 		err = rows.Scan(
@@ -260,9 +467,24 @@ func (c *Client) GetPendingFriendRequests(ctx context.Context, userName string)
 		if err != nil {
 			return nil, fmt.Errorf("scanning row: %s", err.Error())
 		}
-		respFriendships = append(respFriendships, friendship)
+		page.Items = append(page.Items, friendship)
+	}
+
+	// the extra row only tells that there is a next page
+	if len(page.Items) > opts.Limit {
+		page.Items = page.Items[:opts.Limit]
+		last := page.Items[opts.Limit-1]
+		otherName := last.User1
+		if otherColumn == "user2" {
+			otherName = last.User2
+		}
+		page.NextCursor = database.EncodeCursor(&database.Cursor{
+			Name:      otherName,
+			CreatedAt: last.CreatedAt,
+			Id:        last.Id,
+		})
 	}
-	return respFriendships, nil
+	return page, nil
 }
 
 func (c *Client) GetUserFriendsList(ctx context.Context) (map[string][]string, error) {
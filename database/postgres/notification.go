@@ -0,0 +1,196 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"socialite/database"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func (c *Client) PutNotification(ctx context.Context, notification *database.Notification) error {
+	if notification == nil {
+		return errors.New("notification input is nil")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	err := c.Pool.QueryRow(
+		queryCtx,
+		`INSERT INTO notifications
+			(user_name, type, actor, party_name, friendship_id, created_at)
+		VALUES
+			($1, $2, $3, $4, $5, $6)
+		RETURNING id`,
+		notification.UserName,
+		notification.Type,
+		notification.Actor,
+		notification.PartyName,
+		notification.FriendshipId,
+		notification.CreatedAt,
+	).Scan(&notification.Id)
+	if err != nil {
+		// user or actor does not exist
+		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23503" {
+			return database.Err_NotFound
+		}
+		return fmt.Errorf("executing postgres insert: %s", err.Error())
+	}
+	return nil
+}
+
+func (c *Client) GetNotifications(ctx context.Context, userName string, opts *database.ListOptions) (*database.Page[*database.Notification], error) {
+	if userName == "" {
+		return nil, errors.New("user name input is empty")
+	}
+	if opts == nil {
+		opts = &database.ListOptions{}
+	}
+	err := opts.Normalize(database.ListSort_CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	cursor, _ := database.DecodeCursor(opts.Cursor)
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	query := newListQuery(userName)
+	if opts.UnreadOnly {
+		query.where("read_at IS NULL")
+	}
+	if cursor != nil {
+		query.where(fmt.Sprintf("(created_at, id) < (%s, %s)", query.arg(cursor.CreatedAt), query.arg(cursor.Id)))
+	}
+
+	rows, err := c.Pool.Query(
+		queryCtx,
+		`SELECT
+			id, type, actor, party_name, friendship_id, created_at, read_at
+		FROM notifications
+		WHERE
+			user_name = $1`+query.whereClause()+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT `+query.arg(opts.Limit+1),
+		query.args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying rows: %s", err.Error())
+	}
+	defer rows.Close()
+
+	page := &database.Page[*database.Notification]{
+		Items: make([]*database.Notification, 0, opts.Limit),
+	}
+	for rows.Next() {
+		notification := &database.Notification{UserName: userName}
+		err := rows.Scan(
+			&notification.Id,
+			&notification.Type,
+			&notification.Actor,
+			&notification.PartyName,
+			&notification.FriendshipId,
+			&notification.CreatedAt,
+			&notification.ReadAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scanning row: %s", err.Error())
+		}
+		page.Items = append(page.Items, notification)
+	}
+
+	// the extra row only tells that there is a next page
+	if len(page.Items) > opts.Limit {
+		page.Items = page.Items[:opts.Limit]
+		last := page.Items[opts.Limit-1]
+		page.NextCursor = database.EncodeCursor(&database.Cursor{
+			CreatedAt: last.CreatedAt,
+			Id:        last.Id,
+		})
+	}
+	return page, nil
+}
+
+func (c *Client) GetUnreadNotificationCount(ctx context.Context, userName string) (int, error) {
+	if userName == "" {
+		return 0, errors.New("user name input is empty")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	var count int
+	err := c.Pool.QueryRow(
+		queryCtx,
+		`SELECT
+			COUNT(*)
+		FROM notifications
+		WHERE
+			user_name = $1
+			AND read_at IS NULL`,
+		userName,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("scanning row: %s", err.Error())
+	}
+	return count, nil
+}
+
+func (c *Client) MarkNotificationRead(ctx context.Context, userName string, notificationId int32) error {
+	if userName == "" || notificationId == 0 {
+		return errors.New("user name or notification id input is empty")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	// marking an already read notification keeps the first read time
+	pgTag, err := c.Pool.Exec(
+		queryCtx,
+		`UPDATE notifications
+		SET
+			read_at = COALESCE(read_at, $1)
+		WHERE
+			id = $2
+			AND user_name = $3`,
+		time.Now(),
+		notificationId,
+		userName,
+	)
+	if err != nil {
+		return fmt.Errorf("updating notification: %s", err.Error())
+	}
+	if pgTag.RowsAffected() == 0 {
+		return database.Err_NotFound
+	}
+	return nil
+}
+
+func (c *Client) MarkAllNotificationsRead(ctx context.Context, userName string) (int64, error) {
+	if userName == "" {
+		return 0, errors.New("user name input is empty")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	pgTag, err := c.Pool.Exec(
+		queryCtx,
+		`UPDATE notifications
+		SET
+			read_at = $1
+		WHERE
+			user_name = $2
+			AND read_at IS NULL`,
+		time.Now(),
+		userName,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("updating notifications: %s", err.Error())
+	}
+	return pgTag.RowsAffected(), nil
+}
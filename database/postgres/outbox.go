@@ -0,0 +1,172 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"socialite/database"
+	"socialite/events"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// putOutboxEvent writes the event in the transaction of the mutation that caused it,
+// so the event exists if and only if the mutation is committed
+func putOutboxEvent(ctx context.Context, tx pgx.Tx, event *events.Event) error {
+	_, err := tx.Exec(
+		ctx,
+		`INSERT INTO outbox_events
+			(event_id, event_type, actor, user_name, party_name, state, created_at)
+		VALUES
+			($1, $2, $3, $4, $5, $6, $7)`,
+		event.Id,
+		event.Type,
+		event.Actor,
+		event.UserName,
+		event.PartyName,
+		event.State,
+		event.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("inserting outbox event: %s", err.Error())
+	}
+	return nil
+}
+
+// newOutboxEvent creates an event of the user in the context, see database.WithActor
+func newOutboxEvent(ctx context.Context, eventType events.Type, userName, partyName string) *events.Event {
+	event := events.New(eventType, database.ActorFromContext(ctx))
+	event.UserName = userName
+	event.PartyName = partyName
+	return event
+}
+
+// PublishOutboxEvents claims the oldest unpublished events, hands them to publish and marks the ones handed over published.
+// A claim is a lease, committed right away so no transaction stays open while publish waits: the events of a publisher
+// crashing or failing before marking them are claimed again once the lease expires and published again.
+// The bus thus gets each event at least once, the webhook dispatcher skips the deliveries already made by event id
+func (c *Client) PublishOutboxEvents(ctx context.Context, limit int, publish func(context.Context, *events.Event) error) (int, error) {
+	if publish == nil {
+		return 0, errors.New("publish input is nil")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	// the lease outlasts the publishing, which is bounded by the query timeout
+	now := time.Now()
+	rows, err := c.Pool.Query(
+		queryCtx,
+		`WITH claimed AS (
+			UPDATE outbox_events
+			SET
+				claimed_until = $2
+			WHERE
+				id IN (
+					SELECT id
+					FROM outbox_events
+					WHERE
+						published_at IS NULL
+						AND ( claimed_until IS NULL OR claimed_until < $3 )
+					ORDER BY id
+					LIMIT $1
+					FOR UPDATE SKIP LOCKED
+				)
+			RETURNING id, event_id, event_type, actor, user_name, party_name, state, created_at
+		)
+		SELECT
+			id, event_id, event_type, actor, user_name, party_name, state, created_at
+		FROM claimed
+		ORDER BY id`,
+		limit,
+		now.Add(2*c.timeout),
+		now,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("claiming outbox events: %s", err.Error())
+	}
+
+	ids := make([]int64, 0, limit)
+	outboxEvents := make([]*events.Event, 0, limit)
+	for rows.Next() {
+		var id int64
+		var eventType string
+		event := &events.Event{}
+		err = rows.Scan(
+			&id,
+			&event.Id,
+			&eventType,
+			&event.Actor,
+			&event.UserName,
+			&event.PartyName,
+			&event.State,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scanning row: %s", err.Error())
+		}
+		event.Type = events.Type(eventType)
+		ids = append(ids, id)
+		outboxEvents = append(outboxEvents, event)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("reading rows: %s", err.Error())
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	// the events after a failed one are left to the next claim
+	published := 0
+	var publishErr error
+	for _, eachEvent := range outboxEvents {
+		publishErr = publish(queryCtx, eachEvent)
+		if publishErr != nil {
+			publishErr = fmt.Errorf("publishing event %s: %s", eachEvent.Id, publishErr.Error())
+			break
+		}
+		published++
+	}
+	if published == 0 {
+		return 0, publishErr
+	}
+
+	// marked with a fresh timeout, the publishing may have used up the first one
+	markCtx, cancelMarkCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelMarkCtx()
+	_, err = c.Pool.Exec(
+		markCtx,
+		`UPDATE outbox_events
+		SET
+			published_at = $1
+		WHERE
+			id = ANY($2)`,
+		time.Now(),
+		ids[:published],
+	)
+	if err != nil {
+		return 0, fmt.Errorf("marking outbox events published: %s", err.Error())
+	}
+	return published, publishErr
+}
+
+func (c *Client) DeletePublishedOutboxEvents(ctx context.Context, publishedBefore time.Time) (int64, error) {
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	pgTag, err := c.Pool.Exec(
+		queryCtx,
+		`DELETE FROM outbox_events
+		WHERE
+			published_at < $1`,
+		publishedBefore,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("deleting published outbox events: %s", err.Error())
+	}
+	return pgTag.RowsAffected(), nil
+}
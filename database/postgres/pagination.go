@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+)
+
+// listQuery collects the conditions and arguments of a paginated query
+type listQuery struct {
+	conditions []string
+	args       []any
+}
+
+func newListQuery(args ...any) *listQuery {
+	return &listQuery{args: args}
+}
+
+// arg adds an argument and returns its placeholder
+func (q *listQuery) arg(value any) string {
+	q.args = append(q.args, value)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+func (q *listQuery) where(condition string) {
+	q.conditions = append(q.conditions, condition)
+}
+
+// whereClause joins the conditions, to be appended to a query which already has a WHERE
+func (q *listQuery) whereClause() string {
+	if len(q.conditions) == 0 {
+		return ""
+	}
+	return " AND " + strings.Join(q.conditions, " AND ")
+}
+
+// prefixPattern escapes LIKE wildcards in the prefix
+func prefixPattern(prefix string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(strings.ToLower(prefix)) + "%"
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"socialite/database"
+	"socialite/events"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -33,11 +35,13 @@ func (c *Client) PutParty(ctx context.Context, party *database.Party) error {
 	_, err = tx.Exec(
 		queryCtx,
 		`INSERT INTO party
-			(name, creator, created_at, updated_at)
+			(name, creator, state, state_updated_at, created_at, updated_at)
 		VALUES
-			($1, $2, $3, $4)`,
+			($1, $2, $3, $4, $5, $6)`,
 		party.Name,
 		party.Creator,
+		party.State,
+		party.StateUpdatedAt,
 		party.CreatedAt,
 		party.UpdatedAt,
 	)
@@ -70,6 +74,13 @@ func (c *Client) PutParty(ctx context.Context, party *database.Party) error {
 		return fmt.Errorf("inserting party membership: %s", err.Error())
 	}
 
+	event := events.New(events.Type_PartyCreated, party.Creator)
+	event.PartyName = party.Name
+	err = putOutboxEvent(queryCtx, tx, event)
+	if err != nil {
+		return err
+	}
+
 	// commit transaction
 	commitErr := tx.Commit(queryCtx)
 	if commitErr != nil {
@@ -91,7 +102,7 @@ func (c *Client) GetParty(ctx context.Context, partyName string) (*database.Part
 	err := c.Pool.QueryRow(
 		queryCtx,
 		`SELECT
-			creator, created_at, updated_at
+			creator, state, state_updated_at, created_at, updated_at
 		FROM
 			party
 		WHERE
@@ -99,6 +110,8 @@ func (c *Client) GetParty(ctx context.Context, partyName string) (*database.Part
 		partyName,
 	).Scan(
 		&party.Creator,
+		&party.State,
+		&party.StateUpdatedAt,
 		&party.CreatedAt,
 		&party.UpdatedAt,
 	)
@@ -111,44 +124,86 @@ func (c *Client) GetParty(ctx context.Context, partyName string) (*database.Part
 	return &party, nil
 }
 
-func (c *Client) GetCreatedParties(ctx context.Context, userName string) ([]*database.Party, error) {
+func (c *Client) GetCreatedParties(ctx context.Context, userName string, opts *database.ListOptions) (*database.Page[*database.Party], error) {
 	if userName == "" {
 		return nil, errors.New("user name is empty")
 	}
+	if opts == nil {
+		opts = &database.ListOptions{}
+	}
+	err := opts.Normalize(database.ListSort_CreatedAt, database.ListSort_Name)
+	if err != nil {
+		return nil, err
+	}
+	cursor, _ := database.DecodeCursor(opts.Cursor)
 
 	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
 	defer cancelQueryCtx()
 
+	query := newListQuery(userName)
+	if opts.NamePrefix != "" {
+		query.where("name LIKE " + query.arg(prefixPattern(opts.NamePrefix)))
+	}
+	var orderBy string
+	switch opts.Sort {
+	case database.ListSort_Name:
+		if cursor != nil {
+			query.where("name > " + query.arg(cursor.Name))
+		}
+		orderBy = "name"
+	default:
+		if cursor != nil {
+			query.where(fmt.Sprintf("(created_at, name) < (%s, %s)", query.arg(cursor.CreatedAt), query.arg(cursor.Name)))
+		}
+		orderBy = "created_at DESC, name DESC"
+	}
+
 	rows, err := c.Pool.Query(
 		queryCtx,
 		`SELECT
-			name, creator, created_at, updated_at
+			name, creator, state, state_updated_at, created_at, updated_at
 		FROM
 			party
 		WHERE
-			creator = $1`,
-		userName,
+			creator = $1`+query.whereClause()+`
+		ORDER BY `+orderBy+`
+		LIMIT `+query.arg(opts.Limit+1),
+		query.args...,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("querying rows: %s", err.Error())
 	}
 	defer rows.Close()
 
-	createdParties := make([]*database.Party, 0)
+	page := &database.Page[*database.Party]{
+		Items: make([]*database.Party, 0, opts.Limit),
+	}
 	for rows.Next() {
 		party := database.Party{}
 		err := rows.Scan(
 			&party.Name,
 			&party.Creator,
+			&party.State,
+			&party.StateUpdatedAt,
 			&party.CreatedAt,
 			&party.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("scanning row: %s", err.Error())
 		}
-		createdParties = append(createdParties, &party)
+		page.Items = append(page.Items, &party)
 	}
-	return createdParties, nil
+
+	// the extra row only tells that there is a next page
+	if len(page.Items) > opts.Limit {
+		page.Items = page.Items[:opts.Limit]
+		last := page.Items[opts.Limit-1]
+		page.NextCursor = database.EncodeCursor(&database.Cursor{
+			Name:      last.Name,
+			CreatedAt: last.CreatedAt,
+		})
+	}
+	return page, nil
 }
 
 func (c *Client) GetPartyMembers(ctx context.Context, partyName string) ([]string, error) {
@@ -224,3 +279,230 @@ func (c *Client) GetAllPartyMembers(ctx context.Context) (map[string][]string, e
 	}
 	return members, nil
 }
+
+// GetUserParties returns the parties of the user with the membership status, or all of them when the status is empty
+func (c *Client) GetUserParties(ctx context.Context, userName string, status database.PartyMembership_Status) ([]*database.UserParty, error) {
+	if userName == "" {
+		return nil, errors.New("user name is empty")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	rows, err := c.Pool.Query(
+		queryCtx,
+		`SELECT
+			p.name, p.creator, p.state, p.state_updated_at, p.created_at, p.updated_at, pm.status,
+			CASE WHEN p.creator = pm.user_name THEN $3 ELSE $4 END AS role,
+			pm.created_at
+		FROM party p
+		JOIN party_members pm
+			ON p.name = pm.party_name
+		WHERE
+			pm.user_name = $1
+			AND ( $2 = '' OR pm.status = $2 )
+		ORDER BY pm.created_at DESC`,
+		userName,
+		status,
+		database.PartyMembership_Role_Creator,
+		database.PartyMembership_Role_Member,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying rows: %s", err.Error())
+	}
+	defer rows.Close()
+
+	userParties := make([]*database.UserParty, 0)
+	for rows.Next() {
+		userParty := database.UserParty{}
+		err := rows.Scan(
+			&userParty.Name,
+			&userParty.Creator,
+			&userParty.State,
+			&userParty.StateUpdatedAt,
+			&userParty.CreatedAt,
+			&userParty.UpdatedAt,
+			&userParty.Status,
+			&userParty.Role,
+			&userParty.MembershipCreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scanning row: %s", err.Error())
+		}
+		userParties = append(userParties, &userParty)
+	}
+	return userParties, nil
+}
+
+func (c *Client) UpdatePartyState(ctx context.Context, partyName string, from, to database.Party_State) error {
+	if partyName == "" {
+		return errors.New("party name is empty")
+	}
+	if !from.CanTransitionTo(to) {
+		return fmt.Errorf("party state cannot change from %s to %s", from, to)
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	// start transaction
+	tx, err := c.Pool.Begin(queryCtx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %s", err.Error())
+	}
+	// rollback is a no-op once committed
+	defer tx.Rollback(queryCtx)
+
+	// only update if nobody else changed the state in between
+	pgTag, err := tx.Exec(
+		queryCtx,
+		`UPDATE party
+		SET
+			state = $1,
+			state_updated_at = $2,
+			updated_at = $2
+		WHERE
+			name = $3
+			AND state = $4`,
+		to,
+		time.Now(),
+		partyName,
+		from,
+	)
+	if err != nil {
+		return fmt.Errorf("updating party state: %s", err.Error())
+	}
+	if pgTag.RowsAffected() == 0 {
+		return database.Err_StateChanged
+	}
+
+	err = putPartyStateEvent(queryCtx, tx, partyName, to)
+	if err != nil {
+		return err
+	}
+
+	// commit transaction
+	err = tx.Commit(queryCtx)
+	if err != nil {
+		return fmt.Errorf("committing transaction: %s", err.Error())
+	}
+	return nil
+}
+
+// putPartyStateEvent writes the new state of the party to the outbox,
+// the actor of the context is empty when the server changed the state
+func putPartyStateEvent(ctx context.Context, tx pgx.Tx, partyName string, state database.Party_State) error {
+	event := newOutboxEvent(ctx, events.Type_PartyStateChanged, "", partyName)
+	event.State = string(state)
+	return putOutboxEvent(ctx, tx, event)
+}
+
+func (c *Client) StartPartyReadyCheck(ctx context.Context, partyName string) error {
+	if partyName == "" {
+		return errors.New("party name is empty")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	// start transaction
+	tx, err := c.Pool.Begin(queryCtx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %s", err.Error())
+	}
+	// rollback is a no-op once committed
+	defer tx.Rollback(queryCtx)
+
+	// move party to ready check, only from idle
+	now := time.Now()
+	pgTag, err := tx.Exec(
+		queryCtx,
+		`UPDATE party
+		SET
+			state = $1,
+			state_updated_at = $2,
+			updated_at = $2
+		WHERE
+			name = $3
+			AND state = $4`,
+		database.Party_State_ReadyCheck,
+		now,
+		partyName,
+		database.Party_State_Idle,
+	)
+	if err != nil {
+		return fmt.Errorf("updating party state: %s", err.Error())
+	}
+	if pgTag.RowsAffected() == 0 {
+		return database.Err_StateChanged
+	}
+
+	// reset readiness of every member, invitees do not take part
+	_, err = tx.Exec(
+		queryCtx,
+		`UPDATE party_members
+		SET
+			ready = CASE WHEN status = $1 THEN $2 ELSE NULL END
+		WHERE
+			party_name = $3`,
+		database.PartyMembership_Status_Active,
+		database.PartyMembership_Ready_Pending,
+		partyName,
+	)
+	if err != nil {
+		return fmt.Errorf("resetting party members readiness: %s", err.Error())
+	}
+
+	err = putPartyStateEvent(queryCtx, tx, partyName, database.Party_State_ReadyCheck)
+	if err != nil {
+		return err
+	}
+
+	// commit transaction
+	err = tx.Commit(queryCtx)
+	if err != nil {
+		return fmt.Errorf("committing transaction: %s", err.Error())
+	}
+	return nil
+}
+
+func (c *Client) PutPartyReadyResponse(ctx context.Context, partyName, userName string, ready database.PartyMembership_Ready) error {
+	if partyName == "" {
+		return errors.New("party name is empty")
+	}
+	if userName == "" {
+		return errors.New("user name is empty")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	// responses are only accepted while the ready check is running
+	pgTag, err := c.Pool.Exec(
+		queryCtx,
+		`UPDATE party_members pm
+		SET
+			ready = $1,
+			updated_at = $2
+		FROM party p
+		WHERE
+			p.name = pm.party_name
+			AND p.state = $3
+			AND pm.party_name = $4
+			AND pm.user_name = $5
+			AND pm.status = $6`,
+		ready,
+		time.Now(),
+		database.Party_State_ReadyCheck,
+		partyName,
+		userName,
+		database.PartyMembership_Status_Active,
+	)
+	if err != nil {
+		return fmt.Errorf("updating party member readiness: %s", err.Error())
+	}
+	if pgTag.RowsAffected() == 0 {
+		return database.Err_StateChanged
+	}
+	return nil
+}
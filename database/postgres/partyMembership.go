@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"socialite/database"
+	"socialite/events"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -74,6 +75,14 @@ func (c *Client) PutPartyMembership(ctx context.Context, membership *database.Pa
 		return fmt.Errorf("updating party updated_at: %s", err.Error())
 	}
 
+	// the actor of the context is the one inviting
+	if membership.Status == database.PartyMembership_Status_Invited {
+		err = putOutboxEvent(queryCtx, tx, newOutboxEvent(ctx, events.Type_PartyMemberInvited, membership.UserName, membership.PartyName))
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -137,6 +146,16 @@ func (c *Client) UpdatePartyMembership(ctx context.Context, membership *database
 		return fmt.Errorf("updating party updated_at: %s", err.Error())
 	}
 
+	// an invitation is only accepted by the invitee
+	if membership.Status == database.PartyMembership_Status_Active {
+		event := events.New(events.Type_PartyMemberJoined, membership.UserName)
+		event.PartyName = membership.PartyName
+		err = putOutboxEvent(queryCtx, tx, event)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -195,6 +214,20 @@ func (c *Client) DeletePartyMembership(ctx context.Context, membership *database
 		return fmt.Errorf("updating party updated_at: %s", err.Error())
 	}
 
+	// declined and cancelled invitations are no removal, members either leave or are removed by the actor of the context
+	if membership.Status == database.PartyMembership_Status_Active {
+		var event *events.Event
+		if database.ActorFromContext(ctx) == membership.UserName {
+			event = newOutboxEvent(ctx, events.Type_PartyMemberLeft, "", membership.PartyName)
+		} else {
+			event = newOutboxEvent(ctx, events.Type_PartyMemberRemoved, membership.UserName, membership.PartyName)
+		}
+		err = putOutboxEvent(queryCtx, tx, event)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -216,7 +249,7 @@ func (c *Client) GetPartyMembership(ctx context.Context, partyName, userName str
 	err := c.Pool.QueryRow(
 		queryCtx,
 		`SELECT
-			status, created_at, updated_at
+			status, COALESCE(ready, ''), created_at, updated_at
 		FROM party_members			
 		WHERE
 			party_name = $1 AND user_name = $2`,
@@ -224,6 +257,7 @@ func (c *Client) GetPartyMembership(ctx context.Context, partyName, userName str
 		userName,
 	).Scan(
 		&partyMembership.Status,
+		&partyMembership.Ready,
 		&partyMembership.CreatedAt,
 		&partyMembership.UpdatedAt,
 	)
@@ -235,3 +269,68 @@ func (c *Client) GetPartyMembership(ctx context.Context, partyName, userName str
 	}
 	return &partyMembership, nil
 }
+
+func (c *Client) DeleteExpiredPartyInvitations(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	if expiredBefore.IsZero() {
+		return 0, errors.New("expiredBefore input is zero")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	pgTag, err := c.Pool.Exec(
+		queryCtx,
+		`DELETE FROM party_members
+		WHERE
+			status = $1
+			AND
+			created_at < $2`,
+		database.PartyMembership_Status_Invited,
+		expiredBefore,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("deleting expired party invitations: %s", err.Error())
+	}
+	return pgTag.RowsAffected(), nil
+}
+
+func (c *Client) GetPartyMemberships(ctx context.Context, partyName string) ([]*database.PartyMembership, error) {
+	if partyName == "" {
+		return nil, errors.New("party name is empty")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	rows, err := c.Pool.Query(
+		queryCtx,
+		`SELECT
+			user_name, status, COALESCE(ready, ''), created_at, updated_at
+		FROM party_members
+		WHERE
+			party_name = $1
+		ORDER BY created_at`,
+		partyName,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying rows: %s", err.Error())
+	}
+	defer rows.Close()
+
+	memberships := make([]*database.PartyMembership, 0)
+	for rows.Next() {
+		membership := database.PartyMembership{PartyName: partyName}
+		err := rows.Scan(
+			&membership.UserName,
+			&membership.Status,
+			&membership.Ready,
+			&membership.CreatedAt,
+			&membership.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scanning row: %s", err.Error())
+		}
+		memberships = append(memberships, &membership)
+	}
+	return memberships, nil
+}
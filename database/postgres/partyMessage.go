@@ -0,0 +1,170 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"socialite/database"
+)
+
+func (c *Client) PutPartyMessage(ctx context.Context, message *database.PartyMessage, historySize int) error {
+	if message == nil {
+		return errors.New("message input is nil")
+	}
+	if historySize <= 0 {
+		return errors.New("history size is not positive")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	// start transaction
+	tx, err := c.Pool.Begin(queryCtx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %s", err.Error())
+	}
+	// rollback is a no-op once committed
+	defer tx.Rollback(queryCtx)
+
+	// insert message
+	err = tx.QueryRow(
+		queryCtx,
+		`INSERT INTO party_messages
+			(party_name, user_name, text, created_at)
+		VALUES
+			($1, $2, $3, $4)
+		RETURNING id`,
+		message.PartyName,
+		message.UserName,
+		message.Text,
+		message.CreatedAt,
+	).Scan(&message.Id)
+	if err != nil {
+		return fmt.Errorf("inserting party message: %s", err.Error())
+	}
+
+	// keep only the latest messages of the party
+	_, err = tx.Exec(
+		queryCtx,
+		`DELETE FROM party_messages
+		WHERE
+			party_name = $1
+			AND id <= (
+				SELECT id
+				FROM party_messages
+				WHERE party_name = $1
+				ORDER BY id DESC
+				OFFSET $2
+				LIMIT 1
+			)`,
+		message.PartyName,
+		historySize,
+	)
+	if err != nil {
+		return fmt.Errorf("trimming party messages: %s", err.Error())
+	}
+
+	// commit transaction
+	err = tx.Commit(queryCtx)
+	if err != nil {
+		return fmt.Errorf("committing transaction: %s", err.Error())
+	}
+	return nil
+}
+
+func (c *Client) GetPartyMessages(ctx context.Context, partyName string, beforeId int32, limit int) ([]*database.PartyMessage, error) {
+	if partyName == "" {
+		return nil, errors.New("party name is empty")
+	}
+	if limit <= 0 {
+		return nil, errors.New("limit is not positive")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	// zero before id starts from the latest message
+	rows, err := c.Pool.Query(
+		queryCtx,
+		`SELECT
+			id, user_name, text, created_at
+		FROM party_messages
+		WHERE
+			party_name = $1
+			AND ($2 = 0 OR id < $2)
+		ORDER BY id DESC
+		LIMIT $3`,
+		partyName,
+		beforeId,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying rows: %s", err.Error())
+	}
+	defer rows.Close()
+
+	messages := make([]*database.PartyMessage, 0, limit)
+	for rows.Next() {
+		message := database.PartyMessage{PartyName: partyName}
+		err := rows.Scan(
+			&message.Id,
+			&message.UserName,
+			&message.Text,
+			&message.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scanning row: %s", err.Error())
+		}
+		messages = append(messages, &message)
+	}
+	return messages, nil
+}
+
+func (c *Client) GetUserPartyMessages(ctx context.Context, userName string, beforeId int32, limit int) ([]*database.PartyMessage, error) {
+	if userName == "" {
+		return nil, errors.New("user name is empty")
+	}
+	if limit <= 0 {
+		return nil, errors.New("limit is not positive")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	// zero before id starts from the latest message
+	rows, err := c.Pool.Query(
+		queryCtx,
+		`SELECT
+			id, party_name, text, created_at
+		FROM party_messages
+		WHERE
+			user_name = $1
+			AND ($2 = 0 OR id < $2)
+		ORDER BY id DESC
+		LIMIT $3`,
+		userName,
+		beforeId,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying rows: %s", err.Error())
+	}
+	defer rows.Close()
+
+	messages := make([]*database.PartyMessage, 0, limit)
+	for rows.Next() {
+		message := database.PartyMessage{UserName: userName}
+		err := rows.Scan(
+			&message.Id,
+			&message.PartyName,
+			&message.Text,
+			&message.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scanning row: %s", err.Error())
+		}
+		messages = append(messages, &message)
+	}
+	return messages, nil
+}
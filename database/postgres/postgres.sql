@@ -1,10 +1,30 @@
 CREATE DATABASE socialite;
 
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
 CREATE TABLE users (
     name VARCHAR(255) PRIMARY KEY,
-    created_at TIMESTAMP NOT NULL
+    created_at TIMESTAMP NOT NULL,
+    deleted_at TIMESTAMP
 );
 
+CREATE INDEX users_deleted_idx ON users (deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE INDEX users_name_trgm_idx ON users USING GIN (name gin_trgm_ops);
+
+CREATE TABLE user_profiles (
+    user_name VARCHAR(255) PRIMARY KEY,
+    display_name VARCHAR(255) NOT NULL DEFAULT '',
+    avatar_url VARCHAR(2048) NOT NULL DEFAULT '',
+    bio TEXT NOT NULL DEFAULT '',
+    region VARCHAR(50) NOT NULL DEFAULT '',
+    language VARCHAR(50) NOT NULL DEFAULT '',
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_name) REFERENCES users(name) ON DELETE CASCADE
+);
+
+CREATE INDEX user_profiles_display_name_trgm_idx ON user_profiles USING GIN (LOWER(display_name) gin_trgm_ops);
+
 CREATE TABLE friendships (
     id SERIAL PRIMARY KEY,
     user1 VARCHAR(255) NOT NULL,
@@ -17,9 +37,54 @@ CREATE TABLE friendships (
     FOREIGN KEY (user2) REFERENCES users(name) ON DELETE CASCADE
 );
 
+CREATE TABLE friend_metadata (
+    friendship_id INT NOT NULL,
+    user_name VARCHAR(255) NOT NULL,
+    nickname VARCHAR(255) NOT NULL DEFAULT '',
+    favorite BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (friendship_id) REFERENCES friendships(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_name) REFERENCES users(name) ON DELETE CASCADE,
+    PRIMARY KEY (friendship_id, user_name)
+);
+
+CREATE TABLE friend_groups (
+    id SERIAL PRIMARY KEY,
+    user_name VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_name) REFERENCES users(name) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX friend_groups_user_name_idx ON friend_groups (user_name, LOWER(name));
+
+CREATE TABLE friend_group_members (
+    group_id INT NOT NULL,
+    friendship_id INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (group_id) REFERENCES friend_groups(id) ON DELETE CASCADE,
+    FOREIGN KEY (friendship_id) REFERENCES friendships(id) ON DELETE CASCADE,
+    PRIMARY KEY (group_id, friendship_id)
+);
+
+CREATE INDEX friend_group_members_friendship_idx ON friend_group_members (friendship_id);
+
+CREATE TABLE blocks (
+    blocker VARCHAR(255) NOT NULL,
+    blocked VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (blocker) REFERENCES users(name) ON DELETE CASCADE,
+    FOREIGN KEY (blocked) REFERENCES users(name) ON DELETE CASCADE,
+    PRIMARY KEY (blocker, blocked)
+);
+
+CREATE INDEX blocks_blocked_idx ON blocks (blocked);
+
 CREATE TABLE party (
     name VARCHAR(255) PRIMARY KEY,
     creator VARCHAR(255) NOT NULL,
+    state VARCHAR(50) CHECK (state IN ('idle', 'ready_check', 'queued', 'in_match')) NOT NULL DEFAULT 'idle',
+    state_updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     FOREIGN KEY (creator) REFERENCES users(name) ON DELETE CASCADE
@@ -29,9 +94,110 @@ CREATE TABLE party_members (
     party_name VARCHAR(255) NOT NULL,
     user_name VARCHAR(255) NOT NULL,
     status VARCHAR(50) CHECK (status IN ('invited', 'active')) NOT NULL,
+    ready VARCHAR(50) CHECK (ready IN ('pending', 'ready', 'not_ready')),
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     FOREIGN KEY (party_name) REFERENCES party(name) ON DELETE CASCADE,
     FOREIGN KEY (user_name) REFERENCES users(name) ON DELETE CASCADE,
     PRIMARY KEY (party_name, user_name)
-);
\ No newline at end of file
+);
+
+CREATE TABLE party_messages (
+    id SERIAL PRIMARY KEY,
+    party_name VARCHAR(255) NOT NULL,
+    user_name VARCHAR(255) NOT NULL,
+    text TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (party_name) REFERENCES party(name) ON DELETE CASCADE,
+    FOREIGN KEY (user_name) REFERENCES users(name) ON DELETE CASCADE
+);
+
+CREATE INDEX party_messages_party_idx ON party_messages (party_name, id);
+
+CREATE TABLE direct_messages (
+    id SERIAL PRIMARY KEY,
+    sender VARCHAR(255) NOT NULL,
+    recipient VARCHAR(255) NOT NULL,
+    text TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    read_at TIMESTAMP,
+    FOREIGN KEY (sender) REFERENCES users(name) ON DELETE CASCADE,
+    FOREIGN KEY (recipient) REFERENCES users(name) ON DELETE CASCADE
+);
+
+CREATE INDEX direct_messages_conversation_idx ON direct_messages (LEAST(sender, recipient), GREATEST(sender, recipient), id);
+CREATE INDEX direct_messages_unread_idx ON direct_messages (recipient, sender) WHERE read_at IS NULL;
+
+CREATE INDEX party_members_invited_idx ON party_members (created_at) WHERE status = 'invited';
+CREATE INDEX party_messages_user_idx ON party_messages (user_name, id);
+
+CREATE TABLE notifications (
+    id SERIAL PRIMARY KEY,
+    user_name VARCHAR(255) NOT NULL,
+    type VARCHAR(50) CHECK (type IN ('friend_request', 'friend_request_accepted', 'party_invite', 'party_invite_accepted')) NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    party_name VARCHAR(255) NOT NULL DEFAULT '',
+    friendship_id INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    read_at TIMESTAMP,
+    FOREIGN KEY (user_name) REFERENCES users(name) ON DELETE CASCADE,
+    FOREIGN KEY (actor) REFERENCES users(name) ON DELETE CASCADE
+);
+
+CREATE INDEX notifications_user_name_idx ON notifications (user_name, created_at DESC, id DESC);
+CREATE INDEX notifications_unread_idx ON notifications (user_name) WHERE read_at IS NULL;
+
+CREATE TABLE webhook_subscriptions (
+    id SERIAL PRIMARY KEY,
+    url TEXT NOT NULL,
+    event_types TEXT[] NOT NULL,
+    secret VARCHAR(255) NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE webhook_deliveries (
+    id SERIAL PRIMARY KEY,
+    subscription_id INT NOT NULL,
+    event_id VARCHAR(255) NOT NULL,
+    event_type VARCHAR(255) NOT NULL,
+    attempt INT NOT NULL,
+    status_code INT NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (subscription_id) REFERENCES webhook_subscriptions(id) ON DELETE CASCADE
+);
+
+CREATE INDEX webhook_deliveries_subscription_idx ON webhook_deliveries (subscription_id, id DESC);
+
+-- the dispatcher skips the events a subscription already accepted, the outbox may publish an event again
+CREATE UNIQUE INDEX webhook_deliveries_succeeded_idx ON webhook_deliveries (subscription_id, event_id) WHERE status_code BETWEEN 200 AND 299;
+
+CREATE TABLE webhook_dead_letters (
+    id SERIAL PRIMARY KEY,
+    subscription_id INT NOT NULL,
+    event_id VARCHAR(255) NOT NULL,
+    event_type VARCHAR(255) NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INT NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (subscription_id) REFERENCES webhook_subscriptions(id) ON DELETE CASCADE
+);
+
+CREATE TABLE outbox_events (
+    id BIGSERIAL PRIMARY KEY,
+    event_id VARCHAR(255) NOT NULL,
+    event_type VARCHAR(255) NOT NULL,
+    actor VARCHAR(255) NOT NULL DEFAULT '',
+    user_name VARCHAR(255) NOT NULL DEFAULT '',
+    party_name VARCHAR(255) NOT NULL DEFAULT '',
+    state VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- a publisher leases the events it claims, they are claimed again once the lease expires
+    claimed_until TIMESTAMP,
+    published_at TIMESTAMP
+);
+
+CREATE INDEX outbox_events_unpublished_idx ON outbox_events (id) WHERE published_at IS NULL;
@@ -0,0 +1,125 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"socialite/database"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func (c *Client) PutProfile(ctx context.Context, profile *database.Profile) error {
+	if profile == nil {
+		return errors.New("profile input is nil")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	_, err := c.Pool.Exec(
+		queryCtx,
+		`INSERT INTO user_profiles
+			(user_name, display_name, avatar_url, bio, region, language, updated_at)
+		VALUES
+			($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_name) DO UPDATE SET
+			display_name = EXCLUDED.display_name,
+			avatar_url = EXCLUDED.avatar_url,
+			bio = EXCLUDED.bio,
+			region = EXCLUDED.region,
+			language = EXCLUDED.language,
+			updated_at = EXCLUDED.updated_at`,
+		profile.UserName,
+		profile.DisplayName,
+		profile.AvatarUrl,
+		profile.Bio,
+		profile.Region,
+		profile.Language,
+		profile.UpdatedAt,
+	)
+	if err != nil {
+		// user does not exist
+		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23503" {
+			return database.Err_NotFound
+		}
+		return fmt.Errorf("executing postgres upsert: %s", err.Error())
+	}
+	return nil
+}
+
+func (c *Client) GetProfile(ctx context.Context, userName string) (*database.Profile, error) {
+	if userName == "" {
+		return nil, errors.New("user name input is empty")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	row := c.Pool.QueryRow(
+		queryCtx,
+		`SELECT
+			display_name, avatar_url, bio, region, language, updated_at
+		FROM user_profiles
+		WHERE
+			user_name = $1`,
+		userName,
+	)
+
+	profile := &database.Profile{
+		UserName: userName,
+	}
+	err := row.Scan(
+		&profile.DisplayName,
+		&profile.AvatarUrl,
+		&profile.Bio,
+		&profile.Region,
+		&profile.Language,
+		&profile.UpdatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, database.Err_NotFound
+		}
+		return nil, fmt.Errorf("scanning postgres row: %s", err.Error())
+	}
+	return profile, nil
+}
+
+func (c *Client) GetProfileSummaries(ctx context.Context, userNames []string) (map[string]*database.ProfileSummary, error) {
+	summaries := make(map[string]*database.ProfileSummary)
+	if len(userNames) == 0 {
+		return summaries, nil
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	rows, err := c.Pool.Query(
+		queryCtx,
+		`SELECT
+			user_name, display_name, avatar_url
+		FROM user_profiles
+		WHERE
+			user_name = ANY($1)`,
+		userNames,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying postgres: %s", err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userName, displayName, avatarUrl string
+		err = rows.Scan(&userName, &displayName, &avatarUrl)
+		if err != nil {
+			return nil, fmt.Errorf("scanning row: %s", err.Error())
+		}
+		if summary := database.NewProfileSummary(displayName, avatarUrl); summary != nil {
+			summaries[userName] = summary
+		}
+	}
+	return summaries, nil
+}
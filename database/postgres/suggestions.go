@@ -0,0 +1,181 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"socialite/database"
+)
+
+func (c *Client) GetMutualFriends(ctx context.Context, userName, otherName string) ([]*database.User, error) {
+	if userName == "" || otherName == "" {
+		return nil, errors.New("user name input is empty")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	rows, err := c.Pool.Query(
+		queryCtx,
+		`SELECT
+			u.name, u.created_at, COALESCE(p.display_name, ''), COALESCE(p.avatar_url, '')
+		FROM users u
+		LEFT JOIN user_profiles p
+			ON p.user_name = u.name
+		WHERE
+			u.name IN (
+				SELECT CASE WHEN user1 = $1 THEN user2 ELSE user1 END
+				FROM friendships
+				WHERE
+					status = $3
+					AND ( user1 = $1 OR user2 = $1 )
+			)
+			AND u.name IN (
+				SELECT CASE WHEN user1 = $2 THEN user2 ELSE user1 END
+				FROM friendships
+				WHERE
+					status = $3
+					AND ( user1 = $2 OR user2 = $2 )
+			)
+		ORDER BY u.name`,
+		userName,
+		otherName,
+		database.Friendship_Status_Confirmed,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying postgres: %s", err.Error())
+	}
+	defer rows.Close()
+
+	mutualFriends := make([]*database.User, 0)
+	for rows.Next() {
+		user := &database.User{}
+		var displayName, avatarUrl string
+		err = rows.Scan(&user.Name, &user.CreatedAt, &displayName, &avatarUrl)
+		if err != nil {
+			return nil, fmt.Errorf("scanning row: %s", err.Error())
+		}
+		user.Profile = database.NewProfileSummary(displayName, avatarUrl)
+		mutualFriends = append(mutualFriends, user)
+	}
+	return mutualFriends, nil
+}
+
+func (c *Client) GetFriendSuggestions(ctx context.Context, userName string, limit int) ([]*database.FriendSuggestion, error) {
+	if userName == "" {
+		return nil, errors.New("user name input is empty")
+	}
+	if limit <= 0 {
+		return nil, errors.New("limit input is invalid")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	// candidates are friends of friends and anyone sharing a party with the user
+	rows, err := c.Pool.Query(
+		queryCtx,
+		`WITH my_friends AS (
+			SELECT CASE WHEN user1 = $1 THEN user2 ELSE user1 END AS name
+			FROM friendships
+			WHERE
+				status = $2
+				AND ( user1 = $1 OR user2 = $1 )
+		),
+		mutual AS (
+			SELECT
+				CASE WHEN f.user1 = mf.name THEN f.user2 ELSE f.user1 END AS name,
+				COUNT(*) AS mutual_friends
+			FROM friendships f
+			JOIN my_friends mf
+				ON f.user1 = mf.name OR f.user2 = mf.name
+			WHERE
+				f.status = $2
+			GROUP BY 1
+		),
+		party_users AS (
+			SELECT party_name, user_name
+			FROM party_members
+			WHERE
+				status = $3
+		),
+		shared AS (
+			SELECT
+				other.user_name AS name,
+				COUNT(*) AS shared_parties
+			FROM party_users mine
+			JOIN party_users other
+				ON other.party_name = mine.party_name
+			WHERE
+				mine.user_name = $1
+			GROUP BY 1
+		)
+		SELECT
+			u.name,
+			u.created_at,
+			COALESCE(m.mutual_friends, 0),
+			COALESCE(s.shared_parties, 0),
+			COALESCE(p.display_name, ''),
+			COALESCE(p.avatar_url, '')
+		FROM users u
+		LEFT JOIN mutual m
+			ON m.name = u.name
+		LEFT JOIN shared s
+			ON s.name = u.name
+		LEFT JOIN user_profiles p
+			ON p.user_name = u.name
+		WHERE
+			( m.name IS NOT NULL OR s.name IS NOT NULL )
+			AND u.name <> $1
+			AND NOT EXISTS (
+				SELECT 1
+				FROM friendships f
+				WHERE
+					( f.user1 = $1 AND f.user2 = u.name )
+					OR
+					( f.user1 = u.name AND f.user2 = $1 )
+			)
+			AND NOT EXISTS (
+				SELECT 1
+				FROM blocks b
+				WHERE
+					( b.blocker = $1 AND b.blocked = u.name )
+					OR
+					( b.blocker = u.name AND b.blocked = $1 )
+			)
+		ORDER BY
+			3 DESC,
+			4 DESC,
+			u.name
+		LIMIT $4`,
+		userName,
+		database.Friendship_Status_Confirmed,
+		database.PartyMembership_Status_Active,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying postgres: %s", err.Error())
+	}
+	defer rows.Close()
+
+	suggestions := make([]*database.FriendSuggestion, 0, limit)
+	for rows.Next() {
+		suggestion := &database.FriendSuggestion{}
+		var displayName, avatarUrl string
+		err = rows.Scan(
+			&suggestion.Name,
+			&suggestion.CreatedAt,
+			&suggestion.MutualFriends,
+			&suggestion.SharedParties,
+			&displayName,
+			&avatarUrl,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scanning row: %s", err.Error())
+		}
+		suggestion.Profile = database.NewProfileSummary(displayName, avatarUrl)
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions, nil
+}
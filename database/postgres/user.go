@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"socialite/database"
 
@@ -51,7 +53,8 @@ func (c *Client) GetUser(ctx context.Context, name string) (*database.User, erro
 			created_at
 		FROM users
 		WHERE 
-			name = $1`,
+			name = $1
+			AND deleted_at IS NULL`,
 		name,
 	)
 
@@ -67,3 +70,353 @@ func (c *Client) GetUser(ctx context.Context, name string) (*database.User, erro
 	}
 	return user, nil
 }
+
+func (c *Client) SearchUsers(ctx context.Context, userName, query string, opts *database.ListOptions) (*database.Page[*database.UserSearchResult], error) {
+	if userName == "" {
+		return nil, errors.New("user name input is empty")
+	}
+	if query == "" {
+		return nil, errors.New("query input is empty")
+	}
+	if opts == nil {
+		opts = &database.ListOptions{}
+	}
+	err := opts.Normalize(database.ListSort_Relevance)
+	if err != nil {
+		return nil, err
+	}
+	offset := 0
+	cursor, _ := database.DecodeCursor(opts.Cursor)
+	if cursor != nil {
+		offset = cursor.Offset
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	// prefix matches on the user name or display name come first, then fuzzy matches by trigram similarity
+	query = strings.ToLower(query)
+	rows, err := c.Pool.Query(
+		queryCtx,
+		`SELECT
+			u.name,
+			u.created_at,
+			COALESCE(p.display_name, ''),
+			COALESCE(p.avatar_url, ''),
+			CASE
+				WHEN f.status = $4 THEN $5
+				WHEN f.user1 = $1 THEN $6
+				WHEN f.user2 = $1 THEN $7
+				ELSE $8
+			END AS relationship
+		FROM users u
+		LEFT JOIN user_profiles p
+			ON p.user_name = u.name
+		LEFT JOIN friendships f
+			ON ( f.user1 = $1 AND f.user2 = u.name )
+			OR ( f.user2 = $1 AND f.user1 = u.name )
+		WHERE
+			u.name <> $1
+			AND u.deleted_at IS NULL
+			AND (
+				u.name LIKE $3
+				OR u.name % $2
+				OR LOWER(p.display_name) LIKE $3
+				OR LOWER(p.display_name) % $2
+			)
+			AND NOT EXISTS (
+				SELECT 1
+				FROM blocks b
+				WHERE
+					( b.blocker = $1 AND b.blocked = u.name )
+					OR
+					( b.blocker = u.name AND b.blocked = $1 )
+			)
+		ORDER BY
+			( u.name LIKE $3 OR COALESCE(LOWER(p.display_name) LIKE $3, FALSE) ) DESC,
+			GREATEST(similarity(u.name, $2), COALESCE(similarity(LOWER(p.display_name), $2), 0)) DESC,
+			u.name
+		OFFSET $9
+		LIMIT $10`,
+		userName,
+		query,
+		prefixPattern(query),
+		database.Friendship_Status_Confirmed,
+		database.User_Relationship_Friend,
+		database.User_Relationship_RequestSent,
+		database.User_Relationship_RequestReceived,
+		database.User_Relationship_None,
+		offset,
+		opts.Limit+1,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying postgres: %s", err.Error())
+	}
+	defer rows.Close()
+
+	page := &database.Page[*database.UserSearchResult]{
+		Items: make([]*database.UserSearchResult, 0, opts.Limit),
+	}
+	for rows.Next() {
+		result := &database.UserSearchResult{}
+		var displayName, avatarUrl string
+		err = rows.Scan(
+			&result.Name,
+			&result.CreatedAt,
+			&displayName,
+			&avatarUrl,
+			&result.Relationship,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scanning row: %s", err.Error())
+		}
+		result.Profile = database.NewProfileSummary(displayName, avatarUrl)
+		page.Items = append(page.Items, result)
+	}
+
+	// the extra row only tells that there is a next page
+	if len(page.Items) > opts.Limit {
+		page.Items = page.Items[:opts.Limit]
+		page.NextCursor = database.EncodeCursor(&database.Cursor{Offset: offset + opts.Limit})
+	}
+	return page, nil
+}
+
+func (c *Client) DeleteUser(ctx context.Context, name string, softDelete bool) (*database.UserDeletion, error) {
+	if name == "" {
+		return nil, errors.New("name input is empty")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	// start transaction
+	tx, err := c.Pool.Begin(queryCtx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %s", err.Error())
+	}
+	// rollback is a no-op once committed
+	defer tx.Rollback(queryCtx)
+
+	// lock the user, so concurrent deletions do not interleave
+	pgTag, err := tx.Exec(
+		queryCtx,
+		`SELECT 1
+		FROM users
+		WHERE
+			name = $1
+			AND deleted_at IS NULL
+		FOR UPDATE`,
+		name,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("locking user: %s", err.Error())
+	}
+	if pgTag.RowsAffected() == 0 {
+		return nil, database.Err_NotFound
+	}
+
+	deletion := &database.UserDeletion{
+		UserName:           name,
+		Friends:            make([]string, 0),
+		Parties:            make([]string, 0),
+		TransferredParties: make(map[string]string),
+		DisbandedParties:   make([]string, 0),
+	}
+
+	// friendships and pending requests in both directions
+	deletion.Friends, err = queryNames(
+		queryCtx,
+		tx,
+		`DELETE FROM friendships
+		WHERE
+			user1 = $1
+			OR user2 = $1
+		RETURNING
+			CASE WHEN user1 = $1 THEN user2 ELSE user1 END`,
+		name,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("deleting friendships: %s", err.Error())
+	}
+
+	// hand the created parties over to their oldest active member, or disband them
+	createdParties, err := queryNames(
+		queryCtx,
+		tx,
+		`SELECT name
+		FROM party
+		WHERE
+			creator = $1
+		FOR UPDATE`,
+		name,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("getting created parties: %s", err.Error())
+	}
+	for _, partyName := range createdParties {
+		var newCreator string
+		err = tx.QueryRow(
+			queryCtx,
+			`SELECT user_name
+			FROM party_members
+			WHERE
+				party_name = $1
+				AND user_name <> $2
+				AND status = $3
+			ORDER BY created_at
+			LIMIT 1`,
+			partyName,
+			name,
+			database.PartyMembership_Status_Active,
+		).Scan(&newCreator)
+		if err != nil && err != pgx.ErrNoRows {
+			return nil, fmt.Errorf("getting new party creator: %s", err.Error())
+		}
+
+		if err == pgx.ErrNoRows {
+			_, err = tx.Exec(
+				queryCtx,
+				`DELETE FROM party
+				WHERE
+					name = $1`,
+				partyName,
+			)
+			if err != nil {
+				return nil, fmt.Errorf("disbanding party: %s", err.Error())
+			}
+			deletion.DisbandedParties = append(deletion.DisbandedParties, partyName)
+			continue
+		}
+
+		_, err = tx.Exec(
+			queryCtx,
+			`UPDATE party
+			SET
+				creator = $1,
+				updated_at = $2
+			WHERE
+				name = $3`,
+			newCreator,
+			time.Now(),
+			partyName,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("transferring party: %s", err.Error())
+		}
+		deletion.TransferredParties[partyName] = newCreator
+	}
+
+	// memberships and invitations of the parties which still exist
+	deletion.Parties, err = queryNames(
+		queryCtx,
+		tx,
+		`DELETE FROM party_members
+		WHERE
+			user_name = $1
+		RETURNING
+			party_name`,
+		name,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("deleting party memberships: %s", err.Error())
+	}
+
+	// notifications about the user are gone along with what they were about
+	_, err = tx.Exec(
+		queryCtx,
+		`DELETE FROM notifications
+		WHERE
+			actor = $1`,
+		name,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("deleting notifications: %s", err.Error())
+	}
+
+	// blocks in both directions
+	_, err = tx.Exec(
+		queryCtx,
+		`DELETE FROM blocks
+		WHERE
+			blocker = $1
+			OR blocked = $1`,
+		name,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("deleting blocks: %s", err.Error())
+	}
+
+	// a soft deleted user keeps the name and the rest of the data until purged,
+	// a hard delete cascades to everything else
+	if softDelete {
+		_, err = tx.Exec(
+			queryCtx,
+			`UPDATE users
+			SET
+				deleted_at = $1
+			WHERE
+				name = $2`,
+			time.Now(),
+			name,
+		)
+	} else {
+		_, err = tx.Exec(
+			queryCtx,
+			`DELETE FROM users
+			WHERE
+				name = $1`,
+			name,
+		)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("deleting user: %s", err.Error())
+	}
+
+	// commit transaction
+	err = tx.Commit(queryCtx)
+	if err != nil {
+		return nil, fmt.Errorf("committing transaction: %s", err.Error())
+	}
+	return deletion, nil
+}
+
+// PurgeDeletedUsers returns the names of the users it deleted
+func (c *Client) PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time) ([]string, error) {
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	rows, err := c.Pool.Query(
+		queryCtx,
+		`DELETE FROM users
+		WHERE
+			deleted_at IS NOT NULL
+			AND deleted_at < $1
+		RETURNING name`,
+		deletedBefore,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("executing postgres deletion: %s", err.Error())
+	}
+	names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("scanning rows: %s", err.Error())
+	}
+	return names, nil
+}
+
+// queryNames runs a query returning a single text column
+func queryNames(ctx context.Context, tx pgx.Tx, query string, args ...any) ([]string, error) {
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
+	if names == nil {
+		names = make([]string, 0)
+	}
+	return names, nil
+}
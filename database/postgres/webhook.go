@@ -0,0 +1,306 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"socialite/database"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func (c *Client) PutWebhookSubscription(ctx context.Context, subscription *database.WebhookSubscription) error {
+	if subscription == nil {
+		return errors.New("subscription input is nil")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	err := c.Pool.QueryRow(
+		queryCtx,
+		`INSERT INTO webhook_subscriptions
+			(url, event_types, secret, active, created_at)
+		VALUES
+			($1, $2, $3, $4, $5)
+		RETURNING id`,
+		subscription.Url,
+		subscription.EventTypes,
+		subscription.Secret,
+		subscription.Active,
+		subscription.CreatedAt,
+	).Scan(&subscription.Id)
+	if err != nil {
+		return fmt.Errorf("executing postgres insert: %s", err.Error())
+	}
+	return nil
+}
+
+// GetWebhookSubscriptions lists every subscription without its secret
+func (c *Client) GetWebhookSubscriptions(ctx context.Context) ([]*database.WebhookSubscription, error) {
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	rows, err := c.Pool.Query(
+		queryCtx,
+		`SELECT
+			id, url, event_types, '', active, created_at
+		FROM webhook_subscriptions
+		ORDER BY id`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying rows: %s", err.Error())
+	}
+	return scanWebhookSubscriptions(rows)
+}
+
+// GetWebhookSubscriptionsForEvent lists the active subscriptions of the event type along with their secrets
+func (c *Client) GetWebhookSubscriptionsForEvent(ctx context.Context, eventType string) ([]*database.WebhookSubscription, error) {
+	if eventType == "" {
+		return nil, errors.New("event type input is empty")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	rows, err := c.Pool.Query(
+		queryCtx,
+		`SELECT
+			id, url, event_types, secret, active, created_at
+		FROM webhook_subscriptions
+		WHERE
+			active
+			AND $1 = ANY(event_types)
+		ORDER BY id`,
+		eventType,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying rows: %s", err.Error())
+	}
+	return scanWebhookSubscriptions(rows)
+}
+
+func scanWebhookSubscriptions(rows pgx.Rows) ([]*database.WebhookSubscription, error) {
+	defer rows.Close()
+
+	subscriptions := make([]*database.WebhookSubscription, 0)
+	for rows.Next() {
+		subscription := &database.WebhookSubscription{}
+		err := rows.Scan(
+			&subscription.Id,
+			&subscription.Url,
+			&subscription.EventTypes,
+			&subscription.Secret,
+			&subscription.Active,
+			&subscription.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scanning row: %s", err.Error())
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	return subscriptions, nil
+}
+
+func (c *Client) DeleteWebhookSubscription(ctx context.Context, subscriptionId int32) error {
+	if subscriptionId == 0 {
+		return errors.New("subscription id input is empty")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	pgTag, err := c.Pool.Exec(
+		queryCtx,
+		`DELETE FROM webhook_subscriptions
+		WHERE
+			id = $1`,
+		subscriptionId,
+	)
+	if err != nil {
+		return fmt.Errorf("deleting webhook subscription: %s", err.Error())
+	}
+	if pgTag.RowsAffected() == 0 {
+		return database.Err_NotFound
+	}
+	return nil
+}
+
+func (c *Client) PutWebhookDelivery(ctx context.Context, delivery *database.WebhookDelivery) error {
+	if delivery == nil {
+		return errors.New("delivery input is nil")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	err := c.Pool.QueryRow(
+		queryCtx,
+		`INSERT INTO webhook_deliveries
+			(subscription_id, event_id, event_type, attempt, status_code, error, duration_ms, created_at)
+		VALUES
+			($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id`,
+		delivery.SubscriptionId,
+		delivery.EventId,
+		delivery.EventType,
+		delivery.Attempt,
+		delivery.StatusCode,
+		delivery.Error,
+		delivery.DurationMs,
+		delivery.CreatedAt,
+	).Scan(&delivery.Id)
+	if err != nil {
+		// a second successful delivery of the event to the subscription
+		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23505" {
+			return database.Err_DuplicatePrimaryKey
+		}
+		return fmt.Errorf("executing postgres insert: %s", err.Error())
+	}
+	return nil
+}
+
+// IsWebhookDelivered tells if the event was already accepted by the subscription
+func (c *Client) IsWebhookDelivered(ctx context.Context, subscriptionId int32, eventId string) (bool, error) {
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	var delivered bool
+	err := c.Pool.QueryRow(
+		queryCtx,
+		`SELECT EXISTS (
+			SELECT 1
+			FROM webhook_deliveries
+			WHERE
+				subscription_id = $1
+				AND event_id = $2
+				AND status_code BETWEEN 200 AND 299
+		)`,
+		subscriptionId,
+		eventId,
+	).Scan(&delivered)
+	if err != nil {
+		return false, fmt.Errorf("querying postgres: %s", err.Error())
+	}
+	return delivered, nil
+}
+
+// GetWebhookDeliveries lists the latest delivery attempts of the subscription
+func (c *Client) GetWebhookDeliveries(ctx context.Context, subscriptionId int32, limit int) ([]*database.WebhookDelivery, error) {
+	if subscriptionId == 0 {
+		return nil, errors.New("subscription id input is empty")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	rows, err := c.Pool.Query(
+		queryCtx,
+		`SELECT
+			id, event_id, event_type, attempt, status_code, error, duration_ms, created_at
+		FROM webhook_deliveries
+		WHERE
+			subscription_id = $1
+		ORDER BY id DESC
+		LIMIT $2`,
+		subscriptionId,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying rows: %s", err.Error())
+	}
+	defer rows.Close()
+
+	deliveries := make([]*database.WebhookDelivery, 0, limit)
+	for rows.Next() {
+		delivery := &database.WebhookDelivery{SubscriptionId: subscriptionId}
+		err := rows.Scan(
+			&delivery.Id,
+			&delivery.EventId,
+			&delivery.EventType,
+			&delivery.Attempt,
+			&delivery.StatusCode,
+			&delivery.Error,
+			&delivery.DurationMs,
+			&delivery.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scanning row: %s", err.Error())
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+func (c *Client) PutWebhookDeadLetter(ctx context.Context, deadLetter *database.WebhookDeadLetter) error {
+	if deadLetter == nil {
+		return errors.New("dead letter input is nil")
+	}
+
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	err := c.Pool.QueryRow(
+		queryCtx,
+		`INSERT INTO webhook_dead_letters
+			(subscription_id, event_id, event_type, payload, attempts, error, created_at)
+		VALUES
+			($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id`,
+		deadLetter.SubscriptionId,
+		deadLetter.EventId,
+		deadLetter.EventType,
+		string(deadLetter.Payload),
+		deadLetter.Attempts,
+		deadLetter.Error,
+		deadLetter.CreatedAt,
+	).Scan(&deadLetter.Id)
+	if err != nil {
+		return fmt.Errorf("executing postgres insert: %s", err.Error())
+	}
+	return nil
+}
+
+// GetWebhookDeadLetters lists the latest dead letters of every subscription
+func (c *Client) GetWebhookDeadLetters(ctx context.Context, limit int) ([]*database.WebhookDeadLetter, error) {
+	queryCtx, cancelQueryCtx := context.WithTimeout(ctx, c.timeout)
+	defer cancelQueryCtx()
+
+	rows, err := c.Pool.Query(
+		queryCtx,
+		`SELECT
+			id, subscription_id, event_id, event_type, payload, attempts, error, created_at
+		FROM webhook_dead_letters
+		ORDER BY id DESC
+		LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying rows: %s", err.Error())
+	}
+	defer rows.Close()
+
+	deadLetters := make([]*database.WebhookDeadLetter, 0, limit)
+	for rows.Next() {
+		deadLetter := &database.WebhookDeadLetter{}
+		var payload []byte
+		err := rows.Scan(
+			&deadLetter.Id,
+			&deadLetter.SubscriptionId,
+			&deadLetter.EventId,
+			&deadLetter.EventType,
+			&payload,
+			&deadLetter.Attempts,
+			&deadLetter.Error,
+			&deadLetter.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scanning row: %s", err.Error())
+		}
+		deadLetter.Payload = payload
+		deadLetters = append(deadLetters, deadLetter)
+	}
+	return deadLetters, nil
+}
@@ -0,0 +1,549 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"socialite/database"
+	"socialite/events"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("socialite/database")
+
+// Database wraps a database with a span for each of its methods, whatever its backend
+type Database struct {
+	db database.Database
+}
+
+func New(db database.Database) database.Database {
+	return &Database{db: db}
+}
+
+// recordError marks the span failed, a row not found is an answer rather than a failure
+func recordError(span trace.Span, err error) error {
+	if err != nil && !errors.Is(err, database.Err_NotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+func (d *Database) PutUser(ctx context.Context, user *database.User) error {
+	ctx, span := tracer.Start(ctx, "database.PutUser")
+	defer span.End()
+	err := d.db.PutUser(ctx, user)
+	return recordError(span, err)
+}
+
+func (d *Database) GetUser(ctx context.Context, name string) (*database.User, error) {
+	ctx, span := tracer.Start(ctx, "database.GetUser")
+	defer span.End()
+	result, err := d.db.GetUser(ctx, name)
+	return result, recordError(span, err)
+}
+
+func (d *Database) DeleteUser(ctx context.Context, name string, softDelete bool) (*database.UserDeletion, error) {
+	ctx, span := tracer.Start(ctx, "database.DeleteUser")
+	defer span.End()
+	result, err := d.db.DeleteUser(ctx, name, softDelete)
+	return result, recordError(span, err)
+}
+
+func (d *Database) PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time) ([]string, error) {
+	ctx, span := tracer.Start(ctx, "database.PurgeDeletedUsers")
+	defer span.End()
+	result, err := d.db.PurgeDeletedUsers(ctx, deletedBefore)
+	return result, recordError(span, err)
+}
+
+func (d *Database) PutProfile(ctx context.Context, profile *database.Profile) error {
+	ctx, span := tracer.Start(ctx, "database.PutProfile")
+	defer span.End()
+	err := d.db.PutProfile(ctx, profile)
+	return recordError(span, err)
+}
+
+func (d *Database) GetProfile(ctx context.Context, userName string) (*database.Profile, error) {
+	ctx, span := tracer.Start(ctx, "database.GetProfile")
+	defer span.End()
+	result, err := d.db.GetProfile(ctx, userName)
+	return result, recordError(span, err)
+}
+
+func (d *Database) GetProfileSummaries(ctx context.Context, userNames []string) (map[string]*database.ProfileSummary, error) {
+	ctx, span := tracer.Start(ctx, "database.GetProfileSummaries")
+	defer span.End()
+	result, err := d.db.GetProfileSummaries(ctx, userNames)
+	return result, recordError(span, err)
+}
+
+func (d *Database) SearchUsers(ctx context.Context, userName, query string, opts *database.ListOptions) (*database.Page[*database.UserSearchResult], error) {
+	ctx, span := tracer.Start(ctx, "database.SearchUsers")
+	defer span.End()
+	result, err := d.db.SearchUsers(ctx, userName, query, opts)
+	return result, recordError(span, err)
+}
+
+func (d *Database) GetUserFriends(ctx context.Context, name string, opts *database.ListOptions) (*database.Page[*database.Friend], error) {
+	ctx, span := tracer.Start(ctx, "database.GetUserFriends")
+	defer span.End()
+	result, err := d.db.GetUserFriends(ctx, name, opts)
+	return result, recordError(span, err)
+}
+
+func (d *Database) GetMutualFriends(ctx context.Context, userName, otherName string) ([]*database.User, error) {
+	ctx, span := tracer.Start(ctx, "database.GetMutualFriends")
+	defer span.End()
+	result, err := d.db.GetMutualFriends(ctx, userName, otherName)
+	return result, recordError(span, err)
+}
+
+func (d *Database) GetFriendSuggestions(ctx context.Context, userName string, limit int) ([]*database.FriendSuggestion, error) {
+	ctx, span := tracer.Start(ctx, "database.GetFriendSuggestions")
+	defer span.End()
+	result, err := d.db.GetFriendSuggestions(ctx, userName, limit)
+	return result, recordError(span, err)
+}
+
+func (d *Database) PutFriendship(ctx context.Context, friendship *database.Friendship) error {
+	ctx, span := tracer.Start(ctx, "database.PutFriendship")
+	defer span.End()
+	err := d.db.PutFriendship(ctx, friendship)
+	return recordError(span, err)
+}
+
+func (d *Database) GetPendingFriendRequests(ctx context.Context, userName string, opts *database.ListOptions) (*database.Page[*database.Friendship], error) {
+	ctx, span := tracer.Start(ctx, "database.GetPendingFriendRequests")
+	defer span.End()
+	result, err := d.db.GetPendingFriendRequests(ctx, userName, opts)
+	return result, recordError(span, err)
+}
+
+func (d *Database) GetSentFriendRequests(ctx context.Context, userName string, opts *database.ListOptions) (*database.Page[*database.Friendship], error) {
+	ctx, span := tracer.Start(ctx, "database.GetSentFriendRequests")
+	defer span.End()
+	result, err := d.db.GetSentFriendRequests(ctx, userName, opts)
+	return result, recordError(span, err)
+}
+
+func (d *Database) GetUserFriendsList(ctx context.Context) (map[string][]string, error) {
+	ctx, span := tracer.Start(ctx, "database.GetUserFriendsList")
+	defer span.End()
+	result, err := d.db.GetUserFriendsList(ctx)
+	return result, recordError(span, err)
+}
+
+func (d *Database) GetFriendship(ctx context.Context, user1, user2 string) (*database.Friendship, error) {
+	ctx, span := tracer.Start(ctx, "database.GetFriendship")
+	defer span.End()
+	result, err := d.db.GetFriendship(ctx, user1, user2)
+	return result, recordError(span, err)
+}
+
+func (d *Database) GetFriendshipById(ctx context.Context, friendshipId int32) (*database.Friendship, error) {
+	ctx, span := tracer.Start(ctx, "database.GetFriendshipById")
+	defer span.End()
+	result, err := d.db.GetFriendshipById(ctx, friendshipId)
+	return result, recordError(span, err)
+}
+
+func (d *Database) UpdateFriendship(ctx context.Context, friendship *database.Friendship) error {
+	ctx, span := tracer.Start(ctx, "database.UpdateFriendship")
+	defer span.End()
+	err := d.db.UpdateFriendship(ctx, friendship)
+	return recordError(span, err)
+}
+
+func (d *Database) DeleteFriendship(ctx context.Context, friendshipId int32) error {
+	ctx, span := tracer.Start(ctx, "database.DeleteFriendship")
+	defer span.End()
+	err := d.db.DeleteFriendship(ctx, friendshipId)
+	return recordError(span, err)
+}
+
+func (d *Database) GetFriendMetadata(ctx context.Context, userName string, friendshipId int32) (*database.FriendMetadata, error) {
+	ctx, span := tracer.Start(ctx, "database.GetFriendMetadata")
+	defer span.End()
+	result, err := d.db.GetFriendMetadata(ctx, userName, friendshipId)
+	return result, recordError(span, err)
+}
+
+func (d *Database) PutFriendMetadata(ctx context.Context, metadata *database.FriendMetadata) error {
+	ctx, span := tracer.Start(ctx, "database.PutFriendMetadata")
+	defer span.End()
+	err := d.db.PutFriendMetadata(ctx, metadata)
+	return recordError(span, err)
+}
+
+func (d *Database) GetFavoriteFriendNames(ctx context.Context, userName string) ([]string, error) {
+	ctx, span := tracer.Start(ctx, "database.GetFavoriteFriendNames")
+	defer span.End()
+	result, err := d.db.GetFavoriteFriendNames(ctx, userName)
+	return result, recordError(span, err)
+}
+
+func (d *Database) GetFriendGroups(ctx context.Context, userName string) ([]*database.FriendGroup, error) {
+	ctx, span := tracer.Start(ctx, "database.GetFriendGroups")
+	defer span.End()
+	result, err := d.db.GetFriendGroups(ctx, userName)
+	return result, recordError(span, err)
+}
+
+func (d *Database) GetFriendGroup(ctx context.Context, userName string, groupId int32) (*database.FriendGroup, error) {
+	ctx, span := tracer.Start(ctx, "database.GetFriendGroup")
+	defer span.End()
+	result, err := d.db.GetFriendGroup(ctx, userName, groupId)
+	return result, recordError(span, err)
+}
+
+func (d *Database) PutFriendGroup(ctx context.Context, group *database.FriendGroup) error {
+	ctx, span := tracer.Start(ctx, "database.PutFriendGroup")
+	defer span.End()
+	err := d.db.PutFriendGroup(ctx, group)
+	return recordError(span, err)
+}
+
+func (d *Database) UpdateFriendGroup(ctx context.Context, group *database.FriendGroup) error {
+	ctx, span := tracer.Start(ctx, "database.UpdateFriendGroup")
+	defer span.End()
+	err := d.db.UpdateFriendGroup(ctx, group)
+	return recordError(span, err)
+}
+
+func (d *Database) DeleteFriendGroup(ctx context.Context, userName string, groupId int32) error {
+	ctx, span := tracer.Start(ctx, "database.DeleteFriendGroup")
+	defer span.End()
+	err := d.db.DeleteFriendGroup(ctx, userName, groupId)
+	return recordError(span, err)
+}
+
+func (d *Database) PutFriendGroupMember(ctx context.Context, groupId, friendshipId int32) error {
+	ctx, span := tracer.Start(ctx, "database.PutFriendGroupMember")
+	defer span.End()
+	err := d.db.PutFriendGroupMember(ctx, groupId, friendshipId)
+	return recordError(span, err)
+}
+
+func (d *Database) DeleteFriendGroupMember(ctx context.Context, groupId, friendshipId int32) error {
+	ctx, span := tracer.Start(ctx, "database.DeleteFriendGroupMember")
+	defer span.End()
+	err := d.db.DeleteFriendGroupMember(ctx, groupId, friendshipId)
+	return recordError(span, err)
+}
+
+func (d *Database) PutBlock(ctx context.Context, block *database.Block) error {
+	ctx, span := tracer.Start(ctx, "database.PutBlock")
+	defer span.End()
+	err := d.db.PutBlock(ctx, block)
+	return recordError(span, err)
+}
+
+func (d *Database) DeleteBlock(ctx context.Context, blocker, blocked string) error {
+	ctx, span := tracer.Start(ctx, "database.DeleteBlock")
+	defer span.End()
+	err := d.db.DeleteBlock(ctx, blocker, blocked)
+	return recordError(span, err)
+}
+
+func (d *Database) GetBlocks(ctx context.Context, blocker string) ([]*database.Block, error) {
+	ctx, span := tracer.Start(ctx, "database.GetBlocks")
+	defer span.End()
+	result, err := d.db.GetBlocks(ctx, blocker)
+	return result, recordError(span, err)
+}
+
+func (d *Database) IsBlocked(ctx context.Context, blocker, blocked string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "database.IsBlocked")
+	defer span.End()
+	result, err := d.db.IsBlocked(ctx, blocker, blocked)
+	return result, recordError(span, err)
+}
+
+func (d *Database) GetBlockedUserNames(ctx context.Context, userName string) ([]string, error) {
+	ctx, span := tracer.Start(ctx, "database.GetBlockedUserNames")
+	defer span.End()
+	result, err := d.db.GetBlockedUserNames(ctx, userName)
+	return result, recordError(span, err)
+}
+
+func (d *Database) PutParty(ctx context.Context, party *database.Party) error {
+	ctx, span := tracer.Start(ctx, "database.PutParty")
+	defer span.End()
+	err := d.db.PutParty(ctx, party)
+	return recordError(span, err)
+}
+
+func (d *Database) GetParty(ctx context.Context, partyName string) (*database.Party, error) {
+	ctx, span := tracer.Start(ctx, "database.GetParty")
+	defer span.End()
+	result, err := d.db.GetParty(ctx, partyName)
+	return result, recordError(span, err)
+}
+
+func (d *Database) GetCreatedParties(ctx context.Context, userName string, opts *database.ListOptions) (*database.Page[*database.Party], error) {
+	ctx, span := tracer.Start(ctx, "database.GetCreatedParties")
+	defer span.End()
+	result, err := d.db.GetCreatedParties(ctx, userName, opts)
+	return result, recordError(span, err)
+}
+
+func (d *Database) GetUserParties(ctx context.Context, userName string, status database.PartyMembership_Status) ([]*database.UserParty, error) {
+	ctx, span := tracer.Start(ctx, "database.GetUserParties")
+	defer span.End()
+	result, err := d.db.GetUserParties(ctx, userName, status)
+	return result, recordError(span, err)
+}
+
+func (d *Database) UpdatePartyState(ctx context.Context, partyName string, from, to database.Party_State) error {
+	ctx, span := tracer.Start(ctx, "database.UpdatePartyState")
+	defer span.End()
+	err := d.db.UpdatePartyState(ctx, partyName, from, to)
+	return recordError(span, err)
+}
+
+func (d *Database) StartPartyReadyCheck(ctx context.Context, partyName string) error {
+	ctx, span := tracer.Start(ctx, "database.StartPartyReadyCheck")
+	defer span.End()
+	err := d.db.StartPartyReadyCheck(ctx, partyName)
+	return recordError(span, err)
+}
+
+func (d *Database) PutPartyReadyResponse(ctx context.Context, partyName, userName string, ready database.PartyMembership_Ready) error {
+	ctx, span := tracer.Start(ctx, "database.PutPartyReadyResponse")
+	defer span.End()
+	err := d.db.PutPartyReadyResponse(ctx, partyName, userName, ready)
+	return recordError(span, err)
+}
+
+func (d *Database) PutPartyMembership(ctx context.Context, membership *database.PartyMembership) error {
+	ctx, span := tracer.Start(ctx, "database.PutPartyMembership")
+	defer span.End()
+	err := d.db.PutPartyMembership(ctx, membership)
+	return recordError(span, err)
+}
+
+func (d *Database) GetPartyMembership(ctx context.Context, partyName, userName string) (*database.PartyMembership, error) {
+	ctx, span := tracer.Start(ctx, "database.GetPartyMembership")
+	defer span.End()
+	result, err := d.db.GetPartyMembership(ctx, partyName, userName)
+	return result, recordError(span, err)
+}
+
+func (d *Database) UpdatePartyMembership(ctx context.Context, membership *database.PartyMembership) error {
+	ctx, span := tracer.Start(ctx, "database.UpdatePartyMembership")
+	defer span.End()
+	err := d.db.UpdatePartyMembership(ctx, membership)
+	return recordError(span, err)
+}
+
+func (d *Database) DeletePartyMembership(ctx context.Context, membership *database.PartyMembership) error {
+	ctx, span := tracer.Start(ctx, "database.DeletePartyMembership")
+	defer span.End()
+	err := d.db.DeletePartyMembership(ctx, membership)
+	return recordError(span, err)
+}
+
+func (d *Database) GetPartyMembers(ctx context.Context, partyName string) ([]string, error) {
+	ctx, span := tracer.Start(ctx, "database.GetPartyMembers")
+	defer span.End()
+	result, err := d.db.GetPartyMembers(ctx, partyName)
+	return result, recordError(span, err)
+}
+
+func (d *Database) GetPartyMemberships(ctx context.Context, partyName string) ([]*database.PartyMembership, error) {
+	ctx, span := tracer.Start(ctx, "database.GetPartyMemberships")
+	defer span.End()
+	result, err := d.db.GetPartyMemberships(ctx, partyName)
+	return result, recordError(span, err)
+}
+
+func (d *Database) GetAllPartyMembers(ctx context.Context) (map[string][]string, error) {
+	ctx, span := tracer.Start(ctx, "database.GetAllPartyMembers")
+	defer span.End()
+	result, err := d.db.GetAllPartyMembers(ctx)
+	return result, recordError(span, err)
+}
+
+func (d *Database) DeleteExpiredPartyInvitations(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	ctx, span := tracer.Start(ctx, "database.DeleteExpiredPartyInvitations")
+	defer span.End()
+	result, err := d.db.DeleteExpiredPartyInvitations(ctx, expiredBefore)
+	return result, recordError(span, err)
+}
+
+func (d *Database) PutPartyMessage(ctx context.Context, message *database.PartyMessage, historySize int) error {
+	ctx, span := tracer.Start(ctx, "database.PutPartyMessage")
+	defer span.End()
+	err := d.db.PutPartyMessage(ctx, message, historySize)
+	return recordError(span, err)
+}
+
+func (d *Database) GetPartyMessages(ctx context.Context, partyName string, beforeId int32, limit int) ([]*database.PartyMessage, error) {
+	ctx, span := tracer.Start(ctx, "database.GetPartyMessages")
+	defer span.End()
+	result, err := d.db.GetPartyMessages(ctx, partyName, beforeId, limit)
+	return result, recordError(span, err)
+}
+
+func (d *Database) GetUserPartyMessages(ctx context.Context, userName string, beforeId int32, limit int) ([]*database.PartyMessage, error) {
+	ctx, span := tracer.Start(ctx, "database.GetUserPartyMessages")
+	defer span.End()
+	result, err := d.db.GetUserPartyMessages(ctx, userName, beforeId, limit)
+	return result, recordError(span, err)
+}
+
+func (d *Database) PutDirectMessage(ctx context.Context, message *database.DirectMessage) error {
+	ctx, span := tracer.Start(ctx, "database.PutDirectMessage")
+	defer span.End()
+	err := d.db.PutDirectMessage(ctx, message)
+	return recordError(span, err)
+}
+
+func (d *Database) GetDirectMessages(ctx context.Context, user1, user2 string, beforeId int32, limit int) ([]*database.DirectMessage, error) {
+	ctx, span := tracer.Start(ctx, "database.GetDirectMessages")
+	defer span.End()
+	result, err := d.db.GetDirectMessages(ctx, user1, user2, beforeId, limit)
+	return result, recordError(span, err)
+}
+
+func (d *Database) MarkDirectMessagesRead(ctx context.Context, recipient, sender string, upToId int32) (int64, error) {
+	ctx, span := tracer.Start(ctx, "database.MarkDirectMessagesRead")
+	defer span.End()
+	result, err := d.db.MarkDirectMessagesRead(ctx, recipient, sender, upToId)
+	return result, recordError(span, err)
+}
+
+func (d *Database) GetUnreadDirectMessageCounts(ctx context.Context, recipient string) (map[string]int, error) {
+	ctx, span := tracer.Start(ctx, "database.GetUnreadDirectMessageCounts")
+	defer span.End()
+	result, err := d.db.GetUnreadDirectMessageCounts(ctx, recipient)
+	return result, recordError(span, err)
+}
+
+func (d *Database) GetDirectMessageContacts(ctx context.Context, userName string) ([]string, error) {
+	ctx, span := tracer.Start(ctx, "database.GetDirectMessageContacts")
+	defer span.End()
+	result, err := d.db.GetDirectMessageContacts(ctx, userName)
+	return result, recordError(span, err)
+}
+
+func (d *Database) PutNotification(ctx context.Context, notification *database.Notification) error {
+	ctx, span := tracer.Start(ctx, "database.PutNotification")
+	defer span.End()
+	err := d.db.PutNotification(ctx, notification)
+	return recordError(span, err)
+}
+
+func (d *Database) GetNotifications(ctx context.Context, userName string, opts *database.ListOptions) (*database.Page[*database.Notification], error) {
+	ctx, span := tracer.Start(ctx, "database.GetNotifications")
+	defer span.End()
+	result, err := d.db.GetNotifications(ctx, userName, opts)
+	return result, recordError(span, err)
+}
+
+func (d *Database) GetUnreadNotificationCount(ctx context.Context, userName string) (int, error) {
+	ctx, span := tracer.Start(ctx, "database.GetUnreadNotificationCount")
+	defer span.End()
+	result, err := d.db.GetUnreadNotificationCount(ctx, userName)
+	return result, recordError(span, err)
+}
+
+func (d *Database) MarkNotificationRead(ctx context.Context, userName string, notificationId int32) error {
+	ctx, span := tracer.Start(ctx, "database.MarkNotificationRead")
+	defer span.End()
+	err := d.db.MarkNotificationRead(ctx, userName, notificationId)
+	return recordError(span, err)
+}
+
+func (d *Database) MarkAllNotificationsRead(ctx context.Context, userName string) (int64, error) {
+	ctx, span := tracer.Start(ctx, "database.MarkAllNotificationsRead")
+	defer span.End()
+	result, err := d.db.MarkAllNotificationsRead(ctx, userName)
+	return result, recordError(span, err)
+}
+
+func (d *Database) PutWebhookSubscription(ctx context.Context, subscription *database.WebhookSubscription) error {
+	ctx, span := tracer.Start(ctx, "database.PutWebhookSubscription")
+	defer span.End()
+	err := d.db.PutWebhookSubscription(ctx, subscription)
+	return recordError(span, err)
+}
+
+func (d *Database) GetWebhookSubscriptions(ctx context.Context) ([]*database.WebhookSubscription, error) {
+	ctx, span := tracer.Start(ctx, "database.GetWebhookSubscriptions")
+	defer span.End()
+	result, err := d.db.GetWebhookSubscriptions(ctx)
+	return result, recordError(span, err)
+}
+
+func (d *Database) GetWebhookSubscriptionsForEvent(ctx context.Context, eventType string) ([]*database.WebhookSubscription, error) {
+	ctx, span := tracer.Start(ctx, "database.GetWebhookSubscriptionsForEvent")
+	defer span.End()
+	result, err := d.db.GetWebhookSubscriptionsForEvent(ctx, eventType)
+	return result, recordError(span, err)
+}
+
+func (d *Database) DeleteWebhookSubscription(ctx context.Context, subscriptionId int32) error {
+	ctx, span := tracer.Start(ctx, "database.DeleteWebhookSubscription")
+	defer span.End()
+	err := d.db.DeleteWebhookSubscription(ctx, subscriptionId)
+	return recordError(span, err)
+}
+
+func (d *Database) PutWebhookDelivery(ctx context.Context, delivery *database.WebhookDelivery) error {
+	ctx, span := tracer.Start(ctx, "database.PutWebhookDelivery")
+	defer span.End()
+	err := d.db.PutWebhookDelivery(ctx, delivery)
+	return recordError(span, err)
+}
+
+func (d *Database) IsWebhookDelivered(ctx context.Context, subscriptionId int32, eventId string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "database.IsWebhookDelivered")
+	defer span.End()
+	result, err := d.db.IsWebhookDelivered(ctx, subscriptionId, eventId)
+	return result, recordError(span, err)
+}
+
+func (d *Database) GetWebhookDeliveries(ctx context.Context, subscriptionId int32, limit int) ([]*database.WebhookDelivery, error) {
+	ctx, span := tracer.Start(ctx, "database.GetWebhookDeliveries")
+	defer span.End()
+	result, err := d.db.GetWebhookDeliveries(ctx, subscriptionId, limit)
+	return result, recordError(span, err)
+}
+
+func (d *Database) PutWebhookDeadLetter(ctx context.Context, deadLetter *database.WebhookDeadLetter) error {
+	ctx, span := tracer.Start(ctx, "database.PutWebhookDeadLetter")
+	defer span.End()
+	err := d.db.PutWebhookDeadLetter(ctx, deadLetter)
+	return recordError(span, err)
+}
+
+func (d *Database) GetWebhookDeadLetters(ctx context.Context, limit int) ([]*database.WebhookDeadLetter, error) {
+	ctx, span := tracer.Start(ctx, "database.GetWebhookDeadLetters")
+	defer span.End()
+	result, err := d.db.GetWebhookDeadLetters(ctx, limit)
+	return result, recordError(span, err)
+}
+
+func (d *Database) PublishOutboxEvents(ctx context.Context, limit int, publish func(context.Context, *events.Event) error) (int, error) {
+	ctx, span := tracer.Start(ctx, "database.PublishOutboxEvents")
+	defer span.End()
+	result, err := d.db.PublishOutboxEvents(ctx, limit, publish)
+	return result, recordError(span, err)
+}
+
+func (d *Database) DeletePublishedOutboxEvents(ctx context.Context, publishedBefore time.Time) (int64, error) {
+	ctx, span := tracer.Start(ctx, "database.DeletePublishedOutboxEvents")
+	defer span.End()
+	result, err := d.db.DeletePublishedOutboxEvents(ctx, publishedBefore)
+	return result, recordError(span, err)
+}
+
+func (d *Database) Close() {
+	d.db.Close()
+}
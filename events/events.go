@@ -0,0 +1,162 @@
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+type Type string
+
+const (
+	Type_FriendRequestSent     Type = "friend.request_sent"
+	Type_FriendRequestAccepted Type = "friend.request_accepted"
+	Type_FriendRemoved         Type = "friend.removed"
+	Type_PartyCreated          Type = "party.created"
+	Type_PartyMemberInvited    Type = "party.member_invited"
+	Type_PartyMemberJoined     Type = "party.member_joined"
+	Type_PartyMemberLeft       Type = "party.member_left"
+	Type_PartyMemberRemoved    Type = "party.member_removed"
+	Type_PartyStateChanged     Type = "party.state_changed"
+	Type_PresenceOnline        Type = "presence.online"
+	Type_PresenceOffline       Type = "presence.offline"
+)
+
+// Types lists every event type, in the order they are documented
+var Types = []Type{
+	Type_FriendRequestSent,
+	Type_FriendRequestAccepted,
+	Type_FriendRemoved,
+	Type_PartyCreated,
+	Type_PartyMemberInvited,
+	Type_PartyMemberJoined,
+	Type_PartyMemberLeft,
+	Type_PartyMemberRemoved,
+	Type_PartyStateChanged,
+	Type_PresenceOnline,
+	Type_PresenceOffline,
+}
+
+// IsValid tells if the type is one of Types
+func (t Type) IsValid() bool {
+	for _, eachType := range Types {
+		if t == eachType {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is a social event, published on the bus for whoever listens to it
+type Event struct {
+	Id   string `json:"id"`
+	Type Type   `json:"type"`
+	// the user who caused the event, empty for the ones caused by the server
+	Actor string `json:"actor,omitempty"`
+	// the other user of friend and party member events
+	UserName  string    `json:"user_name,omitempty"`
+	PartyName string    `json:"party_name,omitempty"`
+	State     string    `json:"state,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func New(eventType Type, actor string) *Event {
+	return &Event{
+		Id:        newId(),
+		Type:      eventType,
+		Actor:     actor,
+		CreatedAt: time.Now(),
+	}
+}
+
+// newId returns a random id, unique enough for receivers to drop duplicate deliveries
+func newId() string {
+	id := make([]byte, 16)
+	_, err := rand.Read(id)
+	if err != nil {
+		log.Printf("[ERROR] events.newId: reading random bytes: %s", err.Error())
+		return time.Now().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(id)
+}
+
+// Bus fans published events out to every subscription
+type Bus struct {
+	rwmutex       sync.RWMutex
+	subscriptions map[*Subscription]bool
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		subscriptions: make(map[*Subscription]bool),
+	}
+}
+
+// Subscription receives the events published after it was made, until closed
+type Subscription struct {
+	Events <-chan *Event
+	events chan *Event
+	bus    *Bus
+}
+
+// Subscribe buffers up to bufferSize events for the subscription,
+// Publish never waits on a subscriber so events beyond that are dropped, PublishWait waits for room instead
+func (b *Bus) Subscribe(bufferSize int) *Subscription {
+	events := make(chan *Event, bufferSize)
+	subscription := &Subscription{
+		Events: events,
+		events: events,
+		bus:    b,
+	}
+
+	b.rwmutex.Lock()
+	b.subscriptions[subscription] = true
+	b.rwmutex.Unlock()
+	return subscription
+}
+
+// Publish hands the event to every subscription
+func (b *Bus) Publish(event *Event) {
+	b.rwmutex.RLock()
+	defer b.rwmutex.RUnlock()
+
+	for eachSubscription := range b.subscriptions {
+		select {
+		case eachSubscription.events <- event:
+		default:
+			log.Printf("[ERROR] events.Publish: subscription is full, dropping event %s of type %s", event.Id, event.Type)
+		}
+	}
+}
+
+// PublishWait hands the event to every subscription, waiting for the full ones until the context is done.
+// When it fails some subscriptions may have received the event already, so publishing it again duplicates it there
+func (b *Bus) PublishWait(ctx context.Context, event *Event) error {
+	b.rwmutex.RLock()
+	defer b.rwmutex.RUnlock()
+
+	for eachSubscription := range b.subscriptions {
+		select {
+		case eachSubscription.events <- event:
+		case <-ctx.Done():
+			return fmt.Errorf("events.PublishWait: handing event %s to a full subscription: %s", event.Id, ctx.Err().Error())
+		}
+	}
+	return nil
+}
+
+// Close stops the subscription and closes its events channel
+func (s *Subscription) Close() {
+	s.bus.rwmutex.Lock()
+	defer s.bus.rwmutex.Unlock()
+
+	if !s.bus.subscriptions[s] {
+		return
+	}
+	delete(s.bus.subscriptions, s)
+	close(s.events)
+}
@@ -0,0 +1,80 @@
+package events_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"socialite/events"
+)
+
+func TestBusPublish(t *testing.T) {
+	bus := events.NewBus()
+	first := bus.Subscribe(1)
+	second := bus.Subscribe(1)
+
+	bus.Publish(events.New(events.Type_PresenceOnline, "user1"))
+	// the subscriptions are full, publishing drops instead of waiting
+	bus.Publish(events.New(events.Type_PresenceOffline, "user1"))
+
+	for _, eachSubscription := range []*events.Subscription{first, second} {
+		event := <-eachSubscription.Events
+		if event.Type != events.Type_PresenceOnline {
+			t.Errorf("received %s, expected %s", event.Type, events.Type_PresenceOnline)
+		}
+		if len(eachSubscription.Events) != 0 {
+			t.Error("event beyond the buffer is kept")
+		}
+	}
+
+	first.Close()
+	first.Close()
+	if _, ok := <-first.Events; ok {
+		t.Error("closed subscription still receives events")
+	}
+	bus.Publish(events.New(events.Type_PresenceOnline, "user2"))
+	if event := <-second.Events; event.Actor != "user2" {
+		t.Errorf("received event of %s, expected user2", event.Actor)
+	}
+}
+
+func TestBusPublishWait(t *testing.T) {
+	bus := events.NewBus()
+	subscription := bus.Subscribe(1)
+
+	err := bus.PublishWait(context.Background(), events.New(events.Type_PresenceOnline, "user1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the subscription is full, publishing waits until the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	err = bus.PublishWait(ctx, events.New(events.Type_PresenceOffline, "user1"))
+	if err == nil {
+		t.Error("publishing to a full subscription does not fail")
+	}
+
+	// it is delivered once the subscriber catches up
+	delivered := make(chan error, 1)
+	go func() {
+		delivered <- bus.PublishWait(context.Background(), events.New(events.Type_PresenceOffline, "user1"))
+	}()
+	if event := <-subscription.Events; event.Type != events.Type_PresenceOnline {
+		t.Errorf("received %s, expected %s", event.Type, events.Type_PresenceOnline)
+	}
+	if err = <-delivered; err != nil {
+		t.Error(err)
+	}
+	if event := <-subscription.Events; event.Type != events.Type_PresenceOffline {
+		t.Errorf("received %s, expected %s", event.Type, events.Type_PresenceOffline)
+	}
+}
+
+func TestTypeIsValid(t *testing.T) {
+	if !events.Type_PartyStateChanged.IsValid() {
+		t.Error("known type is invalid")
+	}
+	if events.Type("party.deleted").IsValid() {
+		t.Error("unknown type is valid")
+	}
+}
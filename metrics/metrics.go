@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "socialite"
+
+// connection types of the websocket and message metrics
+const (
+	Connection_Status      = "status"
+	Connection_Party       = "party"
+	Connection_EventStream = "event_stream"
+)
+
+// reasons of the dropped messages metric, see the slow consumer policies of the server
+const (
+	Drop_Dropped      = "dropped"
+	Drop_Coalesced    = "coalesced"
+	Drop_Disconnected = "disconnected"
+)
+
+var (
+	HttpRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route and status code.",
+		},
+		[]string{"method", "route", "status"},
+	)
+	HttpRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method and route, websockets and event streams last as long as the connection.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"method", "route"},
+	)
+	WebsocketConnections = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "websocket_connections",
+			Help:      "Open status websockets, party websockets and event streams.",
+		},
+		[]string{"type"},
+	)
+	MessagesSent = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "messages_sent_total",
+			Help:      "Messages written to clients by connection type.",
+		},
+		[]string{"type"},
+	)
+	MessagesDropped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "messages_dropped_total",
+			Help:      "Messages not delivered to slow clients by connection type and reason.",
+		},
+		[]string{"type", "reason"},
+	)
+	CronDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "cron_duration_seconds",
+			Help:      "Duration of each run of a cron.",
+			Buckets:   []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60},
+		},
+		[]string{"cron"},
+	)
+	CronFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cron_failures_total",
+			Help:      "Runs of a cron that failed.",
+		},
+		[]string{"cron"},
+	)
+)
+
+// NewRegistry registers the metrics of the service along with the go runtime and process metrics,
+// and the collectors given for the connections of the server
+func NewRegistry(serverCollectors ...prometheus.Collector) *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HttpRequests,
+		HttpRequestDuration,
+		WebsocketConnections,
+		MessagesSent,
+		MessagesDropped,
+		CronDuration,
+		CronFailures,
+	)
+	registry.MustRegister(serverCollectors...)
+	return registry
+}
+
+// NewOnlineUsersGauge reports the users connected to this server, as counted by count
+func NewOnlineUsersGauge(count func() int) prometheus.Collector {
+	return prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "online_users",
+			Help:      "Users with a status websocket or an event stream on this server.",
+		},
+		func() float64 {
+			return float64(count())
+		},
+	)
+}
@@ -0,0 +1,58 @@
+package metrics_test
+
+import (
+	"strings"
+	"testing"
+
+	"socialite/metrics"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type cacheStats struct {
+	hits, misses uint64
+}
+
+func (c *cacheStats) Stats() (uint64, uint64) {
+	return c.hits, c.misses
+}
+
+func TestCacheCollector(t *testing.T) {
+	collector := metrics.NewCacheCollector(&cacheStats{hits: 3, misses: 1})
+
+	expected := `
+# HELP socialite_cache_hit_ratio Share of the cache lookups that found a value.
+# TYPE socialite_cache_hit_ratio gauge
+socialite_cache_hit_ratio 0.75
+# HELP socialite_cache_hits_total Cache lookups that found a value.
+# TYPE socialite_cache_hits_total counter
+socialite_cache_hits_total 3
+# HELP socialite_cache_misses_total Cache lookups that found nothing.
+# TYPE socialite_cache_misses_total counter
+socialite_cache_misses_total 1
+`
+	err := testutil.CollectAndCompare(collector, strings.NewReader(expected))
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNewRegistry(t *testing.T) {
+	online := 2
+	registry := metrics.NewRegistry(metrics.NewOnlineUsersGauge(func() int { return online }))
+	metrics.MessagesDropped.WithLabelValues(metrics.Connection_Status, metrics.Drop_Coalesced).Inc()
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gathered := make(map[string]bool, len(families))
+	for _, eachFamily := range families {
+		gathered[eachFamily.GetName()] = true
+	}
+	for _, eachName := range []string{"socialite_online_users", "socialite_messages_dropped_total", "go_goroutines"} {
+		if !gathered[eachName] {
+			t.Errorf("%s is not gathered", eachName)
+		}
+	}
+}
@@ -5,6 +5,9 @@ var (
 	Err_UserAlreadyRegistered             = GeneralResponse{Message: "user already registered"}
 	Err_UserNotFound                      = GeneralResponse{Message: "user not found"}
 	Err_UserIdMissing                     = GeneralResponse{Message: "user_id is missing"}
+	Err_UserIdInvalid                     = GeneralResponse{Message: "user_id is invalid"}
+	Err_PartyIdMissing                    = GeneralResponse{Message: "party_id is missing"}
+	Err_PartyIdInvalid                    = GeneralResponse{Message: "party_id is invalid"}
 	Err_CannotSendRequestToSelf           = GeneralResponse{Message: "cannot send request to self"}
 	Err_FriendshipNotFound                = GeneralResponse{Message: "friendship not found"}
 	Err_FriendshipAlreadyExists           = GeneralResponse{Message: "friendship already exists"}
@@ -16,13 +19,50 @@ var (
 	Err_FriendshipRequestAlreadyConfirmed = GeneralResponse{Message: "friendship request already confirmed"}
 	Err_SomethingWrong                    = GeneralResponse{Message: "something went wrong"}
 	Err_AuthHeaderMissing                 = GeneralResponse{Message: "'Authorization' header is missing"}
+	Err_UserRevoked                       = GeneralResponse{Message: "user is not registered or has been deleted"}
 	Err_PartyNotFound                     = GeneralResponse{Message: "party not found"}
 	Err_NotPartyCreator                   = GeneralResponse{Message: "you are not the creator of this party"}
 	Err_UserAlreadyInParty                = GeneralResponse{Message: "user is already in this party"}
 	Err_PartyInvitationNotFound           = GeneralResponse{Message: "party invitation not found"}
 	Err_PartyMembershipNotFound           = GeneralResponse{Message: "party membership not found"}
+	Err_PartyMembershipStatusInvalid      = GeneralResponse{Message: "status must be active or invited"}
 	Err_CannotInviteSelf                  = GeneralResponse{Message: "cannot invite self to party"}
 	Err_PartyCreatorCannotLeave           = GeneralResponse{Message: "party creator cannot leave party"}
+	Err_PartyStateChangeInvalid           = GeneralResponse{Message: "party state cannot be changed"}
+	Err_PartyReadyCheckNotRunning         = GeneralResponse{Message: "party ready check is not running"}
+	Err_UnknownMessageType                = GeneralResponse{Message: "unknown message type"}
+	Err_PaginationInvalid                 = GeneralResponse{Message: "pagination parameters are invalid"}
+	Err_MessageTooLong                    = GeneralResponse{Message: "message is too long"}
+	Err_RateLimited                       = GeneralResponse{Message: "too many messages, slow down"}
+	Err_NotFriends                        = GeneralResponse{Message: "you are not friends with this user"}
+	Err_CannotMessageSelf                 = GeneralResponse{Message: "cannot send message to self"}
+	Err_CannotBlockSelf                   = GeneralResponse{Message: "cannot block self"}
+	Err_UserAlreadyBlocked                = GeneralResponse{Message: "user is already blocked"}
+	Err_BlockNotFound                     = GeneralResponse{Message: "block not found"}
+	Err_UserBlocked                       = GeneralResponse{Message: "you have blocked this user"}
+	Err_SearchQueryMissing                = GeneralResponse{Message: "search query is missing"}
+	Err_SearchQueryTooLong                = GeneralResponse{Message: "search query is too long"}
+	Err_ExportFormatInvalid               = GeneralResponse{Message: "export format must be json or zip"}
+	Err_GroupIdMissing                    = GeneralResponse{Message: "group_id is missing"}
+	Err_GroupIdInvalid                    = GeneralResponse{Message: "group_id is invalid"}
+	Err_FriendGroupNotFound               = GeneralResponse{Message: "friend group not found"}
+	Err_FriendGroupNameTaken              = GeneralResponse{Message: "friend group name is already taken"}
+	Err_FriendGroupLimitReached           = GeneralResponse{Message: "friend group limit reached"}
+	Err_FriendAlreadyInGroup              = GeneralResponse{Message: "friend is already in this group"}
+	Err_FriendNotInGroup                  = GeneralResponse{Message: "friend is not in this group"}
+	Err_NotificationIdMissing             = GeneralResponse{Message: "notification_id is missing"}
+	Err_NotificationIdInvalid             = GeneralResponse{Message: "notification_id is invalid"}
+	Err_NotificationNotFound              = GeneralResponse{Message: "notification not found"}
+	Err_AdminTokenInvalid                 = GeneralResponse{Message: "admin token is invalid"}
+	Err_WebhooksDisabled                  = GeneralResponse{Message: "webhook admin is disabled"}
+	Err_WebhookIdMissing                  = GeneralResponse{Message: "webhook_id is missing"}
+	Err_WebhookIdInvalid                  = GeneralResponse{Message: "webhook_id is invalid"}
+	Err_WebhookNotFound                   = GeneralResponse{Message: "webhook not found"}
+	Err_WebhookEventTypeInvalid           = GeneralResponse{Message: "webhook event type is invalid"}
+	Err_PresenceScopeInvalid              = GeneralResponse{Message: "presence scope must be all, favorites or group"}
+	Err_LastEventIdInvalid                = GeneralResponse{Message: "Last-Event-ID is invalid"}
+	Err_AlreadyResumed                    = GeneralResponse{Message: "status websocket is already resumed"}
+	Err_ShuttingDown                      = GeneralResponse{Message: "server is shutting down, reconnect"}
 )
 
 var (
@@ -0,0 +1,25 @@
+package server
+
+import (
+	"time"
+
+	"socialite/events"
+)
+
+const (
+	// eventsBufferSize is how many events a bus subscriber of the server may fall behind by
+	eventsBufferSize = 1_000
+	// outboxPollInterval is how long the outbox publisher waits once the outbox is empty
+	outboxPollInterval = time.Second
+	// outboxRetention is how long published outbox events are kept before being purged
+	outboxRetention = 24 * time.Hour
+)
+
+// PublishEvent publishes an event about the user and party, either may be empty,
+// events of database mutations are published from the outbox instead
+func (s *Server) PublishEvent(eventType events.Type, actor, userName, partyName string) {
+	event := events.New(eventType, actor)
+	event.UserName = userName
+	event.PartyName = partyName
+	s.events.Publish(event)
+}
@@ -0,0 +1,218 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"socialite/cache"
+	"socialite/database"
+	"socialite/events"
+	"socialite/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// eventStream is a server-sent events connection of a user, fed by the same routing as the status websocket
+type eventStream struct {
+	queue     *sendQueue[*cache.UserEvent]
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newEventStream(queueSize int, policy SlowConsumerPolicy) *eventStream {
+	return &eventStream{
+		queue: newSendQueue(metrics.Connection_EventStream, queueSize, policy, presenceCoalesceKey),
+		done:  make(chan struct{}),
+	}
+}
+
+// write queues the event for the stream without waiting on it,
+// a stream too slow to keep up is closed under the disconnect policy
+func (e *eventStream) write(event *cache.UserEvent) {
+	if !e.queue.push(event) {
+		e.close()
+	}
+}
+
+// close stops the stream, its handler then returns
+func (e *eventStream) close() {
+	e.closeOnce.Do(func() { close(e.done) })
+}
+
+func (s *Server) addEventStream(userName string, stream *eventStream) {
+	s.rwmutex.Lock()
+	wasConnected := s.isUserConnected(userName)
+	if s.userEventStreams[userName] == nil {
+		s.userEventStreams[userName] = make(map[*eventStream]bool)
+	}
+	s.userEventStreams[userName][stream] = true
+	s.rwmutex.Unlock()
+
+	if !wasConnected {
+		s.PublishEvent(events.Type_PresenceOnline, userName, "", "")
+	}
+}
+
+func (s *Server) removeEventStream(userName string, stream *eventStream) {
+	stream.close()
+
+	s.rwmutex.Lock()
+	delete(s.userEventStreams[userName], stream)
+	if len(s.userEventStreams[userName]) == 0 {
+		delete(s.userEventStreams, userName)
+	}
+	connected := s.isUserConnected(userName)
+	if !connected {
+		delete(s.userPresenceScopes, userName)
+	}
+	s.rwmutex.Unlock()
+
+	if !connected {
+		s.PublishEvent(events.Type_PresenceOffline, userName, "", "")
+	}
+}
+
+// EventStream streams the status messages of the status websocket as server-sent events,
+// a client reconnecting with the Last-Event-ID header first receives the ones it missed
+func (s *Server) EventStream(ginCtx *gin.Context) {
+	// get user from context
+	user, exists := ginCtx.Get(Header_AuthUserKey)
+	if !exists || user == nil {
+		ginCtx.JSON(http.StatusUnauthorized, Err_AuthHeaderMissing)
+		return
+	}
+	userInstance := user.(*database.User)
+	if s.draining.Load() {
+		ginCtx.JSON(http.StatusServiceUnavailable, Err_ShuttingDown)
+		return
+	}
+
+	// read the sequence number of the last event received
+	var lastSeq int64
+	lastEventId := ginCtx.GetHeader(Header_LastEventId)
+	if lastEventId != "" {
+		var err error
+		lastSeq, err = strconv.ParseInt(lastEventId, 10, 64)
+		if err != nil || lastSeq < 0 {
+			ginCtx.JSON(http.StatusBadRequest, Err_LastEventIdInvalid)
+			return
+		}
+	}
+
+	// same presence scope as the subscribe message of the status websocket
+	if scope := ginCtx.Query("scope"); scope != "" {
+		var groupId int64
+		if groupIdQuery := ginCtx.Query("group_id"); groupIdQuery != "" {
+			var err error
+			groupId, err = strconv.ParseInt(groupIdQuery, 10, 32)
+			if err != nil {
+				ginCtx.JSON(http.StatusBadRequest, Err_GroupIdInvalid)
+				return
+			}
+		}
+		errResp := s.SetPresenceScope(ginCtx, userInstance.Name, PresenceScope(scope), int32(groupId))
+		if errResp != nil {
+			ginCtx.JSON(http.StatusBadRequest, errResp)
+			return
+		}
+	}
+
+	metrics.WebsocketConnections.WithLabelValues(metrics.Connection_EventStream).Inc()
+	defer metrics.WebsocketConnections.WithLabelValues(metrics.Connection_EventStream).Dec()
+
+	// register before replaying, events routed in between are skipped by their sequence number
+	stream := newEventStream(s.websocketSendQueueSize, s.websocketSlowConsumerPolicy)
+	s.addEventStream(userInstance.Name, stream)
+	defer s.removeEventStream(userInstance.Name, stream)
+
+	ginCtx.Header("Content-Type", "text/event-stream")
+	ginCtx.Header("Cache-Control", "no-cache")
+	ginCtx.Header("Connection", "keep-alive")
+	ginCtx.Header("X-Accel-Buffering", "no")
+	ginCtx.Status(http.StatusOK)
+	ginCtx.Writer.Flush()
+
+	if lastEventId != "" {
+		missed, complete, err := s.cache.GetUserEventsAfter(ginCtx, userInstance.Name, lastSeq)
+		if err != nil {
+			log.Printf("[ERROR] getting events of user %s from cache : %s", userInstance.Name, err.Error())
+		}
+		// the client has to fetch its state again, the events it missed are gone
+		if err != nil || !complete {
+			resync := &cache.UserEvent{Type: MessageType_Resync, Message: &WebsocketStatusOutgoingMessage{MsgType: MessageType_Resync}}
+			if !writeEventStream(ginCtx, resync) {
+				return
+			}
+		}
+		for _, eachEvent := range missed {
+			if !writeEventStream(ginCtx, eachEvent) {
+				return
+			}
+			lastSeq = eachEvent.Seq
+		}
+	}
+	s.userOnlineStatus <- userInstance.Name
+
+	// heartbeats at the websocket ping interval keep proxies from closing the idle stream and the user online
+	heartbeat := time.NewTicker(s.websocketPingInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ginCtx.Request.Context().Done():
+			return
+		case <-stream.done:
+			// the client reconnects to another server
+			if s.draining.Load() {
+				reconnect := &cache.UserEvent{Type: MessageType_Reconnect, Message: &WebsocketStatusOutgoingMessage{MsgType: MessageType_Reconnect}}
+				writeEventStream(ginCtx, reconnect)
+			}
+			return
+		case <-heartbeat.C:
+			_, err := ginCtx.Writer.WriteString(": heartbeat\n\n")
+			if err != nil {
+				return
+			}
+			ginCtx.Writer.Flush()
+			s.userOnlineStatus <- userInstance.Name
+		case <-stream.queue.ready:
+			for _, eachEvent := range stream.queue.popAll() {
+				// already replayed
+				if eachEvent.Seq != 0 && eachEvent.Seq <= lastSeq {
+					continue
+				}
+				if !writeEventStream(ginCtx, eachEvent) {
+					return
+				}
+				if eachEvent.Seq != 0 {
+					lastSeq = eachEvent.Seq
+				}
+			}
+		}
+	}
+}
+
+// writeEventStream writes the event in the server-sent events format, events without a sequence number,
+// like presence, have no id so they do not move the Last-Event-ID of the client
+func writeEventStream(ginCtx *gin.Context, event *cache.UserEvent) bool {
+	data, err := marshalUserEvent(event)
+	if err != nil {
+		log.Printf("[ERROR] marshaling event stream message : %s", err.Error())
+		return true
+	}
+	if event.Seq != 0 {
+		_, err = fmt.Fprintf(ginCtx.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.Seq, event.Type, data)
+	} else {
+		_, err = fmt.Fprintf(ginCtx.Writer, "event: %s\ndata: %s\n\n", event.Type, data)
+	}
+	if err != nil {
+		log.Printf("[ERROR] writing event to event stream : %s", err.Error())
+		return false
+	}
+	ginCtx.Writer.Flush()
+	metrics.MessagesSent.WithLabelValues(metrics.Connection_EventStream).Inc()
+	return true
+}
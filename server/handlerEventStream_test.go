@@ -0,0 +1,31 @@
+package server
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"socialite/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestWriteEventStream(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	ginCtx, _ := gin.CreateTestContext(recorder)
+
+	writeEventStream(ginCtx, &cache.UserEvent{
+		Seq:     7,
+		Type:    MessageType_Notification,
+		Message: &WebsocketStatusOutgoingMessage{MsgType: MessageType_Notification, UserName: "user2"},
+	})
+	writeEventStream(ginCtx, &cache.UserEvent{
+		Type:    MessageType_FriendsOnline,
+		Message: &WebsocketStatusOutgoingMessage{MsgType: MessageType_FriendsOnline, UserName: "user3"},
+	})
+
+	expected := "id: 7\nevent: notification\ndata: {\"msg_type\":\"notification\",\"seq\":7,\"user_name\":\"user2\"}\n\n" +
+		"event: friends_online\ndata: {\"msg_type\":\"friends_online\",\"user_name\":\"user3\"}\n\n"
+	if recorder.Body.String() != expected {
+		t.Errorf("stream is %q, expected %q", recorder.Body.String(), expected)
+	}
+}
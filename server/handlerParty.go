@@ -3,6 +3,7 @@ package server
 import (
 	"log"
 	"net/http"
+	"time"
 
 	"socialite/database"
 
@@ -55,7 +56,14 @@ func (s *Server) GetCreatedParties(ginCtx *gin.Context) {
 	}
 	userInstance := user.(*database.User)
 
-	parties, err := s.db.GetCreatedParties(ginCtx, userInstance.Name)
+	// read pagination, sorting and filters
+	opts, err := ReadListOptions(ginCtx, database.ListSort_CreatedAt, database.ListSort_Name)
+	if err != nil {
+		ginCtx.JSON(http.StatusBadRequest, GeneralResponse{Message: err.Error()})
+		return
+	}
+
+	parties, err := s.db.GetCreatedParties(ginCtx, userInstance.Name, opts)
 	if err != nil {
 		log.Printf("[ERROR] server.GetCreatedParties: getting parties from db: %s", err.Error())
 		ginCtx.JSON(http.StatusBadRequest, GeneralResponse{Message: err.Error()})
@@ -64,3 +72,157 @@ func (s *Server) GetCreatedParties(ginCtx *gin.Context) {
 
 	ginCtx.JSON(http.StatusOK, parties)
 }
+
+func (s *Server) GetUserParties(ginCtx *gin.Context) {
+	// get user from context
+	user, exists := ginCtx.Get(Header_AuthUserKey)
+	if !exists || user == nil {
+		ginCtx.JSON(http.StatusUnauthorized, Err_AuthHeaderMissing)
+		return
+	}
+	userInstance := user.(*database.User)
+
+	// every membership unless filtered by status
+	status := database.PartyMembership_Status(ginCtx.Query("status"))
+	if status != "" && status != database.PartyMembership_Status_Active && status != database.PartyMembership_Status_Invited {
+		ginCtx.JSON(http.StatusBadRequest, Err_PartyMembershipStatusInvalid)
+		return
+	}
+
+	parties, err := s.db.GetUserParties(ginCtx, userInstance.Name, status)
+	if err != nil {
+		log.Printf("[ERROR] server.GetUserParties: getting parties from db: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	ginCtx.JSON(http.StatusOK, s.skipExpiredInvitations(parties))
+}
+
+// skipExpiredInvitations drops the invitations which expired but are not purged yet
+func (s *Server) skipExpiredInvitations(parties []*database.UserParty) []*database.UserParty {
+	filtered := make([]*database.UserParty, 0, len(parties))
+	for _, eachParty := range parties {
+		if eachParty.Status == database.PartyMembership_Status_Invited && time.Since(eachParty.MembershipCreatedAt) > s.partyInviteExpiry {
+			continue
+		}
+		filtered = append(filtered, eachParty)
+	}
+	return filtered
+}
+
+func (s *Server) GetPartyInvitations(ginCtx *gin.Context) {
+	// get user from context
+	user, exists := ginCtx.Get(Header_AuthUserKey)
+	if !exists || user == nil {
+		ginCtx.JSON(http.StatusUnauthorized, Err_AuthHeaderMissing)
+		return
+	}
+	userInstance := user.(*database.User)
+
+	parties, err := s.db.GetUserParties(ginCtx, userInstance.Name, database.PartyMembership_Status_Invited)
+	if err != nil {
+		log.Printf("[ERROR] server.GetPartyInvitations: getting invitations from db: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	ginCtx.JSON(http.StatusOK, s.skipExpiredInvitations(parties))
+}
+
+func (s *Server) GetPartyDetails(ginCtx *gin.Context) {
+	// get user from context
+	user, exists := ginCtx.Get(Header_AuthUserKey)
+	if !exists || user == nil {
+		ginCtx.JSON(http.StatusUnauthorized, Err_AuthHeaderMissing)
+		return
+	}
+	userInstance := user.(*database.User)
+
+	// get party name from path
+	partyName, errResp := ReadPartyIdParam(ginCtx)
+	if errResp != nil {
+		ginCtx.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	party, err := s.db.GetParty(ginCtx, partyName)
+	if err != nil {
+		if err == database.Err_NotFound {
+			ginCtx.JSON(http.StatusNotFound, Err_PartyNotFound)
+			return
+		}
+		log.Printf("[ERROR] server.GetPartyDetails: getting party from db: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	memberships, err := s.db.GetPartyMemberships(ginCtx, partyName)
+	if err != nil {
+		log.Printf("[ERROR] server.GetPartyDetails: getting party memberships from db: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	// only members and invitees can see the party
+	isMember := false
+	for _, eachMembership := range memberships {
+		if eachMembership.UserName == userInstance.Name {
+			isMember = true
+			break
+		}
+	}
+	if !isMember {
+		ginCtx.JSON(http.StatusNotFound, Err_PartyNotFound)
+		return
+	}
+
+	// presence is hidden between blocked users
+	blockedUsers, err := s.db.GetBlockedUserNames(ginCtx, userInstance.Name)
+	if err != nil {
+		log.Printf("[ERROR] server.GetPartyDetails: getting blocked users from db: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+	blockedMap := make(map[string]bool, len(blockedUsers))
+	for _, eachUser := range blockedUsers {
+		blockedMap[eachUser] = true
+	}
+
+	memberNames := make([]string, 0, len(memberships))
+	for _, eachMembership := range memberships {
+		memberNames = append(memberNames, eachMembership.UserName)
+	}
+	profileSummaries, err := s.db.GetProfileSummaries(ginCtx, memberNames)
+	if err != nil {
+		log.Printf("[ERROR] server.GetPartyDetails: getting profile summaries from db: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	resp := PartyDetailsResponse{
+		Party:   party,
+		Members: make([]*PartyMemberResponse, 0, len(memberships)),
+	}
+	for _, eachMembership := range memberships {
+		member := &PartyMemberResponse{
+			UserName: eachMembership.UserName,
+			Status:   eachMembership.Status,
+			Role:     database.PartyMembership_Role_Member,
+			JoinedAt: eachMembership.CreatedAt,
+			Profile:  profileSummaries[eachMembership.UserName],
+		}
+		if eachMembership.UserName == party.Creator {
+			member.Role = database.PartyMembership_Role_Creator
+		}
+		if !blockedMap[eachMembership.UserName] {
+			member.Online, err = s.cache.IsUserOnline(ginCtx, eachMembership.UserName)
+			if err != nil {
+				log.Printf("[ERROR] server.GetPartyDetails: checking if user %s is online: %s", eachMembership.UserName, err.Error())
+			}
+		}
+		resp.Members = append(resp.Members, member)
+	}
+
+	ginCtx.JSON(http.StatusOK, resp)
+}
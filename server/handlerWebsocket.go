@@ -1,10 +1,15 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"socialite/cache"
 	"socialite/database"
+	"socialite/events"
+	"socialite/metrics"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -18,15 +23,67 @@ const (
 	MessageType_Pong                 = "pong"
 	MessageType_FriendsOnline        = "friends_online"
 	MessageType_FriendsOnlineInParty = "friends_online_in_party"
+	MessageType_ReadyCheckStart      = "ready_check_start"
+	MessageType_ReadyCheckResponse   = "ready_check_response"
+	MessageType_SetPartyState        = "set_party_state"
+	MessageType_PartyLobby           = "party_lobby"
+	MessageType_Error                = "error"
+	MessageType_ChatMessage          = "chat_message"
+	MessageType_DirectMessage        = "direct_message"
+	MessageType_DirectMessageRead    = "direct_message_read"
+	MessageType_Subscribe            = "subscribe"
+	MessageType_Notification         = "notification"
+	MessageType_Resync               = "resync"
+	MessageType_Resume               = "resume"
+	MessageType_Reconnect            = "reconnect"
 )
 
 type WebsocketStatusIncomingMessage struct {
-	MsgType MessageType `json:"msg_type"`
+	MsgType   MessageType `json:"msg_type"`
+	UserName  string      `json:"user_name"`
+	Text      string      `json:"text"`
+	MessageId int32       `json:"message_id"`
+	// presence scope of a subscribe message
+	Scope   PresenceScope `json:"scope"`
+	GroupId int32         `json:"group_id"`
+	// sequence number of the last message received, for a resume message
+	Seq int64 `json:"seq"`
 }
 
 type WebsocketStatusOutgoingMessage struct {
-	MsgType  MessageType `json:"msg_type"`
-	UserName string      `json:"user_name"`
+	MsgType MessageType `json:"msg_type"`
+	// sequence number of the messages kept for replay, in the order they were sent to the user
+	Seq           int64                   `json:"seq,omitempty"`
+	UserName      string                  `json:"user_name"`
+	DirectMessage *database.DirectMessage `json:"direct_message,omitempty"`
+	ReadUpToId    int32                   `json:"read_up_to_id,omitempty"`
+	Notification  *database.Notification  `json:"notification,omitempty"`
+	Scope         PresenceScope           `json:"scope,omitempty"`
+	Message       string                  `json:"message,omitempty"`
+}
+
+type WebsocketPartyIncomingMessage struct {
+	MsgType MessageType          `json:"msg_type"`
+	Ready   bool                 `json:"ready"`
+	State   database.Party_State `json:"state"`
+	Text    string               `json:"text"`
+}
+
+type WebsocketPartyOutgoingMessage struct {
+	MsgType     MessageType            `json:"msg_type"`
+	PartyName   string                 `json:"party_name,omitempty"`
+	State       database.Party_State   `json:"state,omitempty"`
+	ReadyCheck  *PartyReadyCheck       `json:"ready_check,omitempty"`
+	ChatMessage *database.PartyMessage `json:"chat_message,omitempty"`
+	Message     string                 `json:"message,omitempty"`
+}
+
+type PartyReadyCheck struct {
+	Deadline time.Time                                 `json:"deadline"`
+	Ready    int                                       `json:"ready"`
+	NotReady int                                       `json:"not_ready"`
+	Pending  int                                       `json:"pending"`
+	Members  map[string]database.PartyMembership_Ready `json:"members"`
 }
 
 func (s *Server) WebsocketStatus(ginCtx *gin.Context) {
@@ -37,6 +94,10 @@ func (s *Server) WebsocketStatus(ginCtx *gin.Context) {
 		return
 	}
 	userInstance := user.(*database.User)
+	if s.draining.Load() {
+		ginCtx.JSON(http.StatusServiceUnavailable, Err_ShuttingDown)
+		return
+	}
 	s.userOnlineStatus <- userInstance.Name
 
 	// upgrade to websocket
@@ -49,77 +110,331 @@ func (s *Server) WebsocketStatus(ginCtx *gin.Context) {
 		return
 	}
 	defer conn.Close()
-	closeChan := make(chan bool)
-	writeChan := make(chan []byte)
-	s.rwmutex.Lock()
-	s.userWebsocketChannels[userInstance.Name] = writeChan
-	s.rwmutex.Unlock()
 
+	metrics.WebsocketConnections.WithLabelValues(metrics.Connection_Status).Inc()
+	defer metrics.WebsocketConnections.WithLabelValues(metrics.Connection_Status).Dec()
+
+	// register connection to receive status messages, it is forgotten whichever way the handler returns
+	statusConn := newStatusConnection(conn, s.websocketSendQueueSize, s.websocketSlowConsumerPolicy)
+	s.addUserWebsocket(userInstance.Name, statusConn)
+	defer s.removeUserWebsocket(userInstance.Name, statusConn)
+	defer close(statusConn.done)
+	s.keepAlive(conn, userInstance.Name)
+
+	resumeChan := make(chan int64)
+
+	// Goroutine for writing messages and pings, a failed write closes the connection so the reads fail too
 	go func() {
-		defer func() { closeChan <- true }()
+		defer conn.Close()
+		pingTicker := time.NewTicker(s.websocketPingInterval)
+		defer pingTicker.Stop()
+		resume := newStatusResume(s.userEventsBufferSize)
 		for {
 			select {
-			case <-ginCtx.Done():
-				log.Printf("[ERROR] request context expired in status websocket")
+			case <-statusConn.done:
 				return
-			default:
-				msgType, msg, err := conn.ReadMessage()
-				if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
-					log.Printf("[ERROR] websocket conn is closed : %s", err.Error())
+			case <-pingTicker.C:
+				if !s.writePing(conn) {
 					return
 				}
-				if err != nil {
-					if err == websocket.ErrCloseSent {
+			case <-statusConn.queue.ready:
+				for _, eachEvent := range statusConn.queue.popAll() {
+					if resume.replayed(eachEvent) {
+						continue
+					}
+					conn.SetWriteDeadline(time.Now().Add(s.websocketWriteTimeout))
+					if !writeStatusEvent(conn, eachEvent) {
 						return
 					}
-					log.Printf("[ERROR] reading message from websocket : %s", err.Error())
-				}
-				if msgType != websocket.TextMessage {
-					log.Printf("[ERROR] websocket message type is not text : %d", msgType)
-					continue
+					resume.written(eachEvent)
 				}
-				incomingMsg := WebsocketStatusIncomingMessage{}
-				err = json.Unmarshal(msg, &incomingMsg)
-				if err != nil {
-					log.Printf("[ERROR] unmarshaling websocket message : %s", err.Error())
-					continue
-				}
-				// handle incoming message
-				switch incomingMsg.MsgType {
-				case MessageType_Ping:
-					resp := WebsocketStatusOutgoingMessage{MsgType: MessageType_Pong}
-					respBytes, err := json.Marshal(resp)
-					if err != nil {
-						log.Printf("[ERROR] marshaling websocket message : %s", err.Error())
-						continue
-					}
-					writeChan <- respBytes
-					s.userOnlineStatus <- userInstance.Name
-				default:
-					log.Printf("[ERROR] unknown message type : %s", incomingMsg.MsgType)
-					continue
+			case seq := <-resumeChan:
+				// replayed here so no message sent to the user in the meantime is written in between
+				conn.SetWriteDeadline(time.Now().Add(s.websocketWriteTimeout))
+				if !s.ResumeStatusWebsocket(ginCtx, conn, userInstance.Name, seq, resume) {
+					return
 				}
 			}
 		}
 	}()
 
-	// Goroutine for writing messages
-	go func() {
-		for {
+	// replies go through the same queue as the routed messages, without being kept for replay
+	reply := func(resp *WebsocketStatusOutgoingMessage) {
+		statusConn.write(&cache.UserEvent{Type: string(resp.MsgType), Message: resp})
+	}
+
+	// read messages until the connection is closed or the client stops answering pings
+	for {
+		msgType, msg, err := conn.ReadMessage()
+		if err != nil {
+			logWebsocketReadError(userInstance.Name, err)
+			return
+		}
+		if msgType != websocket.TextMessage {
+			log.Printf("[ERROR] websocket message type is not text : %d", msgType)
+			continue
+		}
+		incomingMsg := WebsocketStatusIncomingMessage{}
+		err = json.Unmarshal(msg, &incomingMsg)
+		if err != nil {
+			log.Printf("[ERROR] unmarshaling websocket message : %s", err.Error())
+			continue
+		}
+		// handle incoming message
+		msgCtx, span := startMessageSpan(ginCtx, "status", incomingMsg.MsgType)
+		switch incomingMsg.MsgType {
+		case MessageType_Ping:
+			// kept for older clients, the online status is refreshed by the pongs of protocol pings
+			reply(&WebsocketStatusOutgoingMessage{MsgType: MessageType_Pong})
+		case MessageType_DirectMessage:
+			resp := &WebsocketStatusOutgoingMessage{MsgType: MessageType_DirectMessage, UserName: userInstance.Name}
+			message, _, errResp := s.CreateDirectMessage(msgCtx, userInstance.Name, incomingMsg.UserName, incomingMsg.Text)
+			if errResp != nil {
+				resp = &WebsocketStatusOutgoingMessage{MsgType: MessageType_Error, Message: errResp.Message}
+			}
+			resp.DirectMessage = message
+			reply(resp)
+		case MessageType_DirectMessageRead:
+			errResp := s.MarkDirectMessagesRead(msgCtx, userInstance.Name, incomingMsg.UserName, incomingMsg.MessageId)
+			if errResp != nil {
+				reply(&WebsocketStatusOutgoingMessage{MsgType: MessageType_Error, Message: errResp.Message})
+			}
+		case MessageType_Subscribe:
+			resp := &WebsocketStatusOutgoingMessage{MsgType: MessageType_Subscribe, Scope: incomingMsg.Scope}
+			errResp := s.SetPresenceScope(msgCtx, userInstance.Name, incomingMsg.Scope, incomingMsg.GroupId)
+			if errResp != nil {
+				resp = &WebsocketStatusOutgoingMessage{MsgType: MessageType_Error, Message: errResp.Message}
+			}
+			reply(resp)
+		case MessageType_Resume:
 			select {
-			case <-ginCtx.Done():
-				return
-			case resp := <-writeChan:
-				err := conn.WriteMessage(websocket.TextMessage, resp)
-				if err != nil {
-					log.Printf("[ERROR] writing message to websocket : %s", err.Error())
-					return
-				}
+			case resumeChan <- incomingMsg.Seq:
+			case <-statusConn.done:
 			}
+		default:
+			log.Printf("[ERROR] unknown message type : %s", incomingMsg.MsgType)
 		}
-	}()
+		span.End()
+	}
+}
+
+// SendToUser numbers the message in the user's replay buffer and delivers it to the user's status websocket
+// and event streams, if connected, so connections resuming later can still receive it
+func (s *Server) SendToUser(userName string, msg *WebsocketStatusOutgoingMessage) {
+	event := &cache.UserEvent{Type: string(msg.MsgType), Message: msg, CreatedAt: time.Now()}
+	err := s.cache.PutUserEvent(context.Background(), userName, event)
+	if err != nil {
+		log.Printf("[ERROR] putting event of user %s in cache : %s", userName, err.Error())
+	}
+	s.deliverToUser(userName, event)
+}
+
+// marshalUserEvent marshals the status message of the event along with its sequence number
+func marshalUserEvent(event *cache.UserEvent) ([]byte, error) {
+	msg, ok := event.Message.(*WebsocketStatusOutgoingMessage)
+	if !ok {
+		return nil, fmt.Errorf("event message is a %T", event.Message)
+	}
+	sequenced := *msg
+	sequenced.Seq = event.Seq
+	return json.Marshal(sequenced)
+}
+
+// writeStatusEvent writes the event to the status websocket, false once the connection is broken
+func writeStatusEvent(conn *websocket.Conn, event *cache.UserEvent) bool {
+	data, err := marshalUserEvent(event)
+	if err != nil {
+		log.Printf("[ERROR] marshaling websocket message : %s", err.Error())
+		return true
+	}
+	err = conn.WriteMessage(websocket.TextMessage, data)
+	if err != nil {
+		log.Printf("[ERROR] writing message to websocket : %s", err.Error())
+		return false
+	}
+	metrics.MessagesSent.WithLabelValues(metrics.Connection_Status).Inc()
+	return true
+}
+
+// deliverToUser hands the event to every status connection of the user, without keeping it for replay
+func (s *Server) deliverToUser(userName string, event *cache.UserEvent) {
+	s.rwmutex.RLock()
+	statusConn := s.userWebsocketConns[userName]
+	streams := make([]*eventStream, 0, len(s.userEventStreams[userName]))
+	for eachStream := range s.userEventStreams[userName] {
+		streams = append(streams, eachStream)
+	}
+	s.rwmutex.RUnlock()
+
+	if statusConn != nil {
+		statusConn.write(event)
+	}
+	for _, eachStream := range streams {
+		eachStream.write(event)
+	}
+}
+
+// countConnectedUsers counts the users with a status websocket or an event stream on this server
+func (s *Server) countConnectedUsers() int {
+	s.rwmutex.RLock()
+	defer s.rwmutex.RUnlock()
+
+	count := len(s.userWebsocketConns)
+	for userName := range s.userEventStreams {
+		if s.userWebsocketConns[userName] == nil {
+			count++
+		}
+	}
+	return count
+}
+
+// connectedUserNames returns the users with a status or party websocket or an event stream on this server
+func (s *Server) connectedUserNames() []string {
+	names := make(map[string]bool)
+	s.rwmutex.RLock()
+	for userName := range s.userWebsocketConns {
+		names[userName] = true
+	}
+	for userName := range s.userEventStreams {
+		names[userName] = true
+	}
+	s.rwmutex.RUnlock()
+	s.partyRwmutex.RLock()
+	for _, eachPartyConns := range s.partyWebsocketConns {
+		for eachConn := range eachPartyConns {
+			names[eachConn.userName] = true
+		}
+	}
+	s.partyRwmutex.RUnlock()
+
+	userNames := make([]string, 0, len(names))
+	for userName := range names {
+		userNames = append(userNames, userName)
+	}
+	return userNames
+}
+
+// isUserConnected tells if the user has a status websocket or an event stream, s.rwmutex must be held
+func (s *Server) isUserConnected(userName string) bool {
+	return s.userWebsocketConns[userName] != nil || len(s.userEventStreams[userName]) > 0
+}
+
+// statusConnection is the status websocket of a user, fed by the same routing as the event streams
+type statusConnection struct {
+	ws    *websocket.Conn
+	queue *sendQueue[*cache.UserEvent]
+	done  chan struct{}
+}
+
+func newStatusConnection(ws *websocket.Conn, queueSize int, policy SlowConsumerPolicy) *statusConnection {
+	return &statusConnection{
+		ws:    ws,
+		queue: newSendQueue(metrics.Connection_Status, queueSize, policy, presenceCoalesceKey),
+		done:  make(chan struct{}),
+	}
+}
+
+// write queues the event for the connection's writer without waiting on it,
+// a connection too slow to keep up is closed under the disconnect policy
+func (c *statusConnection) write(event *cache.UserEvent) {
+	if !c.queue.push(event) {
+		go closeSlowConsumer(c.ws)
+	}
+}
+
+// presenceCoalesceKey is the key of presence updates, a newer update about the same friend supersedes a queued one
+func presenceCoalesceKey(event *cache.UserEvent) string {
+	if event.Seq != 0 || event.Type != MessageType_FriendsOnline {
+		return ""
+	}
+	msg, ok := event.Message.(*WebsocketStatusOutgoingMessage)
+	if !ok {
+		return ""
+	}
+	return msg.UserName
+}
+
+// addUserWebsocket makes the connection the user's status websocket, replacing any older one
+func (s *Server) addUserWebsocket(userName string, conn *statusConnection) {
+	s.rwmutex.Lock()
+	wasConnected := s.isUserConnected(userName)
+	s.userWebsocketConns[userName] = conn
+	s.rwmutex.Unlock()
 
-	<-closeChan
+	if !wasConnected {
+		s.PublishEvent(events.Type_PresenceOnline, userName, "", "")
+	}
+}
+
+// removeUserWebsocket forgets the user's status websocket, unless a newer one has replaced it
+func (s *Server) removeUserWebsocket(userName string, conn *statusConnection) {
+	s.rwmutex.Lock()
+	if s.userWebsocketConns[userName] != conn {
+		s.rwmutex.Unlock()
+		return
+	}
+	delete(s.userWebsocketConns, userName)
+	connected := s.isUserConnected(userName)
+	if !connected {
+		delete(s.userPresenceScopes, userName)
+	}
+	s.rwmutex.Unlock()
+
+	if !connected {
+		s.PublishEvent(events.Type_PresenceOffline, userName, "", "")
+	}
+}
+
+// CloseUserWebsockets closes the status and party websockets and the event streams of the user with the reason,
+// their handlers then return as the reads fail
+func (s *Server) CloseUserWebsockets(userName, reason string) {
+	closeMsg := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason)
+
+	s.rwmutex.RLock()
+	statusConn := s.userWebsocketConns[userName]
+	streams := make([]*eventStream, 0, len(s.userEventStreams[userName]))
+	for eachStream := range s.userEventStreams[userName] {
+		streams = append(streams, eachStream)
+	}
+	s.rwmutex.RUnlock()
+	if statusConn != nil {
+		statusConn.ws.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+		statusConn.ws.Close()
+	}
+	for _, eachStream := range streams {
+		eachStream.close()
+	}
+
+	s.partyRwmutex.RLock()
+	partyConns := make([]*partyConnection, 0)
+	for _, eachPartyConns := range s.partyWebsocketConns {
+		for eachConn := range eachPartyConns {
+			if eachConn.userName == userName {
+				partyConns = append(partyConns, eachConn)
+			}
+		}
+	}
+	s.partyRwmutex.RUnlock()
+	for _, eachConn := range partyConns {
+		eachConn.ws.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+		eachConn.ws.Close()
+	}
+}
+
+// ClosePartyWebsockets closes every websocket of the party with the reason
+func (s *Server) ClosePartyWebsockets(partyName, reason string) {
+	closeMsg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, reason)
+
+	s.partyRwmutex.RLock()
+	partyConns := make([]*partyConnection, 0, len(s.partyWebsocketConns[partyName]))
+	for eachConn := range s.partyWebsocketConns[partyName] {
+		partyConns = append(partyConns, eachConn)
+	}
+	s.partyRwmutex.RUnlock()
+	for _, eachConn := range partyConns {
+		eachConn.ws.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+		eachConn.ws.Close()
+	}
 }
 
 func (s *Server) WebsocketParty(ginCtx *gin.Context) {
@@ -130,14 +445,15 @@ func (s *Server) WebsocketParty(ginCtx *gin.Context) {
 		return
 	}
 	userInstance := user.(*database.User)
+	if s.draining.Load() {
+		ginCtx.JSON(http.StatusServiceUnavailable, Err_ShuttingDown)
+		return
+	}
 	s.userOnlineStatus <- userInstance.Name
 
-	partyName := ginCtx.Param("party_id")
-	if partyName == "" {
-		ginCtx.JSON(
-			http.StatusBadRequest,
-			GeneralResponse{Message: "party_id is required"},
-		)
+	partyName, errResp := ReadPartyIdParam(ginCtx)
+	if errResp != nil {
+		ginCtx.JSON(http.StatusBadRequest, errResp)
 		return
 	}
 
@@ -148,7 +464,7 @@ func (s *Server) WebsocketParty(ginCtx *gin.Context) {
 			ginCtx.JSON(http.StatusNotFound, Err_PartyMembershipNotFound)
 			return
 		}
-		log.Fatalf("[ERROR] getting party membership for partyname %s and user %s", partyName, userInstance.Name)
+		log.Printf("[ERROR] getting party membership for partyname %s and user %s : %s", partyName, userInstance.Name, err.Error())
 		ginCtx.JSON(
 			http.StatusInternalServerError,
 			Err_SomethingWrong,
@@ -167,52 +483,137 @@ func (s *Server) WebsocketParty(ginCtx *gin.Context) {
 	}
 	defer conn.Close()
 
-	for {
-		time.Sleep(time.Second * 5)
-		// get users friends from the cache
-		userfriendsList, err := s.cache.GetUserFriendsList(ginCtx, userInstance.Name)
-		if err != nil {
-			log.Printf("[ERROR] getting user friends from cache : %s", err.Error())
-			continue
-		}
-		// create a map of user's friends
-		usersOnlineMap := make(map[string]bool, len(userfriendsList))
-		for _, eachFriend := range userfriendsList {
-			usersOnlineMap[eachFriend] = false
-		}
-		// fetch party members from cache
-		partyMembers, err := s.cache.GetPartyMembersList(ginCtx, partyName)
-		if err != nil {
-			log.Printf("[ERROR] getting party members from cache : %s", err.Error())
-			continue
+	metrics.WebsocketConnections.WithLabelValues(metrics.Connection_Party).Inc()
+	defer metrics.WebsocketConnections.WithLabelValues(metrics.Connection_Party).Dec()
+
+	// register connection to receive party broadcasts
+	partyConn := newPartyConnection(userInstance.Name, conn, s.websocketSendQueueSize, s.websocketSlowConsumerPolicy)
+	s.addPartyConnection(partyName, partyConn)
+	defer s.removePartyConnection(partyName, partyConn)
+	defer close(partyConn.done)
+	s.keepAlive(conn, userInstance.Name)
+
+	// Goroutine for writing messages and pings, a failed write closes the connection so the reads fail too
+	go func() {
+		defer conn.Close()
+		pingTicker := time.NewTicker(s.websocketPingInterval)
+		defer pingTicker.Stop()
+		for {
+			select {
+			case <-partyConn.done:
+				return
+			case <-pingTicker.C:
+				if !s.writePing(conn) {
+					return
+				}
+			case <-partyConn.queue.ready:
+				for _, eachMsg := range partyConn.queue.popAll() {
+					conn.SetWriteDeadline(time.Now().Add(s.websocketWriteTimeout))
+					err := conn.WriteMessage(websocket.TextMessage, eachMsg.data)
+					if err != nil {
+						log.Printf("[ERROR] writing message to websocket : %s", err.Error())
+						return
+					}
+					metrics.MessagesSent.WithLabelValues(metrics.Connection_Party).Inc()
+				}
+			}
 		}
-		// get a list of users who are online
-		for _, eachMember := range partyMembers {
-			isUserOnline, err := s.cache.IsUserOnline(ginCtx, eachMember)
+	}()
+
+	// Goroutine for sending online friends in the party
+	go func() {
+		for {
+			select {
+			case <-partyConn.done:
+				return
+			case <-time.After(time.Second * 5):
+			}
+			usersOnlineMap, err := s.GetPartyOnlineFriends(ginCtx, partyName, userInstance.Name)
 			if err != nil {
-				log.Printf("[ERROR] checking if user %s is online : %s", eachMember, err.Error())
+				log.Printf("[ERROR] getting online friends in party : %s", err.Error())
 				continue
 			}
-			if isUserOnline {
-				usersOnlineMap[eachMember] = true
+			// send the list to the user
+			resp, err := json.Marshal(usersOnlineMap)
+			if err != nil {
+				log.Printf("[ERROR] marshaling users online map : %s", err.Error())
+				continue
 			}
+			partyConn.writePresence(resp)
 		}
-		// delete users who are offline
-		for eachUser := range usersOnlineMap {
-			if !usersOnlineMap[eachUser] {
-				delete(usersOnlineMap, eachUser)
-			}
+	}()
+
+	// send current lobby state so reconnecting members catch up
+	s.SendPartyLobby(ginCtx, partyName, partyConn)
+
+	// read messages until the connection is closed or the client stops answering pings
+	for {
+		msgType, msg, err := conn.ReadMessage()
+		if err != nil {
+			logWebsocketReadError(userInstance.Name, err)
+			return
+		}
+		if msgType != websocket.TextMessage {
+			log.Printf("[ERROR] websocket message type is not text : %d", msgType)
+			continue
 		}
-		// send the list to the user
-		resp, err := json.Marshal(usersOnlineMap)
+		incomingMsg := WebsocketPartyIncomingMessage{}
+		err = json.Unmarshal(msg, &incomingMsg)
 		if err != nil {
-			log.Printf("[ERROR] marshaling users online map : %s", err.Error())
+			log.Printf("[ERROR] unmarshaling websocket message : %s", err.Error())
+			continue
+		}
+		msgCtx, span := startMessageSpan(ginCtx, "party", incomingMsg.MsgType)
+		s.HandlePartyMessage(msgCtx, partyName, userInstance.Name, &incomingMsg, partyConn)
+		span.End()
+	}
+}
+
+// GetPartyOnlineFriends returns the online party members among the user's friends
+func (s *Server) GetPartyOnlineFriends(ctx context.Context, partyName, userName string) (map[string]bool, error) {
+	// get users friends from the cache
+	userfriendsList, err := s.cache.GetUserFriendsList(ctx, userName)
+	if err != nil {
+		return nil, fmt.Errorf("getting user friends from cache : %s", err.Error())
+	}
+	// create a map of user's friends
+	usersOnlineMap := make(map[string]bool, len(userfriendsList))
+	for _, eachFriend := range userfriendsList {
+		usersOnlineMap[eachFriend] = false
+	}
+	// fetch party members from cache
+	partyMembers, err := s.cache.GetPartyMembersList(ctx, partyName)
+	if err != nil {
+		return nil, fmt.Errorf("getting party members from cache : %s", err.Error())
+	}
+	// presence is hidden between blocked users
+	blockedUsers, err := s.db.GetBlockedUserNames(ctx, userName)
+	if err != nil {
+		return nil, fmt.Errorf("getting blocked users : %s", err.Error())
+	}
+	blockedMap := make(map[string]bool, len(blockedUsers))
+	for _, eachUser := range blockedUsers {
+		blockedMap[eachUser] = true
+	}
+	// get a list of users who are online
+	for _, eachMember := range partyMembers {
+		if blockedMap[eachMember] {
 			continue
 		}
-		err = conn.WriteMessage(websocket.TextMessage, resp)
+		isUserOnline, err := s.cache.IsUserOnline(ctx, eachMember)
 		if err != nil {
-			log.Printf("[ERROR] writing message to websocket : %s", err.Error())
-			return
+			log.Printf("[ERROR] checking if user %s is online : %s", eachMember, err.Error())
+			continue
+		}
+		if isUserOnline {
+			usersOnlineMap[eachMember] = true
+		}
+	}
+	// delete users who are offline
+	for eachUser := range usersOnlineMap {
+		if !usersOnlineMap[eachUser] {
+			delete(usersOnlineMap, eachUser)
 		}
 	}
+	return usersOnlineMap, nil
 }
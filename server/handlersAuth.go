@@ -3,7 +3,9 @@ package server
 import (
 	"log"
 	"net/http"
+
 	"socialite/database"
+	"socialite/validation"
 
 	"github.com/gin-gonic/gin"
 )
@@ -18,8 +20,15 @@ func (s *Server) Login(ginCtx *gin.Context) {
 		return
 	}
 
+	// names are stored normalized
+	userName, err := validation.Name(reqBody.Name)
+	if err != nil {
+		ginCtx.JSON(http.StatusBadRequest, GeneralResponse{Message: err.Error()})
+		return
+	}
+
 	// get user from database
-	userInstance, err := s.db.GetUser(ginCtx, reqBody.Name)
+	userInstance, err := s.db.GetUser(ginCtx, userName)
 	if err != nil {
 		log.Printf("[ERROR] server.Login: getting user from database: %s", err.Error())
 		if err == database.Err_NotFound {
@@ -64,6 +73,12 @@ func (s *Server) Register(ginCtx *gin.Context) {
 		return
 	}
 
+	// the name as typed becomes the display name, so users keep their capitalization
+	err = s.PutInitialProfile(ginCtx, newUserInstance.Name, reqBody.Name)
+	if err != nil {
+		log.Printf("[ERROR] server.Register: putting profile in database: %s", err.Error())
+	}
+
 	// return success
 	ginCtx.JSON(http.StatusOK, Resp_Success)
 }
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"socialite/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) BlockUser(ginCtx *gin.Context) {
+	// get user from context
+	user, exists := ginCtx.Get(Header_AuthUserKey)
+	if !exists || user == nil {
+		ginCtx.JSON(http.StatusUnauthorized, Err_AuthHeaderMissing)
+		return
+	}
+	userInstance := user.(*database.User)
+
+	// get blocked user name from path
+	blockedName, errResp := ReadUserIdParam(ginCtx)
+	if errResp != nil {
+		ginCtx.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	// cannot block self
+	if blockedName == userInstance.Name {
+		ginCtx.JSON(http.StatusBadRequest, Err_CannotBlockSelf)
+		return
+	}
+
+	// check if user exists
+	_, err := s.db.GetUser(ginCtx, blockedName)
+	if err != nil {
+		if err == database.Err_NotFound {
+			ginCtx.JSON(http.StatusNotFound, Err_UserNotFound)
+			return
+		}
+		log.Printf("[ERROR] server.BlockUser: getting user from db: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	blockInstance, err := database.NewBlock(userInstance.Name, blockedName)
+	if err != nil {
+		log.Printf("[ERROR] server.BlockUser: creating block instance: %s", err.Error())
+		ginCtx.JSON(http.StatusBadRequest, GeneralResponse{Message: err.Error()})
+		return
+	}
+
+	// put block, this also removes any friendship between the users
+	err = s.db.PutBlock(ginCtx, blockInstance)
+	if err != nil {
+		if err == database.Err_DuplicatePrimaryKey {
+			ginCtx.JSON(http.StatusConflict, Err_UserAlreadyBlocked)
+			return
+		}
+		log.Printf("[ERROR] server.BlockUser: putting block in db: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	// stop sharing presence right away instead of waiting for the cron
+	s.RemoveFriendFromCache(ginCtx, userInstance.Name, blockedName)
+	s.RemoveFriendFromCache(ginCtx, blockedName, userInstance.Name)
+
+	ginCtx.JSON(http.StatusOK, Resp_Success)
+}
+
+func (s *Server) UnblockUser(ginCtx *gin.Context) {
+	// get user from context
+	user, exists := ginCtx.Get(Header_AuthUserKey)
+	if !exists || user == nil {
+		ginCtx.JSON(http.StatusUnauthorized, Err_AuthHeaderMissing)
+		return
+	}
+	userInstance := user.(*database.User)
+
+	// get blocked user name from path
+	blockedName, errResp := ReadUserIdParam(ginCtx)
+	if errResp != nil {
+		ginCtx.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	err := s.db.DeleteBlock(ginCtx, userInstance.Name, blockedName)
+	if err != nil {
+		if err == database.Err_NotFound {
+			ginCtx.JSON(http.StatusNotFound, Err_BlockNotFound)
+			return
+		}
+		log.Printf("[ERROR] server.UnblockUser: deleting block from db: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	ginCtx.JSON(http.StatusOK, Resp_Success)
+}
+
+func (s *Server) GetBlockedUsers(ginCtx *gin.Context) {
+	// get user from context
+	user, exists := ginCtx.Get(Header_AuthUserKey)
+	if !exists || user == nil {
+		ginCtx.JSON(http.StatusUnauthorized, Err_AuthHeaderMissing)
+		return
+	}
+	userInstance := user.(*database.User)
+
+	blocks, err := s.db.GetBlocks(ginCtx, userInstance.Name)
+	if err != nil {
+		log.Printf("[ERROR] server.GetBlockedUsers: getting blocks from db: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	ginCtx.JSON(http.StatusOK, blocks)
+}
+
+// IsBlockedEitherWay reports whether any of the two users has blocked the other
+func (s *Server) IsBlockedEitherWay(ctx context.Context, user1, user2 string) (bool, error) {
+	isBlocked, err := s.db.IsBlocked(ctx, user1, user2)
+	if err != nil || isBlocked {
+		return isBlocked, err
+	}
+	return s.db.IsBlocked(ctx, user2, user1)
+}
+
+// RemoveFriendFromCache drops the friend from the user's cached friends list
+func (s *Server) RemoveFriendFromCache(ctx context.Context, userName, friendName string) {
+	friendsList, err := s.cache.GetUserFriendsList(ctx, userName)
+	if err != nil {
+		log.Printf("[ERROR] getting user's friends list from cache : %s", err.Error())
+		return
+	}
+	updatedList := make([]string, 0, len(friendsList))
+	for _, eachFriend := range friendsList {
+		if eachFriend != friendName {
+			updatedList = append(updatedList, eachFriend)
+		}
+	}
+	err = s.cache.PutUserFriendsList(ctx, userName, updatedList)
+	if err != nil {
+		log.Printf("[ERROR] putting user's friends list in cache : %s", err.Error())
+	}
+}
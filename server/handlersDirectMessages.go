@@ -0,0 +1,229 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+
+	"socialite/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	directMessagesDefaultLimit = 50
+	directMessagesMaxLimit     = 100
+	directMessageMaxLength     = 2_000
+)
+
+func (s *Server) SendDirectMessage(ginCtx *gin.Context) {
+	// get user from context
+	user, exists := ginCtx.Get(Header_AuthUserKey)
+	if !exists || user == nil {
+		ginCtx.JSON(http.StatusUnauthorized, Err_AuthHeaderMissing)
+		return
+	}
+	userInstance := user.(*database.User)
+
+	// get recipient name from path
+	recipientName, errResp := ReadUserIdParam(ginCtx)
+	if errResp != nil {
+		ginCtx.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	// read request body
+	var reqBody SendDirectMessageRequest
+	err := ginCtx.BindJSON(&reqBody)
+	if err != nil {
+		log.Printf("[ERROR] server.SendDirectMessage: reading request body: %s", err.Error())
+		ginCtx.JSON(http.StatusBadRequest, Err_ReadingRequest)
+		return
+	}
+
+	message, status, errResp := s.CreateDirectMessage(ginCtx, userInstance.Name, recipientName, reqBody.Text)
+	if errResp != nil {
+		ginCtx.JSON(status, errResp)
+		return
+	}
+
+	ginCtx.JSON(http.StatusOK, message)
+}
+
+func (s *Server) GetDirectMessages(ginCtx *gin.Context) {
+	// get user from context
+	user, exists := ginCtx.Get(Header_AuthUserKey)
+	if !exists || user == nil {
+		ginCtx.JSON(http.StatusUnauthorized, Err_AuthHeaderMissing)
+		return
+	}
+	userInstance := user.(*database.User)
+
+	// get other user's name from path
+	otherUserName, errResp := ReadUserIdParam(ginCtx)
+	if errResp != nil {
+		ginCtx.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	// read pagination from query
+	limit := directMessagesDefaultLimit
+	if limitQuery := ginCtx.Query("limit"); limitQuery != "" {
+		limitInt, err := strconv.Atoi(limitQuery)
+		if err != nil || limitInt <= 0 || limitInt > directMessagesMaxLimit {
+			ginCtx.JSON(http.StatusBadRequest, Err_PaginationInvalid)
+			return
+		}
+		limit = limitInt
+	}
+	var beforeId int32
+	if beforeQuery := ginCtx.Query("before"); beforeQuery != "" {
+		beforeInt, err := strconv.ParseInt(beforeQuery, 10, 32)
+		if err != nil || beforeInt <= 0 {
+			ginCtx.JSON(http.StatusBadRequest, Err_PaginationInvalid)
+			return
+		}
+		beforeId = int32(beforeInt)
+	}
+
+	messages, err := s.db.GetDirectMessages(ginCtx, userInstance.Name, otherUserName, beforeId, limit)
+	if err != nil {
+		log.Printf("[ERROR] server.GetDirectMessages: getting direct messages from db: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	ginCtx.JSON(http.StatusOK, messages)
+}
+
+func (s *Server) ReadDirectMessages(ginCtx *gin.Context) {
+	// get user from context
+	user, exists := ginCtx.Get(Header_AuthUserKey)
+	if !exists || user == nil {
+		ginCtx.JSON(http.StatusUnauthorized, Err_AuthHeaderMissing)
+		return
+	}
+	userInstance := user.(*database.User)
+
+	// get sender name from path
+	senderName, errResp := ReadUserIdParam(ginCtx)
+	if errResp != nil {
+		ginCtx.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	// request body is optional, empty body marks everything as read
+	var reqBody ReadDirectMessagesRequest
+	if ginCtx.Request.ContentLength > 0 {
+		err := ginCtx.BindJSON(&reqBody)
+		if err != nil {
+			log.Printf("[ERROR] server.ReadDirectMessages: reading request body: %s", err.Error())
+			ginCtx.JSON(http.StatusBadRequest, Err_ReadingRequest)
+			return
+		}
+	}
+
+	errResp = s.MarkDirectMessagesRead(ginCtx, userInstance.Name, senderName, reqBody.UpToId)
+	if errResp != nil {
+		ginCtx.JSON(http.StatusInternalServerError, errResp)
+		return
+	}
+
+	ginCtx.JSON(http.StatusOK, Resp_Success)
+}
+
+func (s *Server) GetUnreadDirectMessages(ginCtx *gin.Context) {
+	// get user from context
+	user, exists := ginCtx.Get(Header_AuthUserKey)
+	if !exists || user == nil {
+		ginCtx.JSON(http.StatusUnauthorized, Err_AuthHeaderMissing)
+		return
+	}
+	userInstance := user.(*database.User)
+
+	unreadCounts, err := s.db.GetUnreadDirectMessageCounts(ginCtx, userInstance.Name)
+	if err != nil {
+		log.Printf("[ERROR] server.GetUnreadDirectMessages: getting unread counts from db: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	resp := UnreadDirectMessagesResponse{Senders: unreadCounts}
+	for _, count := range unreadCounts {
+		resp.Total += count
+	}
+
+	ginCtx.JSON(http.StatusOK, resp)
+}
+
+// CreateDirectMessage stores a message between confirmed friends and delivers it to the recipient,
+// the returned http status is only meaningful along with an error response
+func (s *Server) CreateDirectMessage(ctx context.Context, senderName, recipientName, text string) (*database.DirectMessage, int, *GeneralResponse) {
+	if senderName == recipientName {
+		return nil, http.StatusBadRequest, &Err_CannotMessageSelf
+	}
+	if utf8.RuneCountInString(text) > directMessageMaxLength {
+		return nil, http.StatusBadRequest, &Err_MessageTooLong
+	}
+
+	// blocked users cannot message each other, even if a friendship is left over
+	isBlocked, err := s.IsBlockedEitherWay(ctx, senderName, recipientName)
+	if err != nil {
+		log.Printf("[ERROR] server.CreateDirectMessage: checking block: %s", err.Error())
+		return nil, http.StatusInternalServerError, &Err_SomethingWrong
+	}
+	if isBlocked {
+		return nil, http.StatusForbidden, &Err_NotFriends
+	}
+
+	// only friends can message each other
+	friendship, err := s.db.GetFriendship(ctx, senderName, recipientName)
+	if err != nil && err != database.Err_NotFound {
+		log.Printf("[ERROR] server.CreateDirectMessage: getting friendship: %s", err.Error())
+		return nil, http.StatusInternalServerError, &Err_SomethingWrong
+	}
+	if err == database.Err_NotFound || friendship.Status != database.Friendship_Status_Confirmed {
+		return nil, http.StatusForbidden, &Err_NotFriends
+	}
+
+	message, err := database.NewDirectMessage(senderName, recipientName, text)
+	if err != nil {
+		return nil, http.StatusBadRequest, &GeneralResponse{Message: err.Error()}
+	}
+
+	err = s.db.PutDirectMessage(ctx, message)
+	if err != nil {
+		log.Printf("[ERROR] server.CreateDirectMessage: putting direct message in db: %s", err.Error())
+		return nil, http.StatusInternalServerError, &Err_SomethingWrong
+	}
+
+	go s.SendToUser(recipientName, &WebsocketStatusOutgoingMessage{
+		MsgType:       MessageType_DirectMessage,
+		UserName:      senderName,
+		DirectMessage: message,
+	})
+
+	return message, http.StatusOK, nil
+}
+
+// MarkDirectMessagesRead marks the messages received from sender as read and sends a read receipt to the sender
+func (s *Server) MarkDirectMessagesRead(ctx context.Context, readerName, senderName string, upToId int32) *GeneralResponse {
+	updated, err := s.db.MarkDirectMessagesRead(ctx, readerName, senderName, upToId)
+	if err != nil {
+		log.Printf("[ERROR] server.MarkDirectMessagesRead: marking direct messages read in db: %s", err.Error())
+		return &Err_SomethingWrong
+	}
+	if updated == 0 {
+		return nil
+	}
+
+	go s.SendToUser(senderName, &WebsocketStatusOutgoingMessage{
+		MsgType:    MessageType_DirectMessageRead,
+		UserName:   readerName,
+		ReadUpToId: upToId,
+	})
+
+	return nil
+}
@@ -0,0 +1,268 @@
+package server
+
+import (
+	"log"
+	"net/http"
+
+	"socialite/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) GetFriendGroups(ginCtx *gin.Context) {
+	// get user from context
+	user, exists := ginCtx.Get(Header_AuthUserKey)
+	if !exists || user == nil {
+		ginCtx.JSON(http.StatusUnauthorized, Err_AuthHeaderMissing)
+		return
+	}
+	userInstance := user.(*database.User)
+
+	groups, err := s.db.GetFriendGroups(ginCtx, userInstance.Name)
+	if err != nil {
+		log.Printf("[ERROR] server.GetFriendGroups: getting friend groups: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	ginCtx.JSON(http.StatusOK, groups)
+}
+
+func (s *Server) CreateFriendGroup(ginCtx *gin.Context) {
+	// get user from context
+	user, exists := ginCtx.Get(Header_AuthUserKey)
+	if !exists || user == nil {
+		ginCtx.JSON(http.StatusUnauthorized, Err_AuthHeaderMissing)
+		return
+	}
+	userInstance := user.(*database.User)
+
+	// read request body
+	var reqBody FriendGroupRequest
+	err := ginCtx.BindJSON(&reqBody)
+	if err != nil {
+		log.Printf("[ERROR] server.CreateFriendGroup: reading request body: %s", err.Error())
+		ginCtx.JSON(http.StatusBadRequest, Err_ReadingRequest)
+		return
+	}
+
+	group, err := database.NewFriendGroup(userInstance.Name, reqBody.Name)
+	if err != nil {
+		ginCtx.JSON(http.StatusBadRequest, GeneralResponse{Message: err.Error()})
+		return
+	}
+
+	// check the user's group limit
+	groups, err := s.db.GetFriendGroups(ginCtx, userInstance.Name)
+	if err != nil {
+		log.Printf("[ERROR] server.CreateFriendGroup: getting friend groups: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+	if len(groups) >= database.FriendGroupsMaxCount {
+		ginCtx.JSON(http.StatusBadRequest, Err_FriendGroupLimitReached)
+		return
+	}
+
+	err = s.db.PutFriendGroup(ginCtx, group)
+	if err != nil {
+		if err == database.Err_DuplicatePrimaryKey {
+			ginCtx.JSON(http.StatusConflict, Err_FriendGroupNameTaken)
+			return
+		}
+		log.Printf("[ERROR] server.CreateFriendGroup: putting friend group: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	ginCtx.JSON(http.StatusCreated, group)
+}
+
+func (s *Server) RenameFriendGroup(ginCtx *gin.Context) {
+	// get user from context
+	user, exists := ginCtx.Get(Header_AuthUserKey)
+	if !exists || user == nil {
+		ginCtx.JSON(http.StatusUnauthorized, Err_AuthHeaderMissing)
+		return
+	}
+	userInstance := user.(*database.User)
+
+	// get group id from query
+	groupId, errResp := ReadGroupIdParam(ginCtx)
+	if errResp != nil {
+		ginCtx.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	// read request body
+	var reqBody FriendGroupRequest
+	err := ginCtx.BindJSON(&reqBody)
+	if err != nil {
+		log.Printf("[ERROR] server.RenameFriendGroup: reading request body: %s", err.Error())
+		ginCtx.JSON(http.StatusBadRequest, Err_ReadingRequest)
+		return
+	}
+
+	group, err := s.db.GetFriendGroup(ginCtx, userInstance.Name, groupId)
+	if err != nil {
+		if err == database.Err_NotFound {
+			ginCtx.JSON(http.StatusNotFound, Err_FriendGroupNotFound)
+			return
+		}
+		log.Printf("[ERROR] server.RenameFriendGroup: getting friend group: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+	group.Name = reqBody.Name
+	err = group.Validate()
+	if err != nil {
+		ginCtx.JSON(http.StatusBadRequest, GeneralResponse{Message: err.Error()})
+		return
+	}
+
+	err = s.db.UpdateFriendGroup(ginCtx, group)
+	if err != nil {
+		if err == database.Err_NotFound {
+			ginCtx.JSON(http.StatusNotFound, Err_FriendGroupNotFound)
+			return
+		}
+		if err == database.Err_DuplicatePrimaryKey {
+			ginCtx.JSON(http.StatusConflict, Err_FriendGroupNameTaken)
+			return
+		}
+		log.Printf("[ERROR] server.RenameFriendGroup: updating friend group: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	ginCtx.JSON(http.StatusOK, group)
+}
+
+func (s *Server) DeleteFriendGroup(ginCtx *gin.Context) {
+	// get user from context
+	user, exists := ginCtx.Get(Header_AuthUserKey)
+	if !exists || user == nil {
+		ginCtx.JSON(http.StatusUnauthorized, Err_AuthHeaderMissing)
+		return
+	}
+	userInstance := user.(*database.User)
+
+	// get group id from query
+	groupId, errResp := ReadGroupIdParam(ginCtx)
+	if errResp != nil {
+		ginCtx.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	err := s.db.DeleteFriendGroup(ginCtx, userInstance.Name, groupId)
+	if err != nil {
+		if err == database.Err_NotFound {
+			ginCtx.JSON(http.StatusNotFound, Err_FriendGroupNotFound)
+			return
+		}
+		log.Printf("[ERROR] server.DeleteFriendGroup: deleting friend group: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	// a presence scope on the group falls back to all friends
+	s.RefreshPresenceScope(ginCtx, userInstance.Name)
+
+	ginCtx.JSON(http.StatusOK, Resp_Success)
+}
+
+func (s *Server) AddFriendToGroup(ginCtx *gin.Context) {
+	// get user from context
+	user, exists := ginCtx.Get(Header_AuthUserKey)
+	if !exists || user == nil {
+		ginCtx.JSON(http.StatusUnauthorized, Err_AuthHeaderMissing)
+		return
+	}
+	userInstance := user.(*database.User)
+
+	group, friendship, status, errResp := s.readFriendGroupMember(ginCtx, userInstance.Name)
+	if errResp != nil {
+		ginCtx.JSON(status, errResp)
+		return
+	}
+
+	err := s.db.PutFriendGroupMember(ginCtx, group.Id, friendship.Id)
+	if err != nil {
+		if err == database.Err_DuplicatePrimaryKey {
+			ginCtx.JSON(http.StatusConflict, Err_FriendAlreadyInGroup)
+			return
+		}
+		if err == database.Err_NotFound {
+			ginCtx.JSON(http.StatusNotFound, Err_FriendGroupNotFound)
+			return
+		}
+		log.Printf("[ERROR] server.AddFriendToGroup: putting friend group member: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	// a presence scope on the group follows the change
+	s.RefreshPresenceScope(ginCtx, userInstance.Name)
+
+	ginCtx.JSON(http.StatusOK, Resp_Success)
+}
+
+func (s *Server) RemoveFriendFromGroup(ginCtx *gin.Context) {
+	// get user from context
+	user, exists := ginCtx.Get(Header_AuthUserKey)
+	if !exists || user == nil {
+		ginCtx.JSON(http.StatusUnauthorized, Err_AuthHeaderMissing)
+		return
+	}
+	userInstance := user.(*database.User)
+
+	group, friendship, status, errResp := s.readFriendGroupMember(ginCtx, userInstance.Name)
+	if errResp != nil {
+		ginCtx.JSON(status, errResp)
+		return
+	}
+
+	err := s.db.DeleteFriendGroupMember(ginCtx, group.Id, friendship.Id)
+	if err != nil {
+		if err == database.Err_NotFound {
+			ginCtx.JSON(http.StatusNotFound, Err_FriendNotInGroup)
+			return
+		}
+		log.Printf("[ERROR] server.RemoveFriendFromGroup: deleting friend group member: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	// a presence scope on the group follows the change
+	s.RefreshPresenceScope(ginCtx, userInstance.Name)
+
+	ginCtx.JSON(http.StatusOK, Resp_Success)
+}
+
+// readFriendGroupMember reads the group and friend path params, the group must belong to the user
+// and the friend must be a confirmed friend
+func (s *Server) readFriendGroupMember(ginCtx *gin.Context, userName string) (*database.FriendGroup, *database.Friendship, int, *GeneralResponse) {
+	groupId, errResp := ReadGroupIdParam(ginCtx)
+	if errResp != nil {
+		return nil, nil, http.StatusBadRequest, errResp
+	}
+	friendName, errResp := ReadUserIdParam(ginCtx)
+	if errResp != nil {
+		return nil, nil, http.StatusBadRequest, errResp
+	}
+
+	group, err := s.db.GetFriendGroup(ginCtx, userName, groupId)
+	if err != nil {
+		if err == database.Err_NotFound {
+			return nil, nil, http.StatusNotFound, &Err_FriendGroupNotFound
+		}
+		log.Printf("[ERROR] server.readFriendGroupMember: getting friend group: %s", err.Error())
+		return nil, nil, http.StatusInternalServerError, &Err_SomethingWrong
+	}
+
+	friendship, status, errResp := s.GetConfirmedFriendship(ginCtx, userName, friendName)
+	if errResp != nil {
+		return nil, nil, status, errResp
+	}
+	return group, friendship, http.StatusOK, nil
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"strconv"
@@ -11,6 +12,8 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+const friendSuggestionsLimit = 20
+
 func (s *Server) GetFriends(ginCtx *gin.Context) {
 	// get user from context
 	user, exists := ginCtx.Get(Header_AuthUserKey)
@@ -20,8 +23,38 @@ func (s *Server) GetFriends(ginCtx *gin.Context) {
 	}
 	userInstance := user.(*database.User)
 
+	// read pagination, sorting and filters
+	opts, err := ReadListOptions(ginCtx, database.ListSort_Name, database.ListSort_CreatedAt, database.ListSort_OnlineFirst)
+	if err != nil {
+		ginCtx.JSON(http.StatusBadRequest, GeneralResponse{Message: err.Error()})
+		return
+	}
+	if favoriteQuery := ginCtx.Query("favorite"); favoriteQuery != "" {
+		opts.FavoritesOnly, err = strconv.ParseBool(favoriteQuery)
+		if err != nil {
+			ginCtx.JSON(http.StatusBadRequest, GeneralResponse{Message: "favorite is invalid"})
+			return
+		}
+	}
+	if groupQuery := ginCtx.Query("group_id"); groupQuery != "" {
+		groupId, err := strconv.ParseInt(groupQuery, 10, 32)
+		if err != nil || groupId <= 0 {
+			ginCtx.JSON(http.StatusBadRequest, Err_GroupIdInvalid)
+			return
+		}
+		opts.GroupId = int32(groupId)
+	}
+
+	// presence lives in the cache, pass the online friends along
+	opts.OnlineUsers, err = s.GetOnlineFriends(ginCtx, userInstance.Name)
+	if err != nil {
+		log.Printf("[ERROR] server.GetFriends: getting online friends: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
 	// get friends from database
-	friends, err := s.db.GetUserFriends(ginCtx, userInstance.Name)
+	friends, err := s.db.GetUserFriends(ginCtx, userInstance.Name, opts)
 	if err != nil {
 		log.Printf("[ERROR] server.GetFriends: getting friends: %s", err.Error())
 		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
@@ -31,6 +64,87 @@ func (s *Server) GetFriends(ginCtx *gin.Context) {
 	ginCtx.JSON(http.StatusOK, friends)
 }
 
+func (s *Server) UpdateFriend(ginCtx *gin.Context) {
+	// get user from context
+	user, exists := ginCtx.Get(Header_AuthUserKey)
+	if !exists || user == nil {
+		ginCtx.JSON(http.StatusUnauthorized, Err_AuthHeaderMissing)
+		return
+	}
+	userInstance := user.(*database.User)
+
+	// get friend name from query
+	friendName, errResp := ReadUserIdParam(ginCtx)
+	if errResp != nil {
+		ginCtx.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	// read request body
+	var reqBody UpdateFriendRequest
+	err := ginCtx.BindJSON(&reqBody)
+	if err != nil {
+		log.Printf("[ERROR] server.UpdateFriend: reading request body: %s", err.Error())
+		ginCtx.JSON(http.StatusBadRequest, Err_ReadingRequest)
+		return
+	}
+
+	friendship, status, errResp := s.GetConfirmedFriendship(ginCtx, userInstance.Name, friendName)
+	if errResp != nil {
+		ginCtx.JSON(status, errResp)
+		return
+	}
+
+	// get current metadata, friends without any start from empty metadata
+	metadata, err := s.db.GetFriendMetadata(ginCtx, userInstance.Name, friendship.Id)
+	if err != nil {
+		if err != database.Err_NotFound {
+			log.Printf("[ERROR] server.UpdateFriend: getting friend metadata: %s", err.Error())
+			ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+			return
+		}
+		metadata, err = database.NewFriendMetadata(friendship.Id, userInstance.Name)
+		if err != nil {
+			log.Printf("[ERROR] server.UpdateFriend: creating friend metadata instance: %s", err.Error())
+			ginCtx.JSON(http.StatusBadRequest, GeneralResponse{Message: err.Error()})
+			return
+		}
+	}
+
+	// only change the fields present in the request
+	if reqBody.Nickname != nil {
+		metadata.Nickname = *reqBody.Nickname
+	}
+	favoriteChanged := reqBody.Favorite != nil && *reqBody.Favorite != metadata.Favorite
+	if reqBody.Favorite != nil {
+		metadata.Favorite = *reqBody.Favorite
+	}
+	err = metadata.Validate()
+	if err != nil {
+		ginCtx.JSON(http.StatusBadRequest, GeneralResponse{Message: err.Error()})
+		return
+	}
+	metadata.UpdatedAt = time.Now()
+
+	err = s.db.PutFriendMetadata(ginCtx, metadata)
+	if err != nil {
+		if err == database.Err_NotFound {
+			ginCtx.JSON(http.StatusNotFound, Err_FriendshipNotFound)
+			return
+		}
+		log.Printf("[ERROR] server.UpdateFriend: putting friend metadata: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	// a favorites presence scope follows the change
+	if favoriteChanged {
+		s.RefreshPresenceScope(ginCtx, userInstance.Name)
+	}
+
+	ginCtx.JSON(http.StatusOK, metadata)
+}
+
 func (s *Server) RemoveFriend(ginCtx *gin.Context) {
 	// get user from context
 	user, exists := ginCtx.Get(Header_AuthUserKey)
@@ -41,9 +155,9 @@ func (s *Server) RemoveFriend(ginCtx *gin.Context) {
 	userInstance := user.(*database.User)
 
 	// get friend name from query
-	friendName := ginCtx.Param("user_id")
-	if friendName == "" {
-		ginCtx.JSON(http.StatusBadRequest, Err_UserIdMissing)
+	friendName, errResp := ReadUserIdParam(ginCtx)
+	if errResp != nil {
+		ginCtx.JSON(http.StatusBadRequest, errResp)
 		return
 	}
 
@@ -60,7 +174,7 @@ func (s *Server) RemoveFriend(ginCtx *gin.Context) {
 	}
 
 	// remove friendship
-	err = s.db.DeleteFriendship(ginCtx, friendship.Id)
+	err = s.db.DeleteFriendship(database.WithActor(ginCtx, userInstance.Name), friendship.Id)
 	if err != nil {
 		if err == database.Err_NotFound {
 			ginCtx.JSON(http.StatusNotFound, Err_FriendshipNotFound)
@@ -84,9 +198,9 @@ func (s *Server) SendFriendRequest(ginCtx *gin.Context) {
 	userInstance := user.(*database.User)
 
 	// get friend name from query
-	friendName := ginCtx.Param("user_id")
-	if friendName == "" {
-		ginCtx.JSON(http.StatusBadRequest, Err_UserIdMissing)
+	friendName, errResp := ReadUserIdParam(ginCtx)
+	if errResp != nil {
+		ginCtx.JSON(http.StatusBadRequest, errResp)
 		return
 	}
 
@@ -96,6 +210,42 @@ func (s *Server) SendFriendRequest(ginCtx *gin.Context) {
 		return
 	}
 
+	// deleted users keep their row until purged, they cannot be sent requests meanwhile
+	_, err := s.db.GetUser(ginCtx, friendName)
+	if err != nil {
+		if err == database.Err_NotFound {
+			ginCtx.JSON(http.StatusNotFound, Err_UserNotFound)
+			return
+		}
+		log.Printf("[ERROR] server.SendFriendRequest: getting user: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	// cannot send request to a user blocked by self
+	isBlocked, err := s.db.IsBlocked(ginCtx, userInstance.Name, friendName)
+	if err != nil {
+		log.Printf("[ERROR] server.SendFriendRequest: checking block: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+	if isBlocked {
+		ginCtx.JSON(http.StatusForbidden, Err_UserBlocked)
+		return
+	}
+
+	// requests from blocked users are dropped without telling them
+	isBlocked, err = s.db.IsBlocked(ginCtx, friendName, userInstance.Name)
+	if err != nil {
+		log.Printf("[ERROR] server.SendFriendRequest: checking block: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+	if isBlocked {
+		ginCtx.JSON(http.StatusOK, Resp_Success)
+		return
+	}
+
 	// check if friendship already exists
 	friendship, err := s.db.GetFriendship(ginCtx, userInstance.Name, friendName)
 	if err == nil {
@@ -131,6 +281,15 @@ func (s *Server) SendFriendRequest(ginCtx *gin.Context) {
 		return
 	}
 
+	// let the user know
+	notification, err := database.NewNotification(friendName, database.Notification_Type_FriendRequest, userInstance.Name)
+	if err != nil {
+		log.Printf("[ERROR] server.SendFriendRequest: creating notification instance: %s", err.Error())
+	} else {
+		notification.FriendshipId = friendshipInstance.Id
+		s.Notify(ginCtx, notification)
+	}
+
 	ginCtx.JSON(http.StatusOK, Resp_Success)
 }
 
@@ -191,6 +350,15 @@ func (s *Server) AcceptFriendRequest(ginCtx *gin.Context) {
 		return
 	}
 
+	// let the user know
+	notification, err := database.NewNotification(friendshipInstance.User1, database.Notification_Type_FriendRequestAccepted, userInstance.Name)
+	if err != nil {
+		log.Printf("[ERROR] server.AcceptFriendRequest: creating notification instance: %s", err.Error())
+	} else {
+		notification.FriendshipId = friendshipInstance.Id
+		s.Notify(ginCtx, notification)
+	}
+
 	ginCtx.JSON(http.StatusOK, Resp_Success)
 }
 
@@ -259,8 +427,15 @@ func (s *Server) GetFriendRequests(ginCtx *gin.Context) {
 	}
 	userInstance := user.(*database.User)
 
+	// read pagination, sorting and filters
+	opts, err := ReadListOptions(ginCtx, database.ListSort_CreatedAt, database.ListSort_Name)
+	if err != nil {
+		ginCtx.JSON(http.StatusBadRequest, GeneralResponse{Message: err.Error()})
+		return
+	}
+
 	// get friend requests from database
-	friendRequests, err := s.db.GetPendingFriendRequests(ginCtx, userInstance.Name)
+	friendRequests, err := s.db.GetPendingFriendRequests(ginCtx, userInstance.Name, opts)
 	if err != nil {
 		log.Printf("[ERROR] server.GetFriendRequests: getting friend requests: %s", err.Error())
 		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
@@ -269,3 +444,156 @@ func (s *Server) GetFriendRequests(ginCtx *gin.Context) {
 
 	ginCtx.JSON(http.StatusOK, friendRequests)
 }
+
+func (s *Server) GetSentFriendRequests(ginCtx *gin.Context) {
+	// get user from context
+	user, exists := ginCtx.Get(Header_AuthUserKey)
+	if !exists || user == nil {
+		ginCtx.JSON(http.StatusUnauthorized, Err_AuthHeaderMissing)
+		return
+	}
+	userInstance := user.(*database.User)
+
+	// read pagination, sorting and filters
+	opts, err := ReadListOptions(ginCtx, database.ListSort_CreatedAt, database.ListSort_Name)
+	if err != nil {
+		ginCtx.JSON(http.StatusBadRequest, GeneralResponse{Message: err.Error()})
+		return
+	}
+
+	// get sent friend requests from database
+	friendRequests, err := s.db.GetSentFriendRequests(ginCtx, userInstance.Name, opts)
+	if err != nil {
+		log.Printf("[ERROR] server.GetSentFriendRequests: getting sent friend requests: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	ginCtx.JSON(http.StatusOK, friendRequests)
+}
+
+func (s *Server) CancelFriendRequest(ginCtx *gin.Context) {
+	// get user from context
+	user, exists := ginCtx.Get(Header_AuthUserKey)
+	if !exists || user == nil {
+		ginCtx.JSON(http.StatusUnauthorized, Err_AuthHeaderMissing)
+		return
+	}
+	userInstance := user.(*database.User)
+
+	// get request id from path
+	requestId := ginCtx.Param("request_id")
+	if requestId == "" {
+		ginCtx.JSON(http.StatusBadRequest, Err_RequestIdMissing)
+		return
+	}
+
+	requestIdInt, err := strconv.Atoi(requestId)
+	if err != nil {
+		ginCtx.JSON(http.StatusBadRequest, Err_RequestIdInvalid)
+		return
+	}
+
+	friendshipInstance, err := s.db.GetFriendshipById(ginCtx, int32(requestIdInt))
+	if err != nil {
+		if err == database.Err_NotFound {
+			ginCtx.JSON(http.StatusNotFound, Err_FriendshipRequestNotFound)
+			return
+		}
+		log.Printf("[ERROR] server.CancelFriendRequest: getting friendship request: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	// only the sender can cancel the request
+	if friendshipInstance.User1 != userInstance.Name {
+		ginCtx.JSON(http.StatusNotFound, Err_FriendshipRequestNotFound)
+		return
+	}
+	if friendshipInstance.Status == database.Friendship_Status_Confirmed {
+		ginCtx.JSON(http.StatusConflict, Err_FriendshipRequestAlreadyConfirmed)
+		return
+	}
+
+	err = s.db.DeleteFriendship(ginCtx, friendshipInstance.Id)
+	if err != nil {
+		if err == database.Err_NotFound {
+			ginCtx.JSON(http.StatusNotFound, Err_FriendshipRequestNotFound)
+			return
+		}
+		log.Printf("[ERROR] server.CancelFriendRequest: deleting friendship request: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	ginCtx.JSON(http.StatusOK, Resp_Success)
+}
+
+func (s *Server) GetFriendSuggestions(ginCtx *gin.Context) {
+	// get user from context
+	user, exists := ginCtx.Get(Header_AuthUserKey)
+	if !exists || user == nil {
+		ginCtx.JSON(http.StatusUnauthorized, Err_AuthHeaderMissing)
+		return
+	}
+	userInstance := user.(*database.User)
+
+	// suggestions are expensive to rank, serve them from cache while they are fresh
+	suggestions, err := s.cache.GetFriendSuggestions(ginCtx, userInstance.Name)
+	if err != nil {
+		log.Printf("[ERROR] server.GetFriendSuggestions: getting friend suggestions from cache: %s", err.Error())
+	}
+	if suggestions != nil {
+		ginCtx.JSON(http.StatusOK, suggestions)
+		return
+	}
+
+	suggestions, err = s.db.GetFriendSuggestions(ginCtx, userInstance.Name, friendSuggestionsLimit)
+	if err != nil {
+		log.Printf("[ERROR] server.GetFriendSuggestions: getting friend suggestions from db: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	err = s.cache.PutFriendSuggestions(ginCtx, userInstance.Name, suggestions)
+	if err != nil {
+		log.Printf("[ERROR] server.GetFriendSuggestions: putting friend suggestions in cache: %s", err.Error())
+	}
+
+	ginCtx.JSON(http.StatusOK, suggestions)
+}
+
+// GetConfirmedFriendship returns the friendship of the two users, pending requests are not found
+func (s *Server) GetConfirmedFriendship(ctx context.Context, userName, friendName string) (*database.Friendship, int, *GeneralResponse) {
+	friendship, err := s.db.GetFriendship(ctx, userName, friendName)
+	if err != nil {
+		if err == database.Err_NotFound {
+			return nil, http.StatusNotFound, &Err_FriendshipNotFound
+		}
+		log.Printf("[ERROR] server.GetConfirmedFriendship: getting friendship: %s", err.Error())
+		return nil, http.StatusInternalServerError, &Err_SomethingWrong
+	}
+	if friendship.Status != database.Friendship_Status_Confirmed {
+		return nil, http.StatusNotFound, &Err_FriendshipNotFound
+	}
+	return friendship, http.StatusOK, nil
+}
+
+// GetOnlineFriends returns the friends of the user who are currently online
+func (s *Server) GetOnlineFriends(ctx context.Context, userName string) ([]string, error) {
+	friendsList, err := s.cache.GetUserFriendsList(ctx, userName)
+	if err != nil {
+		return nil, err
+	}
+	onlineFriends := make([]string, 0, len(friendsList))
+	for _, eachFriend := range friendsList {
+		isOnline, err := s.cache.IsUserOnline(ctx, eachFriend)
+		if err != nil {
+			return nil, err
+		}
+		if isOnline {
+			onlineFriends = append(onlineFriends, eachFriend)
+		}
+	}
+	return onlineFriends, nil
+}
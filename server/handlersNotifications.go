@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+
+	"socialite/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) GetNotifications(ginCtx *gin.Context) {
+	// get user from context
+	user, exists := ginCtx.Get(Header_AuthUserKey)
+	if !exists || user == nil {
+		ginCtx.JSON(http.StatusUnauthorized, Err_AuthHeaderMissing)
+		return
+	}
+	userInstance := user.(*database.User)
+
+	// read pagination and filters
+	opts, err := ReadListOptions(ginCtx, database.ListSort_CreatedAt)
+	if err != nil {
+		ginCtx.JSON(http.StatusBadRequest, GeneralResponse{Message: err.Error()})
+		return
+	}
+	if unreadQuery := ginCtx.Query("unread"); unreadQuery != "" {
+		opts.UnreadOnly, err = strconv.ParseBool(unreadQuery)
+		if err != nil {
+			ginCtx.JSON(http.StatusBadRequest, GeneralResponse{Message: "unread is invalid"})
+			return
+		}
+	}
+
+	notifications, err := s.db.GetNotifications(ginCtx, userInstance.Name, opts)
+	if err != nil {
+		log.Printf("[ERROR] server.GetNotifications: getting notifications: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	unreadCount, err := s.db.GetUnreadNotificationCount(ginCtx, userInstance.Name)
+	if err != nil {
+		log.Printf("[ERROR] server.GetNotifications: getting unread notification count: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	ginCtx.JSON(http.StatusOK, NotificationsResponse{
+		Page:        notifications,
+		UnreadCount: unreadCount,
+	})
+}
+
+func (s *Server) MarkNotificationRead(ginCtx *gin.Context) {
+	// get user from context
+	user, exists := ginCtx.Get(Header_AuthUserKey)
+	if !exists || user == nil {
+		ginCtx.JSON(http.StatusUnauthorized, Err_AuthHeaderMissing)
+		return
+	}
+	userInstance := user.(*database.User)
+
+	// get notification id from path
+	notificationId, errResp := ReadNotificationIdParam(ginCtx)
+	if errResp != nil {
+		ginCtx.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	err := s.db.MarkNotificationRead(ginCtx, userInstance.Name, notificationId)
+	if err != nil {
+		if err == database.Err_NotFound {
+			ginCtx.JSON(http.StatusNotFound, Err_NotificationNotFound)
+			return
+		}
+		log.Printf("[ERROR] server.MarkNotificationRead: marking notification read: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	ginCtx.JSON(http.StatusOK, Resp_Success)
+}
+
+func (s *Server) MarkAllNotificationsRead(ginCtx *gin.Context) {
+	// get user from context
+	user, exists := ginCtx.Get(Header_AuthUserKey)
+	if !exists || user == nil {
+		ginCtx.JSON(http.StatusUnauthorized, Err_AuthHeaderMissing)
+		return
+	}
+	userInstance := user.(*database.User)
+
+	_, err := s.db.MarkAllNotificationsRead(ginCtx, userInstance.Name)
+	if err != nil {
+		log.Printf("[ERROR] server.MarkAllNotificationsRead: marking notifications read: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	ginCtx.JSON(http.StatusOK, Resp_Success)
+}
+
+// Notify stores the notification in the user's inbox and pushes it to their status websocket, if connected.
+// The action it is about already happened, so failures are only logged
+func (s *Server) Notify(ctx context.Context, notification *database.Notification) {
+	err := s.db.PutNotification(ctx, notification)
+	if err != nil {
+		log.Printf("[ERROR] server.Notify: putting notification: %s", err.Error())
+		return
+	}
+
+	go s.SendToUser(notification.UserName, &WebsocketStatusOutgoingMessage{
+		MsgType:      MessageType_Notification,
+		UserName:     notification.Actor,
+		Notification: notification,
+	})
+}
@@ -3,8 +3,10 @@ package server
 import (
 	"log"
 	"net/http"
+	"time"
 
 	"socialite/database"
+	"socialite/validation"
 
 	"github.com/gin-gonic/gin"
 )
@@ -19,9 +21,9 @@ func (s *Server) InviteUserToParty(ginCtx *gin.Context) {
 	userInstance := user.(*database.User)
 
 	// get party name from path
-	partyName := ginCtx.Param("party_id")
-	if partyName == "" {
-		ginCtx.JSON(http.StatusBadRequest, Err_ReadingRequest)
+	partyName, errResp := ReadPartyIdParam(ginCtx)
+	if errResp != nil {
+		ginCtx.JSON(http.StatusBadRequest, errResp)
 		return
 	}
 
@@ -33,6 +35,11 @@ func (s *Server) InviteUserToParty(ginCtx *gin.Context) {
 		ginCtx.JSON(http.StatusBadRequest, Err_ReadingRequest)
 		return
 	}
+	reqBody.UserName, err = validation.Name(reqBody.UserName)
+	if err != nil {
+		ginCtx.JSON(http.StatusBadRequest, Err_UserIdInvalid)
+		return
+	}
 
 	party, err := s.db.GetParty(ginCtx, partyName)
 	if err != nil {
@@ -56,6 +63,42 @@ func (s *Server) InviteUserToParty(ginCtx *gin.Context) {
 		return
 	}
 
+	// deleted users keep their row until purged, they cannot be invited meanwhile
+	_, err = s.db.GetUser(ginCtx, reqBody.UserName)
+	if err != nil {
+		if err == database.Err_NotFound {
+			ginCtx.JSON(http.StatusNotFound, Err_UserNotFound)
+			return
+		}
+		log.Printf("[ERROR] server.InviteUserToParty: getting user: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	// cannot invite a user blocked by self
+	isBlocked, err := s.db.IsBlocked(ginCtx, userInstance.Name, reqBody.UserName)
+	if err != nil {
+		log.Printf("[ERROR] server.InviteUserToParty: checking block: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+	if isBlocked {
+		ginCtx.JSON(http.StatusForbidden, Err_UserBlocked)
+		return
+	}
+
+	// invites from blocked users are dropped without telling them
+	isBlocked, err = s.db.IsBlocked(ginCtx, reqBody.UserName, userInstance.Name)
+	if err != nil {
+		log.Printf("[ERROR] server.InviteUserToParty: checking block: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+	if isBlocked {
+		ginCtx.JSON(http.StatusOK, Resp_Success)
+		return
+	}
+
 	// check if user is already in party
 
 	partyMembership, err := database.NewPartyMembership(party.Name, reqBody.UserName)
@@ -65,7 +108,7 @@ func (s *Server) InviteUserToParty(ginCtx *gin.Context) {
 		return
 	}
 
-	err = s.db.PutPartyMembership(ginCtx, partyMembership)
+	err = s.db.PutPartyMembership(database.WithActor(ginCtx, userInstance.Name), partyMembership)
 	if err != nil {
 		if err == database.Err_DuplicatePrimaryKey {
 			ginCtx.JSON(http.StatusConflict, Err_UserAlreadyInParty)
@@ -76,6 +119,15 @@ func (s *Server) InviteUserToParty(ginCtx *gin.Context) {
 		return
 	}
 
+	// let the user know
+	notification, err := database.NewNotification(reqBody.UserName, database.Notification_Type_PartyInvite, userInstance.Name)
+	if err != nil {
+		log.Printf("[ERROR] server.InviteUserToParty: creating notification instance: %s", err.Error())
+	} else {
+		notification.PartyName = party.Name
+		s.Notify(ginCtx, notification)
+	}
+
 	ginCtx.JSON(http.StatusOK, Resp_Success)
 }
 
@@ -89,9 +141,9 @@ func (s *Server) JoinParty(ginCtx *gin.Context) {
 	userInstance := user.(*database.User)
 
 	// get party name from path
-	partyName := ginCtx.Param("party_id")
-	if partyName == "" {
-		ginCtx.JSON(http.StatusBadRequest, Err_ReadingRequest)
+	partyName, errResp := ReadPartyIdParam(ginCtx)
+	if errResp != nil {
+		ginCtx.JSON(http.StatusBadRequest, errResp)
 		return
 	}
 
@@ -112,6 +164,12 @@ func (s *Server) JoinParty(ginCtx *gin.Context) {
 		return
 	}
 
+	// expired invitations are purged by a cron, reject them until then
+	if time.Since(partyMembership.CreatedAt) > s.partyInviteExpiry {
+		ginCtx.JSON(http.StatusNotFound, Err_PartyInvitationNotFound)
+		return
+	}
+
 	partyMembership.Status = database.PartyMembership_Status_Active
 
 	err = s.db.UpdatePartyMembership(ginCtx, partyMembership)
@@ -121,6 +179,21 @@ func (s *Server) JoinParty(ginCtx *gin.Context) {
 		return
 	}
 
+	// let the party creator know
+	party, err := s.db.GetParty(ginCtx, partyName)
+	if err != nil {
+		log.Printf("[ERROR] server.JoinParty: getting party from db: %s", err.Error())
+		ginCtx.JSON(http.StatusOK, Resp_Success)
+		return
+	}
+	notification, err := database.NewNotification(party.Creator, database.Notification_Type_PartyInviteAccepted, userInstance.Name)
+	if err != nil {
+		log.Printf("[ERROR] server.JoinParty: creating notification instance: %s", err.Error())
+	} else {
+		notification.PartyName = party.Name
+		s.Notify(ginCtx, notification)
+	}
+
 	ginCtx.JSON(http.StatusOK, Resp_Success)
 }
 
@@ -134,9 +207,9 @@ func (s *Server) LeaveParty(ginCtx *gin.Context) {
 	userInstance := user.(*database.User)
 
 	// get party name from path
-	partyName := ginCtx.Param("party_id")
-	if partyName == "" {
-		ginCtx.JSON(http.StatusBadRequest, Err_ReadingRequest)
+	partyName, errResp := ReadPartyIdParam(ginCtx)
+	if errResp != nil {
+		ginCtx.JSON(http.StatusBadRequest, errResp)
 		return
 	}
 
@@ -170,7 +243,7 @@ func (s *Server) LeaveParty(ginCtx *gin.Context) {
 		return
 	}
 
-	err = s.db.DeletePartyMembership(ginCtx, partyMembership)
+	err = s.db.DeletePartyMembership(database.WithActor(ginCtx, userInstance.Name), partyMembership)
 	if err != nil {
 		log.Printf("[ERROR] server.LeaveParty: deleting party membership from db: %s", err.Error())
 		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
@@ -190,16 +263,16 @@ func (s *Server) RemoveUserFromParty(ginCtx *gin.Context) {
 	userInstance := user.(*database.User)
 
 	// get party name from path
-	partyName := ginCtx.Param("party_id")
-	if partyName == "" {
-		ginCtx.JSON(http.StatusBadRequest, Err_ReadingRequest)
+	partyName, errResp := ReadPartyIdParam(ginCtx)
+	if errResp != nil {
+		ginCtx.JSON(http.StatusBadRequest, errResp)
 		return
 	}
 
 	// get user name from path
-	userName := ginCtx.Param("user_id")
-	if userName == "" {
-		ginCtx.JSON(http.StatusBadRequest, Err_ReadingRequest)
+	userName, errResp := ReadUserIdParam(ginCtx)
+	if errResp != nil {
+		ginCtx.JSON(http.StatusBadRequest, errResp)
 		return
 	}
 
@@ -240,7 +313,7 @@ func (s *Server) RemoveUserFromParty(ginCtx *gin.Context) {
 		return
 	}
 
-	err = s.db.DeletePartyMembership(ginCtx, partyMembership)
+	err = s.db.DeletePartyMembership(database.WithActor(ginCtx, userInstance.Name), partyMembership)
 	if err != nil {
 		log.Printf("[ERROR] server.RemoveUserFromParty: deleting party membership from db: %s", err.Error())
 		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
@@ -249,3 +322,116 @@ func (s *Server) RemoveUserFromParty(ginCtx *gin.Context) {
 
 	ginCtx.JSON(http.StatusOK, Resp_Success)
 }
+
+func (s *Server) DeclinePartyInvitation(ginCtx *gin.Context) {
+	// get user from context
+	user, exists := ginCtx.Get(Header_AuthUserKey)
+	if !exists || user == nil {
+		ginCtx.JSON(http.StatusUnauthorized, Err_AuthHeaderMissing)
+		return
+	}
+	userInstance := user.(*database.User)
+
+	// get party name from path
+	partyName, errResp := ReadPartyIdParam(ginCtx)
+	if errResp != nil {
+		ginCtx.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	// get party membership
+	partyMembership, err := s.db.GetPartyMembership(ginCtx, partyName, userInstance.Name)
+	if err != nil {
+		if err == database.Err_NotFound {
+			ginCtx.JSON(http.StatusNotFound, Err_PartyInvitationNotFound)
+			return
+		}
+		log.Printf("[ERROR] server.DeclinePartyInvitation: getting party membership from db: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	// only pending invitations can be declined
+	if partyMembership.Status != database.PartyMembership_Status_Invited {
+		ginCtx.JSON(http.StatusBadRequest, Err_PartyInvitationNotFound)
+		return
+	}
+
+	err = s.db.DeletePartyMembership(ginCtx, partyMembership)
+	if err != nil {
+		log.Printf("[ERROR] server.DeclinePartyInvitation: deleting party membership from db: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	ginCtx.JSON(http.StatusOK, Resp_Success)
+}
+
+func (s *Server) CancelPartyInvitation(ginCtx *gin.Context) {
+	// get user from context
+	user, exists := ginCtx.Get(Header_AuthUserKey)
+	if !exists || user == nil {
+		ginCtx.JSON(http.StatusUnauthorized, Err_AuthHeaderMissing)
+		return
+	}
+	userInstance := user.(*database.User)
+
+	// get party name from path
+	partyName, errResp := ReadPartyIdParam(ginCtx)
+	if errResp != nil {
+		ginCtx.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	// get invited user name from path
+	userName, errResp := ReadUserIdParam(ginCtx)
+	if errResp != nil {
+		ginCtx.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	// get party
+	party, err := s.db.GetParty(ginCtx, partyName)
+	if err != nil {
+		if err == database.Err_NotFound {
+			ginCtx.JSON(http.StatusNotFound, Err_PartyNotFound)
+			return
+		}
+		log.Printf("[ERROR] server.CancelPartyInvitation: getting party from db: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	// only creator should be able to cancel invitations
+	if party.Creator != userInstance.Name {
+		ginCtx.JSON(http.StatusUnauthorized, Err_NotPartyCreator)
+		return
+	}
+
+	// get party membership
+	partyMembership, err := s.db.GetPartyMembership(ginCtx, partyName, userName)
+	if err != nil {
+		if err == database.Err_NotFound {
+			ginCtx.JSON(http.StatusNotFound, Err_PartyInvitationNotFound)
+			return
+		}
+		log.Printf("[ERROR] server.CancelPartyInvitation: getting party membership from db: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	// active members have to be removed, not uninvited
+	if partyMembership.Status != database.PartyMembership_Status_Invited {
+		ginCtx.JSON(http.StatusBadRequest, Err_PartyInvitationNotFound)
+		return
+	}
+
+	err = s.db.DeletePartyMembership(ginCtx, partyMembership)
+	if err != nil {
+		log.Printf("[ERROR] server.CancelPartyInvitation: deleting party membership from db: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	ginCtx.JSON(http.StatusOK, Resp_Success)
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+
+	"socialite/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	partyMessagesDefaultLimit = 50
+	partyMessagesMaxLimit     = 100
+)
+
+func (s *Server) GetPartyMessages(ginCtx *gin.Context) {
+	// get user from context
+	user, exists := ginCtx.Get(Header_AuthUserKey)
+	if !exists || user == nil {
+		ginCtx.JSON(http.StatusUnauthorized, Err_AuthHeaderMissing)
+		return
+	}
+	userInstance := user.(*database.User)
+
+	// get party name from path
+	partyName, errResp := ReadPartyIdParam(ginCtx)
+	if errResp != nil {
+		ginCtx.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	// read pagination from query
+	limit := partyMessagesDefaultLimit
+	if limitQuery := ginCtx.Query("limit"); limitQuery != "" {
+		limitInt, err := strconv.Atoi(limitQuery)
+		if err != nil || limitInt <= 0 || limitInt > partyMessagesMaxLimit {
+			ginCtx.JSON(http.StatusBadRequest, Err_PaginationInvalid)
+			return
+		}
+		limit = limitInt
+	}
+	var beforeId int32
+	if beforeQuery := ginCtx.Query("before"); beforeQuery != "" {
+		beforeInt, err := strconv.ParseInt(beforeQuery, 10, 32)
+		if err != nil || beforeInt <= 0 {
+			ginCtx.JSON(http.StatusBadRequest, Err_PaginationInvalid)
+			return
+		}
+		beforeId = int32(beforeInt)
+	}
+
+	// only active members can read the chat
+	partyMembership, err := s.db.GetPartyMembership(ginCtx, partyName, userInstance.Name)
+	if err != nil {
+		if err == database.Err_NotFound {
+			ginCtx.JSON(http.StatusNotFound, Err_PartyMembershipNotFound)
+			return
+		}
+		log.Printf("[ERROR] server.GetPartyMessages: getting party membership from db: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+	if partyMembership.Status != database.PartyMembership_Status_Active {
+		ginCtx.JSON(http.StatusNotFound, Err_PartyMembershipNotFound)
+		return
+	}
+
+	messages, err := s.db.GetPartyMessages(ginCtx, partyName, beforeId, limit)
+	if err != nil {
+		log.Printf("[ERROR] server.GetPartyMessages: getting party messages from db: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	ginCtx.JSON(http.StatusOK, messages)
+}
+
+// SendPartyMessage stores a chat message and fans it out to the connected party members
+func (s *Server) SendPartyMessage(ctx context.Context, partyName, userName, text string) *GeneralResponse {
+	if !s.partyChatLimiter.Allow(userName) {
+		return &Err_RateLimited
+	}
+	if utf8.RuneCountInString(text) > s.partyChatMaxLength {
+		return &Err_MessageTooLong
+	}
+
+	// only active members can chat
+	partyMembership, err := s.db.GetPartyMembership(ctx, partyName, userName)
+	if err != nil {
+		if err == database.Err_NotFound {
+			return &Err_PartyMembershipNotFound
+		}
+		log.Printf("[ERROR] server.SendPartyMessage: getting party membership from db: %s", err.Error())
+		return &Err_SomethingWrong
+	}
+	if partyMembership.Status != database.PartyMembership_Status_Active {
+		return &Err_PartyMembershipNotFound
+	}
+
+	message, err := database.NewPartyMessage(partyName, userName, text)
+	if err != nil {
+		return &GeneralResponse{Message: err.Error()}
+	}
+
+	err = s.db.PutPartyMessage(ctx, message, s.partyChatHistorySize)
+	if err != nil {
+		log.Printf("[ERROR] server.SendPartyMessage: putting party message in db: %s", err.Error())
+		return &Err_SomethingWrong
+	}
+
+	resp, err := json.Marshal(WebsocketPartyOutgoingMessage{
+		MsgType:     MessageType_ChatMessage,
+		PartyName:   partyName,
+		ChatMessage: message,
+	})
+	if err != nil {
+		log.Printf("[ERROR] marshaling websocket message : %s", err.Error())
+		return &Err_SomethingWrong
+	}
+	s.BroadcastToParty(partyName, resp)
+	return nil
+}
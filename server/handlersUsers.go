@@ -0,0 +1,479 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"socialite/cache"
+	"socialite/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+const searchQueryMaxLength = 64
+
+func (s *Server) SearchUsers(ginCtx *gin.Context) {
+	// get user from context
+	user, exists := ginCtx.Get(Header_AuthUserKey)
+	if !exists || user == nil {
+		ginCtx.JSON(http.StatusUnauthorized, Err_AuthHeaderMissing)
+		return
+	}
+	userInstance := user.(*database.User)
+
+	// get search query
+	query := strings.TrimSpace(ginCtx.Query("q"))
+	if query == "" {
+		ginCtx.JSON(http.StatusBadRequest, Err_SearchQueryMissing)
+		return
+	}
+	if len(query) > searchQueryMaxLength {
+		ginCtx.JSON(http.StatusBadRequest, Err_SearchQueryTooLong)
+		return
+	}
+
+	// read pagination, results are always ordered by relevance
+	opts, err := ReadListOptions(ginCtx, database.ListSort_Relevance)
+	if err != nil {
+		ginCtx.JSON(http.StatusBadRequest, GeneralResponse{Message: err.Error()})
+		return
+	}
+
+	// search users, blocked users in either direction are excluded
+	results, err := s.db.SearchUsers(ginCtx, userInstance.Name, query, opts)
+	if err != nil {
+		log.Printf("[ERROR] server.SearchUsers: searching users in db: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	ginCtx.JSON(http.StatusOK, results)
+}
+
+func (s *Server) GetMutualFriends(ginCtx *gin.Context) {
+	// get user from context
+	user, exists := ginCtx.Get(Header_AuthUserKey)
+	if !exists || user == nil {
+		ginCtx.JSON(http.StatusUnauthorized, Err_AuthHeaderMissing)
+		return
+	}
+	userInstance := user.(*database.User)
+
+	// get other user name from path
+	otherName, errResp := ReadUserIdParam(ginCtx)
+	if errResp != nil {
+		ginCtx.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	// blocked users are hidden from each other
+	isBlocked, err := s.IsBlockedEitherWay(ginCtx, userInstance.Name, otherName)
+	if err != nil {
+		log.Printf("[ERROR] server.GetMutualFriends: checking blocks in db: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+	if isBlocked {
+		ginCtx.JSON(http.StatusNotFound, Err_UserNotFound)
+		return
+	}
+
+	// serve from cache while fresh
+	mutualFriends, err := s.cache.GetMutualFriends(ginCtx, userInstance.Name, otherName)
+	if err != nil {
+		log.Printf("[ERROR] server.GetMutualFriends: getting mutual friends from cache: %s", err.Error())
+	}
+	if mutualFriends != nil {
+		ginCtx.JSON(http.StatusOK, mutualFriends)
+		return
+	}
+
+	// check if user exists
+	_, err = s.db.GetUser(ginCtx, otherName)
+	if err != nil {
+		if err == database.Err_NotFound {
+			ginCtx.JSON(http.StatusNotFound, Err_UserNotFound)
+			return
+		}
+		log.Printf("[ERROR] server.GetMutualFriends: getting user from db: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	mutualFriends, err = s.db.GetMutualFriends(ginCtx, userInstance.Name, otherName)
+	if err != nil {
+		log.Printf("[ERROR] server.GetMutualFriends: getting mutual friends from db: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	err = s.cache.PutMutualFriends(ginCtx, userInstance.Name, otherName, mutualFriends)
+	if err != nil {
+		log.Printf("[ERROR] server.GetMutualFriends: putting mutual friends in cache: %s", err.Error())
+	}
+
+	ginCtx.JSON(http.StatusOK, mutualFriends)
+}
+
+func (s *Server) GetMyProfile(ginCtx *gin.Context) {
+	// get user from context
+	user, exists := ginCtx.Get(Header_AuthUserKey)
+	if !exists || user == nil {
+		ginCtx.JSON(http.StatusUnauthorized, Err_AuthHeaderMissing)
+		return
+	}
+	userInstance := user.(*database.User)
+
+	resp, status, errResp := s.GetUserProfileResponse(ginCtx, userInstance.Name)
+	if errResp != nil {
+		ginCtx.JSON(status, errResp)
+		return
+	}
+
+	ginCtx.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) UpdateMyProfile(ginCtx *gin.Context) {
+	// get user from context
+	user, exists := ginCtx.Get(Header_AuthUserKey)
+	if !exists || user == nil {
+		ginCtx.JSON(http.StatusUnauthorized, Err_AuthHeaderMissing)
+		return
+	}
+	userInstance := user.(*database.User)
+
+	// read request body
+	var reqBody UpdateProfileRequest
+	err := ginCtx.BindJSON(&reqBody)
+	if err != nil {
+		log.Printf("[ERROR] server.UpdateMyProfile: reading request body: %s", err.Error())
+		ginCtx.JSON(http.StatusBadRequest, Err_ReadingRequest)
+		return
+	}
+
+	// get current profile, users without one start from an empty profile
+	profileInstance, err := s.db.GetProfile(ginCtx, userInstance.Name)
+	if err != nil {
+		if err != database.Err_NotFound {
+			log.Printf("[ERROR] server.UpdateMyProfile: getting profile from db: %s", err.Error())
+			ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+			return
+		}
+		profileInstance, err = database.NewProfile(userInstance.Name)
+		if err != nil {
+			log.Printf("[ERROR] server.UpdateMyProfile: creating profile instance: %s", err.Error())
+			ginCtx.JSON(http.StatusBadRequest, GeneralResponse{Message: err.Error()})
+			return
+		}
+	}
+
+	// only change the fields present in the request
+	if reqBody.DisplayName != nil {
+		profileInstance.DisplayName = *reqBody.DisplayName
+	}
+	if reqBody.AvatarUrl != nil {
+		profileInstance.AvatarUrl = *reqBody.AvatarUrl
+	}
+	if reqBody.Bio != nil {
+		profileInstance.Bio = *reqBody.Bio
+	}
+	if reqBody.Region != nil {
+		profileInstance.Region = *reqBody.Region
+	}
+	if reqBody.Language != nil {
+		profileInstance.Language = *reqBody.Language
+	}
+	err = profileInstance.Validate()
+	if err != nil {
+		ginCtx.JSON(http.StatusBadRequest, GeneralResponse{Message: err.Error()})
+		return
+	}
+	profileInstance.UpdatedAt = time.Now()
+
+	err = s.db.PutProfile(ginCtx, profileInstance)
+	if err != nil {
+		if err == database.Err_NotFound {
+			ginCtx.JSON(http.StatusNotFound, Err_UserNotFound)
+			return
+		}
+		log.Printf("[ERROR] server.UpdateMyProfile: putting profile in db: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	ginCtx.JSON(http.StatusOK, profileInstance)
+}
+
+func (s *Server) GetUserProfile(ginCtx *gin.Context) {
+	// get user from context
+	user, exists := ginCtx.Get(Header_AuthUserKey)
+	if !exists || user == nil {
+		ginCtx.JSON(http.StatusUnauthorized, Err_AuthHeaderMissing)
+		return
+	}
+	userInstance := user.(*database.User)
+
+	// get other user name from path
+	otherName, errResp := ReadUserIdParam(ginCtx)
+	if errResp != nil {
+		ginCtx.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	// blocked users are hidden from each other
+	isBlocked, err := s.IsBlockedEitherWay(ginCtx, userInstance.Name, otherName)
+	if err != nil {
+		log.Printf("[ERROR] server.GetUserProfile: checking blocks in db: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+	if isBlocked {
+		ginCtx.JSON(http.StatusNotFound, Err_UserNotFound)
+		return
+	}
+
+	resp, status, errResp := s.GetUserProfileResponse(ginCtx, otherName)
+	if errResp != nil {
+		ginCtx.JSON(status, errResp)
+		return
+	}
+
+	ginCtx.JSON(http.StatusOK, resp)
+}
+
+// GetUserProfileResponse builds the user along with their profile, an empty one if they never set it
+func (s *Server) GetUserProfileResponse(ctx context.Context, userName string) (*UserProfileResponse, int, *GeneralResponse) {
+	userInstance, err := s.db.GetUser(ctx, userName)
+	if err != nil {
+		if err == database.Err_NotFound {
+			return nil, http.StatusNotFound, &Err_UserNotFound
+		}
+		log.Printf("[ERROR] server.GetUserProfileResponse: getting user from db: %s", err.Error())
+		return nil, http.StatusInternalServerError, &Err_SomethingWrong
+	}
+
+	profileInstance, err := s.db.GetProfile(ctx, userName)
+	if err != nil {
+		if err != database.Err_NotFound {
+			log.Printf("[ERROR] server.GetUserProfileResponse: getting profile from db: %s", err.Error())
+			return nil, http.StatusInternalServerError, &Err_SomethingWrong
+		}
+		profileInstance = &database.Profile{
+			UserName:  userInstance.Name,
+			UpdatedAt: userInstance.CreatedAt,
+		}
+	}
+
+	return &UserProfileResponse{
+		Name:      userInstance.Name,
+		CreatedAt: userInstance.CreatedAt,
+		Profile:   profileInstance,
+	}, http.StatusOK, nil
+}
+
+// PutInitialProfile creates the profile of a newly registered user
+func (s *Server) PutInitialProfile(ctx context.Context, userName, displayName string) error {
+	profileInstance, err := database.NewProfile(userName)
+	if err != nil {
+		return err
+	}
+	profileInstance.DisplayName = displayName
+	err = profileInstance.Validate()
+	if err != nil {
+		return err
+	}
+	return s.db.PutProfile(ctx, profileInstance)
+}
+
+func (s *Server) DeleteMyAccount(ginCtx *gin.Context) {
+	// get user from context
+	user, exists := ginCtx.Get(Header_AuthUserKey)
+	if !exists || user == nil {
+		ginCtx.JSON(http.StatusUnauthorized, Err_AuthHeaderMissing)
+		return
+	}
+	userInstance := user.(*database.User)
+
+	// with a grace period the user is only marked deleted and purged later by a cron
+	deletion, err := s.db.DeleteUser(ginCtx, userInstance.Name, s.userDeletionGracePeriod > 0)
+	if err != nil {
+		if err == database.Err_NotFound {
+			ginCtx.JSON(http.StatusNotFound, Err_UserNotFound)
+			return
+		}
+		log.Printf("[ERROR] server.DeleteMyAccount: deleting user from db: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	s.CleanupDeletedUser(ginCtx, deletion)
+
+	ginCtx.JSON(http.StatusOK, Resp_Success)
+}
+
+// CleanupDeletedUser closes the user's websockets and removes them from everything kept in the cache,
+// the other servers stop accepting their sessions once the cached active flag expires, see IsUserActive
+func (s *Server) CleanupDeletedUser(ctx context.Context, deletion *database.UserDeletion) {
+	s.CloseUserWebsockets(deletion.UserName, "account deleted")
+
+	err := s.cache.EvictUser(ctx, deletion.UserName, deletion.Friends)
+	if err != nil {
+		log.Printf("[ERROR] server.CleanupDeletedUser: evicting user from cache: %s", err.Error())
+	}
+	for _, eachFriend := range deletion.Friends {
+		s.RemoveFriendFromCache(ctx, eachFriend, deletion.UserName)
+	}
+
+	for _, eachParty := range deletion.DisbandedParties {
+		s.stopReadyCheckTimeout(eachParty)
+		s.ClosePartyWebsockets(eachParty, "party disbanded")
+		err = s.cache.DeletePartyMembersList(ctx, eachParty)
+		if err != nil {
+			log.Printf("[ERROR] server.CleanupDeletedUser: deleting party members from cache: %s", err.Error())
+		}
+	}
+	for _, eachParty := range deletion.Parties {
+		s.RemovePartyMemberFromCache(ctx, eachParty, deletion.UserName)
+		// a running ready check may now have every remaining member ready
+		s.EvaluatePartyReadyCheck(ctx, eachParty, false)
+	}
+}
+
+// IsUserActive tells if the user is registered and not deleted, the database is only asked
+// once per cache.UserActiveExpiry so deletions on other servers apply within that delay
+func (s *Server) IsUserActive(ctx context.Context, userName string) (bool, error) {
+	isActive, err := s.cache.IsUserActive(ctx, userName)
+	if err != nil {
+		log.Printf("[ERROR] server.IsUserActive: getting active user from cache: %s", err.Error())
+	}
+	if isActive {
+		return true, nil
+	}
+
+	_, err = s.db.GetUser(ctx, userName)
+	if err != nil {
+		if err == database.Err_NotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	err = s.cache.PutUserActive(ctx, userName)
+	if err != nil {
+		log.Printf("[ERROR] server.IsUserActive: putting active user in cache: %s", err.Error())
+	}
+	return true, nil
+}
+
+// CloseRevokedConnectionsCron closes the connections of the users deleted on other servers
+func (s *Server) CloseRevokedConnectionsCron(ctx context.Context) {
+	log.Printf("[INFO] starting cron for closing connections of deleted users")
+	for {
+		if !sleep(ctx, cache.UserActiveExpiry) {
+			return
+		}
+		for _, eachUser := range s.connectedUserNames() {
+			isActive, err := s.IsUserActive(ctx, eachUser)
+			if err != nil {
+				log.Printf("[ERROR] checking if user %s is active : %s", eachUser, err.Error())
+				continue
+			}
+			if !isActive {
+				s.CloseUserWebsockets(eachUser, "account deleted")
+			}
+		}
+	}
+}
+
+// RemovePartyMemberFromCache drops the user from the party's cached members list
+func (s *Server) RemovePartyMemberFromCache(ctx context.Context, partyName, userName string) {
+	membersList, err := s.cache.GetPartyMembersList(ctx, partyName)
+	if err != nil {
+		log.Printf("[ERROR] getting party members list from cache : %s", err.Error())
+		return
+	}
+	updatedList := make([]string, 0, len(membersList))
+	for _, eachMember := range membersList {
+		if eachMember != userName {
+			updatedList = append(updatedList, eachMember)
+		}
+	}
+	err = s.cache.PutPartyMembersList(ctx, partyName, updatedList)
+	if err != nil {
+		log.Printf("[ERROR] putting party members list in cache : %s", err.Error())
+	}
+}
+
+func (s *Server) ExportMyData(ginCtx *gin.Context) {
+	// get user from context
+	user, exists := ginCtx.Get(Header_AuthUserKey)
+	if !exists || user == nil {
+		ginCtx.JSON(http.StatusUnauthorized, Err_AuthHeaderMissing)
+		return
+	}
+	userInstance := user.(*database.User)
+
+	format := ginCtx.DefaultQuery("format", "json")
+	if format != "json" && format != "zip" {
+		ginCtx.JSON(http.StatusBadRequest, Err_ExportFormatInvalid)
+		return
+	}
+
+	export, err := database.ExportUserData(ginCtx, s.db, userInstance.Name)
+	if err != nil {
+		if err == database.Err_NotFound {
+			ginCtx.JSON(http.StatusNotFound, Err_UserNotFound)
+			return
+		}
+		log.Printf("[ERROR] server.ExportMyData: exporting user data from db: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+	exportJson, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		log.Printf("[ERROR] server.ExportMyData: marshaling export: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	fileName := fmt.Sprintf("socialite-export-%s-%s", userInstance.Name, export.ExportedAt.Format("20060102150405"))
+	if format == "json" {
+		ginCtx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, fileName))
+		ginCtx.Data(http.StatusOK, "application/json", exportJson)
+		return
+	}
+
+	archive, err := zipFile(fileName+".json", exportJson)
+	if err != nil {
+		log.Printf("[ERROR] server.ExportMyData: zipping export: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+	ginCtx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, fileName))
+	ginCtx.Data(http.StatusOK, "application/zip", archive)
+}
+
+// zipFile returns a zip archive holding a single file
+func zipFile(name string, content []byte) ([]byte, error) {
+	var archive bytes.Buffer
+	zipWriter := zip.NewWriter(&archive)
+	fileWriter, err := zipWriter.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	_, err = fileWriter.Write(content)
+	if err != nil {
+		return nil, err
+	}
+	err = zipWriter.Close()
+	if err != nil {
+		return nil, err
+	}
+	return archive.Bytes(), nil
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strconv"
+
+	"socialite/database"
+	"socialite/events"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminMiddleware lets through requests carrying the webhook admin token,
+// every request is rejected when no token is configured
+func (s *Server) AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.webhookAdminToken == "" {
+			c.JSON(http.StatusForbidden, Err_WebhooksDisabled)
+			c.Abort()
+			return
+		}
+		adminToken := c.GetHeader(Header_AdminToken)
+		if subtle.ConstantTimeCompare([]byte(adminToken), []byte(s.webhookAdminToken)) != 1 {
+			c.JSON(http.StatusUnauthorized, Err_AdminTokenInvalid)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func (s *Server) GetWebhooks(ginCtx *gin.Context) {
+	subscriptions, err := s.db.GetWebhookSubscriptions(ginCtx)
+	if err != nil {
+		log.Printf("[ERROR] server.GetWebhooks: getting webhook subscriptions: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	ginCtx.JSON(http.StatusOK, subscriptions)
+}
+
+func (s *Server) CreateWebhook(ginCtx *gin.Context) {
+	// read request body
+	var reqBody CreateWebhookRequest
+	err := ginCtx.BindJSON(&reqBody)
+	if err != nil {
+		log.Printf("[ERROR] server.CreateWebhook: reading request body: %s", err.Error())
+		ginCtx.JSON(http.StatusBadRequest, Err_ReadingRequest)
+		return
+	}
+	for _, eachType := range reqBody.EventTypes {
+		if !events.Type(eachType).IsValid() {
+			ginCtx.JSON(http.StatusBadRequest, Err_WebhookEventTypeInvalid)
+			return
+		}
+	}
+
+	subscription, err := database.NewWebhookSubscription(reqBody.Url, reqBody.EventTypes, reqBody.Secret)
+	if err != nil {
+		ginCtx.JSON(http.StatusBadRequest, GeneralResponse{Message: err.Error()})
+		return
+	}
+
+	err = s.db.PutWebhookSubscription(ginCtx, subscription)
+	if err != nil {
+		log.Printf("[ERROR] server.CreateWebhook: putting webhook subscription: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	// the secret is only shown here, the receiver needs it to verify signatures
+	ginCtx.JSON(http.StatusCreated, subscription)
+}
+
+func (s *Server) DeleteWebhook(ginCtx *gin.Context) {
+	// get webhook id from path
+	webhookId, errResp := ReadWebhookIdParam(ginCtx)
+	if errResp != nil {
+		ginCtx.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	err := s.db.DeleteWebhookSubscription(ginCtx, webhookId)
+	if err != nil {
+		if err == database.Err_NotFound {
+			ginCtx.JSON(http.StatusNotFound, Err_WebhookNotFound)
+			return
+		}
+		log.Printf("[ERROR] server.DeleteWebhook: deleting webhook subscription: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	ginCtx.JSON(http.StatusOK, Resp_Success)
+}
+
+func (s *Server) GetWebhookDeliveries(ginCtx *gin.Context) {
+	// get webhook id from path
+	webhookId, errResp := ReadWebhookIdParam(ginCtx)
+	if errResp != nil {
+		ginCtx.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+	limit, errResp := readLimitQuery(ginCtx)
+	if errResp != nil {
+		ginCtx.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	deliveries, err := s.db.GetWebhookDeliveries(ginCtx, webhookId, limit)
+	if err != nil {
+		log.Printf("[ERROR] server.GetWebhookDeliveries: getting webhook deliveries: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	ginCtx.JSON(http.StatusOK, deliveries)
+}
+
+func (s *Server) GetWebhookDeadLetters(ginCtx *gin.Context) {
+	limit, errResp := readLimitQuery(ginCtx)
+	if errResp != nil {
+		ginCtx.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	deadLetters, err := s.db.GetWebhookDeadLetters(ginCtx, limit)
+	if err != nil {
+		log.Printf("[ERROR] server.GetWebhookDeadLetters: getting webhook dead letters: %s", err.Error())
+		ginCtx.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+		return
+	}
+
+	ginCtx.JSON(http.StatusOK, deadLetters)
+}
+
+// readLimitQuery reads the limit of the latest-first logs, which are not paginated
+func readLimitQuery(ginCtx *gin.Context) (int, *GeneralResponse) {
+	limitQuery := ginCtx.Query("limit")
+	if limitQuery == "" {
+		return database.ListDefaultLimit, nil
+	}
+	limit, err := strconv.Atoi(limitQuery)
+	if err != nil || limit <= 0 || limit > database.ListMaxLimit {
+		return 0, &Err_PaginationInvalid
+	}
+	return limit, nil
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"net"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// keepAlive makes the reads of the websocket fail once the client misses the pong timeout,
+// every pong pushes the read deadline back and refreshes the user's online status
+func (s *Server) keepAlive(conn *websocket.Conn, userName string) {
+	conn.SetReadDeadline(time.Now().Add(s.websocketPongTimeout))
+	conn.SetPongHandler(func(string) error {
+		s.userOnlineStatus <- userName
+		return conn.SetReadDeadline(time.Now().Add(s.websocketPongTimeout))
+	})
+}
+
+// writePing pings the client of the websocket, false once the connection is broken
+func (s *Server) writePing(conn *websocket.Conn) bool {
+	err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(s.websocketWriteTimeout))
+	if err != nil {
+		log.Printf("[ERROR] writing ping to websocket : %s", err.Error())
+		return false
+	}
+	return true
+}
+
+// logWebsocketReadError logs why the reads of the user's websocket stopped,
+// closes by the client or the server are expected and not logged
+func logWebsocketReadError(userName string, err error) {
+	if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) || errors.Is(err, net.ErrClosed) {
+		return
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		log.Printf("[INFO] websocket of user %s missed the pong timeout, closing it", userName)
+		return
+	}
+	log.Printf("[ERROR] reading message from websocket of user %s : %s", userName, err.Error())
+}
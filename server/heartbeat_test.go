@@ -0,0 +1,87 @@
+package server
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestKeepAlive(t *testing.T) {
+	s := &Server{
+		userOnlineStatus:      make(chan string, 100),
+		websocketPingInterval: time.Millisecond * 20,
+		websocketPongTimeout:  time.Millisecond * 100,
+		websocketWriteTimeout: time.Second,
+	}
+	readErrs := make(chan error, 1)
+	upgrader := websocket.Upgrader{}
+	wsServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		done := make(chan struct{})
+		defer close(done)
+		s.keepAlive(conn, "user1")
+		go func() {
+			pingTicker := time.NewTicker(s.websocketPingInterval)
+			defer pingTicker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-pingTicker.C:
+					if !s.writePing(conn) {
+						return
+					}
+				}
+			}
+		}()
+		_, _, err = conn.ReadMessage()
+		readErrs <- err
+	}))
+	defer wsServer.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(wsServer.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// pongs are only written while the client reads
+	go func() {
+		conn.SetReadDeadline(time.Now().Add(time.Millisecond * 300))
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	select {
+	case err := <-readErrs:
+		t.Fatalf("connection answering pings is closed : %v", err)
+	case <-time.After(time.Millisecond * 250):
+	}
+	if len(s.userOnlineStatus) == 0 {
+		t.Error("pongs do not refresh the online status")
+	}
+
+	select {
+	case err := <-readErrs:
+		var netErr net.Error
+		if !errors.As(err, &netErr) || !netErr.Timeout() {
+			t.Errorf("read failed with %v, expected a timeout", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("connection not answering pings is kept open")
+	}
+}
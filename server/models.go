@@ -1,7 +1,15 @@
 package server
 
+import (
+	"time"
+
+	"socialite/database"
+)
+
 const (
+	Header_AdminToken  = "X-Admin-Token"
 	Header_AuthUserKey = "auth_user"
+	Header_LastEventId = "Last-Event-ID"
 )
 
 type HealthResponse struct {
@@ -24,6 +32,43 @@ type LoginResponse struct {
 	Token string `json:"token"`
 }
 
+type UserProfileResponse struct {
+	Name      string            `json:"name"`
+	CreatedAt time.Time         `json:"created_at"`
+	Profile   *database.Profile `json:"profile"`
+}
+
+// UpdateProfileRequest only changes the fields which are present
+type UpdateProfileRequest struct {
+	DisplayName *string `json:"display_name"`
+	AvatarUrl   *string `json:"avatar_url"`
+	Bio         *string `json:"bio"`
+	Region      *string `json:"region"`
+	Language    *string `json:"language"`
+}
+
+// UpdateFriendRequest only changes the fields which are present
+type UpdateFriendRequest struct {
+	Nickname *string `json:"nickname"`
+	Favorite *bool   `json:"favorite"`
+}
+
+type FriendGroupRequest struct {
+	Name string `json:"name"`
+}
+
+type NotificationsResponse struct {
+	*database.Page[*database.Notification]
+	UnreadCount int `json:"unread_count"`
+}
+
+// CreateWebhookRequest makes a random secret when none is given
+type CreateWebhookRequest struct {
+	Url        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Secret     string   `json:"secret"`
+}
+
 type CreatePartyRequest struct {
 	Name string `json:"name"`
 }
@@ -31,3 +76,30 @@ type CreatePartyRequest struct {
 type InviteUserToPartyRequest struct {
 	UserName string `json:"user_name"`
 }
+
+type PartyMemberResponse struct {
+	UserName string                          `json:"user_name"`
+	Status   database.PartyMembership_Status `json:"status"`
+	Role     database.PartyMembership_Role   `json:"role"`
+	Online   bool                            `json:"online"`
+	JoinedAt time.Time                       `json:"joined_at"`
+	Profile  *database.ProfileSummary        `json:"profile,omitempty"`
+}
+
+type PartyDetailsResponse struct {
+	*database.Party
+	Members []*PartyMemberResponse `json:"members"`
+}
+
+type SendDirectMessageRequest struct {
+	Text string `json:"text"`
+}
+
+type ReadDirectMessagesRequest struct {
+	UpToId int32 `json:"up_to_id"`
+}
+
+type UnreadDirectMessagesResponse struct {
+	Total   int            `json:"total"`
+	Senders map[string]int `json:"senders"`
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"errors"
+	"strconv"
+
+	"socialite/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReadListOptions reads pagination, sorting and filtering query params and validates them for the allowed sorts
+func ReadListOptions(ginCtx *gin.Context, allowedSorts ...database.ListSort) (*database.ListOptions, error) {
+	opts := &database.ListOptions{
+		Cursor:     ginCtx.Query("cursor"),
+		Sort:       database.ListSort(ginCtx.Query("sort")),
+		NamePrefix: ginCtx.Query("name_prefix"),
+	}
+	if limitQuery := ginCtx.Query("limit"); limitQuery != "" {
+		limit, err := strconv.Atoi(limitQuery)
+		if err != nil {
+			return nil, errors.New("limit is invalid")
+		}
+		opts.Limit = limit
+	}
+	if onlineQuery := ginCtx.Query("online"); onlineQuery != "" {
+		onlineOnly, err := strconv.ParseBool(onlineQuery)
+		if err != nil {
+			return nil, errors.New("online is invalid")
+		}
+		opts.OnlineOnly = onlineOnly
+	}
+	err := opts.Normalize(allowedSorts...)
+	if err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"strconv"
+
+	"socialite/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReadUserIdParam reads the user_id path param as a normalized user name
+func ReadUserIdParam(ginCtx *gin.Context) (string, *GeneralResponse) {
+	userName := ginCtx.Param("user_id")
+	if userName == "" {
+		return "", &Err_UserIdMissing
+	}
+	userName, err := validation.Name(userName)
+	if err != nil {
+		return "", &Err_UserIdInvalid
+	}
+	return userName, nil
+}
+
+// ReadPartyIdParam reads the party_id path param as a normalized party name
+func ReadPartyIdParam(ginCtx *gin.Context) (string, *GeneralResponse) {
+	partyName := ginCtx.Param("party_id")
+	if partyName == "" {
+		return "", &Err_PartyIdMissing
+	}
+	partyName, err := validation.Name(partyName)
+	if err != nil {
+		return "", &Err_PartyIdInvalid
+	}
+	return partyName, nil
+}
+
+// ReadGroupIdParam reads the group_id path param as a friend group id
+func ReadGroupIdParam(ginCtx *gin.Context) (int32, *GeneralResponse) {
+	return readIdParam(ginCtx, "group_id", &Err_GroupIdMissing, &Err_GroupIdInvalid)
+}
+
+// ReadNotificationIdParam reads the notification_id path param as a notification id
+func ReadNotificationIdParam(ginCtx *gin.Context) (int32, *GeneralResponse) {
+	return readIdParam(ginCtx, "notification_id", &Err_NotificationIdMissing, &Err_NotificationIdInvalid)
+}
+
+// ReadWebhookIdParam reads the webhook_id path param as a webhook subscription id
+func ReadWebhookIdParam(ginCtx *gin.Context) (int32, *GeneralResponse) {
+	return readIdParam(ginCtx, "webhook_id", &Err_WebhookIdMissing, &Err_WebhookIdInvalid)
+}
+
+// readIdParam reads a path param holding a positive serial id
+func readIdParam(ginCtx *gin.Context, param string, errMissing, errInvalid *GeneralResponse) (int32, *GeneralResponse) {
+	id := ginCtx.Param(param)
+	if id == "" {
+		return 0, errMissing
+	}
+	idInt, err := strconv.ParseInt(id, 10, 32)
+	if err != nil || idInt <= 0 {
+		return 0, errInvalid
+	}
+	return int32(idInt), nil
+}
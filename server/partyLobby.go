@@ -0,0 +1,368 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"socialite/database"
+	"socialite/metrics"
+
+	"github.com/gorilla/websocket"
+)
+
+type partyConnection struct {
+	userName string
+	ws       *websocket.Conn
+	queue    *sendQueue[*partyMessage]
+	done     chan struct{}
+}
+
+// partyMessage is a message waiting for the writer of a party connection
+type partyMessage struct {
+	data []byte
+	// the online friends in the party, a newer list supersedes a queued one
+	presence bool
+}
+
+func newPartyConnection(userName string, ws *websocket.Conn, queueSize int, policy SlowConsumerPolicy) *partyConnection {
+	return &partyConnection{
+		userName: userName,
+		ws:       ws,
+		queue: newSendQueue(metrics.Connection_Party, queueSize, policy, func(msg *partyMessage) string {
+			if msg.presence {
+				return MessageType_FriendsOnlineInParty
+			}
+			return ""
+		}),
+		done: make(chan struct{}),
+	}
+}
+
+// write queues the message for the connection's writer without waiting on it,
+// a connection too slow to keep up is closed under the disconnect policy
+func (c *partyConnection) write(msg []byte) {
+	c.push(&partyMessage{data: msg})
+}
+
+// writePresence queues the online friends in the party, coalesced with a queued list under the coalesce policy
+func (c *partyConnection) writePresence(msg []byte) {
+	c.push(&partyMessage{data: msg, presence: true})
+}
+
+func (c *partyConnection) push(msg *partyMessage) {
+	if !c.queue.push(msg) {
+		go closeSlowConsumer(c.ws)
+	}
+}
+
+func (s *Server) addPartyConnection(partyName string, conn *partyConnection) {
+	s.partyRwmutex.Lock()
+	defer s.partyRwmutex.Unlock()
+
+	if s.partyWebsocketConns[partyName] == nil {
+		s.partyWebsocketConns[partyName] = make(map[*partyConnection]bool)
+	}
+	s.partyWebsocketConns[partyName][conn] = true
+}
+
+func (s *Server) removePartyConnection(partyName string, conn *partyConnection) {
+	s.partyRwmutex.Lock()
+	defer s.partyRwmutex.Unlock()
+
+	delete(s.partyWebsocketConns[partyName], conn)
+	if len(s.partyWebsocketConns[partyName]) == 0 {
+		delete(s.partyWebsocketConns, partyName)
+	}
+}
+
+// BroadcastToParty sends the message to every connected member of the party
+func (s *Server) BroadcastToParty(partyName string, msg []byte) {
+	s.partyRwmutex.RLock()
+	conns := make([]*partyConnection, 0, len(s.partyWebsocketConns[partyName]))
+	for eachConn := range s.partyWebsocketConns[partyName] {
+		conns = append(conns, eachConn)
+	}
+	s.partyRwmutex.RUnlock()
+
+	for _, eachConn := range conns {
+		eachConn.write(msg)
+	}
+}
+
+func (s *Server) HandlePartyMessage(ctx context.Context, partyName, userName string, msg *WebsocketPartyIncomingMessage, conn *partyConnection) {
+	var errResp *GeneralResponse
+	switch msg.MsgType {
+	case MessageType_ReadyCheckStart:
+		errResp = s.StartPartyReadyCheck(ctx, partyName, userName)
+	case MessageType_ReadyCheckResponse:
+		errResp = s.AnswerPartyReadyCheck(ctx, partyName, userName, msg.Ready)
+	case MessageType_SetPartyState:
+		errResp = s.SetPartyState(ctx, partyName, userName, msg.State)
+	case MessageType_ChatMessage:
+		errResp = s.SendPartyMessage(ctx, partyName, userName, msg.Text)
+	default:
+		log.Printf("[ERROR] unknown message type : %s", msg.MsgType)
+		errResp = &Err_UnknownMessageType
+	}
+	if errResp == nil {
+		return
+	}
+
+	resp, err := json.Marshal(WebsocketPartyOutgoingMessage{
+		MsgType:   MessageType_Error,
+		PartyName: partyName,
+		Message:   errResp.Message,
+	})
+	if err != nil {
+		log.Printf("[ERROR] marshaling websocket message : %s", err.Error())
+		return
+	}
+	conn.write(resp)
+}
+
+func (s *Server) StartPartyReadyCheck(ctx context.Context, partyName, userName string) *GeneralResponse {
+	party, err := s.db.GetParty(ctx, partyName)
+	if err != nil {
+		if err == database.Err_NotFound {
+			return &Err_PartyNotFound
+		}
+		log.Printf("[ERROR] server.StartPartyReadyCheck: getting party from db: %s", err.Error())
+		return &Err_SomethingWrong
+	}
+
+	// only creator should be able to start a ready check
+	if party.Creator != userName {
+		return &Err_NotPartyCreator
+	}
+
+	err = s.db.StartPartyReadyCheck(database.WithActor(ctx, userName), partyName)
+	if err != nil {
+		if err == database.Err_StateChanged {
+			return &Err_PartyStateChangeInvalid
+		}
+		log.Printf("[ERROR] server.StartPartyReadyCheck: starting ready check in db: %s", err.Error())
+		return &Err_SomethingWrong
+	}
+
+	s.scheduleReadyCheckTimeout(partyName, s.partyReadyCheckTimeout)
+	s.BroadcastPartyLobby(ctx, partyName)
+	return nil
+}
+
+func (s *Server) AnswerPartyReadyCheck(ctx context.Context, partyName, userName string, ready bool) *GeneralResponse {
+	readyStatus := database.PartyMembership_Ready_NotReady
+	if ready {
+		readyStatus = database.PartyMembership_Ready_Ready
+	}
+
+	err := s.db.PutPartyReadyResponse(ctx, partyName, userName, readyStatus)
+	if err != nil {
+		if err == database.Err_StateChanged {
+			return &Err_PartyReadyCheckNotRunning
+		}
+		log.Printf("[ERROR] server.AnswerPartyReadyCheck: putting ready response in db: %s", err.Error())
+		return &Err_SomethingWrong
+	}
+
+	s.EvaluatePartyReadyCheck(ctx, partyName, false)
+	return nil
+}
+
+func (s *Server) SetPartyState(ctx context.Context, partyName, userName string, state database.Party_State) *GeneralResponse {
+	party, err := s.db.GetParty(ctx, partyName)
+	if err != nil {
+		if err == database.Err_NotFound {
+			return &Err_PartyNotFound
+		}
+		log.Printf("[ERROR] server.SetPartyState: getting party from db: %s", err.Error())
+		return &Err_SomethingWrong
+	}
+
+	// only creator should be able to change the state
+	if party.Creator != userName {
+		return &Err_NotPartyCreator
+	}
+
+	// ready checks are started and completed by the server only
+	if state == database.Party_State_ReadyCheck ||
+		(party.State == database.Party_State_ReadyCheck && state == database.Party_State_Queued) ||
+		!party.State.CanTransitionTo(state) {
+		return &Err_PartyStateChangeInvalid
+	}
+
+	err = s.db.UpdatePartyState(database.WithActor(ctx, userName), partyName, party.State, state)
+	if err != nil {
+		if err == database.Err_StateChanged {
+			return &Err_PartyStateChangeInvalid
+		}
+		log.Printf("[ERROR] server.SetPartyState: updating party state in db: %s", err.Error())
+		return &Err_SomethingWrong
+	}
+
+	// a cancelled ready check needs no timeout anymore
+	if party.State == database.Party_State_ReadyCheck {
+		s.stopReadyCheckTimeout(partyName)
+	}
+
+	s.BroadcastPartyLobby(ctx, partyName)
+	return nil
+}
+
+// EvaluatePartyReadyCheck moves a running ready check to queued when every member is ready,
+// and back to idle when anyone is not ready or the timeout has passed
+func (s *Server) EvaluatePartyReadyCheck(ctx context.Context, partyName string, timedOut bool) {
+	party, err := s.db.GetParty(ctx, partyName)
+	if err != nil {
+		log.Printf("[ERROR] server.EvaluatePartyReadyCheck: getting party from db: %s", err.Error())
+		return
+	}
+	if party.State != database.Party_State_ReadyCheck {
+		return
+	}
+
+	memberships, err := s.db.GetPartyMemberships(ctx, partyName)
+	if err != nil {
+		log.Printf("[ERROR] server.EvaluatePartyReadyCheck: getting party memberships from db: %s", err.Error())
+		return
+	}
+
+	readyCheck := s.newPartyReadyCheck(party, memberships)
+	var nextState database.Party_State
+	switch {
+	case readyCheck.NotReady > 0:
+		nextState = database.Party_State_Idle
+	case readyCheck.Pending == 0:
+		nextState = database.Party_State_Queued
+	case timedOut || time.Now().After(readyCheck.Deadline):
+		nextState = database.Party_State_Idle
+	}
+
+	if nextState != "" {
+		err = s.db.UpdatePartyState(ctx, partyName, database.Party_State_ReadyCheck, nextState)
+		if err != nil {
+			// someone else completed the ready check already
+			if err == database.Err_StateChanged {
+				return
+			}
+			log.Printf("[ERROR] server.EvaluatePartyReadyCheck: updating party state in db: %s", err.Error())
+			return
+		}
+		s.stopReadyCheckTimeout(partyName)
+	}
+
+	s.BroadcastPartyLobby(ctx, partyName)
+}
+
+// GetPartyLobby builds the current lobby state of the party
+func (s *Server) GetPartyLobby(ctx context.Context, partyName string) (*WebsocketPartyOutgoingMessage, error) {
+	party, err := s.db.GetParty(ctx, partyName)
+	if err != nil {
+		return nil, err
+	}
+	memberships, err := s.db.GetPartyMemberships(ctx, partyName)
+	if err != nil {
+		return nil, err
+	}
+
+	lobby := &WebsocketPartyOutgoingMessage{
+		MsgType:   MessageType_PartyLobby,
+		PartyName: partyName,
+		State:     party.State,
+	}
+	readyCheck := s.newPartyReadyCheck(party, memberships)
+	if len(readyCheck.Members) > 0 {
+		lobby.ReadyCheck = readyCheck
+	}
+	return lobby, nil
+}
+
+func (s *Server) BroadcastPartyLobby(ctx context.Context, partyName string) {
+	lobby, err := s.GetPartyLobby(ctx, partyName)
+	if err != nil {
+		log.Printf("[ERROR] server.BroadcastPartyLobby: getting party lobby: %s", err.Error())
+		return
+	}
+	resp, err := json.Marshal(lobby)
+	if err != nil {
+		log.Printf("[ERROR] marshaling websocket message : %s", err.Error())
+		return
+	}
+	s.BroadcastToParty(partyName, resp)
+}
+
+// SendPartyLobby sends the current lobby state to a single connection,
+// resuming the timeout of a ready check that outlived the server which started it
+func (s *Server) SendPartyLobby(ctx context.Context, partyName string, conn *partyConnection) {
+	lobby, err := s.GetPartyLobby(ctx, partyName)
+	if err != nil {
+		log.Printf("[ERROR] server.SendPartyLobby: getting party lobby: %s", err.Error())
+		return
+	}
+
+	if lobby.State == database.Party_State_ReadyCheck && lobby.ReadyCheck != nil {
+		remaining := time.Until(lobby.ReadyCheck.Deadline)
+		if remaining <= 0 {
+			s.EvaluatePartyReadyCheck(ctx, partyName, true)
+			return
+		}
+		s.partyRwmutex.RLock()
+		_, timerExists := s.partyReadyCheckTimers[partyName]
+		s.partyRwmutex.RUnlock()
+		if !timerExists {
+			s.scheduleReadyCheckTimeout(partyName, remaining)
+		}
+	}
+
+	resp, err := json.Marshal(lobby)
+	if err != nil {
+		log.Printf("[ERROR] marshaling websocket message : %s", err.Error())
+		return
+	}
+	conn.write(resp)
+}
+
+func (s *Server) newPartyReadyCheck(party *database.Party, memberships []*database.PartyMembership) *PartyReadyCheck {
+	readyCheck := &PartyReadyCheck{
+		Deadline: party.StateUpdatedAt.Add(s.partyReadyCheckTimeout),
+		Members:  make(map[string]database.PartyMembership_Ready, len(memberships)),
+	}
+	for _, eachMembership := range memberships {
+		if eachMembership.Status != database.PartyMembership_Status_Active || eachMembership.Ready == "" {
+			continue
+		}
+		readyCheck.Members[eachMembership.UserName] = eachMembership.Ready
+		switch eachMembership.Ready {
+		case database.PartyMembership_Ready_Ready:
+			readyCheck.Ready++
+		case database.PartyMembership_Ready_NotReady:
+			readyCheck.NotReady++
+		default:
+			readyCheck.Pending++
+		}
+	}
+	return readyCheck
+}
+
+func (s *Server) scheduleReadyCheckTimeout(partyName string, after time.Duration) {
+	s.partyRwmutex.Lock()
+	defer s.partyRwmutex.Unlock()
+
+	if timer, exists := s.partyReadyCheckTimers[partyName]; exists {
+		timer.Stop()
+	}
+	s.partyReadyCheckTimers[partyName] = time.AfterFunc(after, func() {
+		s.EvaluatePartyReadyCheck(context.Background(), partyName, true)
+	})
+}
+
+func (s *Server) stopReadyCheckTimeout(partyName string) {
+	s.partyRwmutex.Lock()
+	defer s.partyRwmutex.Unlock()
+
+	if timer, exists := s.partyReadyCheckTimers[partyName]; exists {
+		timer.Stop()
+		delete(s.partyReadyCheckTimers, partyName)
+	}
+}
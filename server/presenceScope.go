@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"log"
+
+	"socialite/database"
+)
+
+type PresenceScope string
+
+const (
+	PresenceScope_All       PresenceScope = "all"
+	PresenceScope_Favorites PresenceScope = "favorites"
+	PresenceScope_Group     PresenceScope = "group"
+)
+
+// presenceScope narrows the friends whose online status is pushed to a status websocket
+type presenceScope struct {
+	scope   PresenceScope
+	groupId int32
+	friends map[string]bool
+}
+
+// resolvePresenceScope looks up the friends in the scope, a missing group is database.Err_NotFound
+func (s *Server) resolvePresenceScope(ctx context.Context, userName string, scope PresenceScope, groupId int32) (*presenceScope, error) {
+	var friendNames []string
+	var err error
+	switch scope {
+	case PresenceScope_Favorites:
+		friendNames, err = s.db.GetFavoriteFriendNames(ctx, userName)
+	case PresenceScope_Group:
+		var group *database.FriendGroup
+		group, err = s.db.GetFriendGroup(ctx, userName, groupId)
+		if group != nil {
+			friendNames = group.Members
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := &presenceScope{
+		scope:   scope,
+		groupId: groupId,
+		friends: make(map[string]bool, len(friendNames)),
+	}
+	for _, eachName := range friendNames {
+		resolved.friends[eachName] = true
+	}
+	return resolved, nil
+}
+
+// SetPresenceScope applies the scope to the user's status websocket, the all scope removes any narrowing
+func (s *Server) SetPresenceScope(ctx context.Context, userName string, scope PresenceScope, groupId int32) *GeneralResponse {
+	switch scope {
+	case PresenceScope_All:
+		s.rwmutex.Lock()
+		delete(s.userPresenceScopes, userName)
+		s.rwmutex.Unlock()
+		return nil
+	case PresenceScope_Favorites:
+		groupId = 0
+	case PresenceScope_Group:
+		if groupId <= 0 {
+			return &Err_GroupIdInvalid
+		}
+	default:
+		return &Err_PresenceScopeInvalid
+	}
+
+	resolved, err := s.resolvePresenceScope(ctx, userName, scope, groupId)
+	if err != nil {
+		if err == database.Err_NotFound {
+			return &Err_FriendGroupNotFound
+		}
+		log.Printf("[ERROR] server.SetPresenceScope: resolving presence scope: %s", err.Error())
+		return &Err_SomethingWrong
+	}
+
+	s.rwmutex.Lock()
+	s.userPresenceScopes[userName] = resolved
+	s.rwmutex.Unlock()
+	return nil
+}
+
+// RefreshPresenceScope resolves the user's scope again after their favorites or groups changed,
+// a scope on a deleted group falls back to all friends
+func (s *Server) RefreshPresenceScope(ctx context.Context, userName string) {
+	s.rwmutex.RLock()
+	current := s.userPresenceScopes[userName]
+	s.rwmutex.RUnlock()
+	if current == nil {
+		return
+	}
+
+	resolved, err := s.resolvePresenceScope(ctx, userName, current.scope, current.groupId)
+	if err != nil && err != database.Err_NotFound {
+		log.Printf("[ERROR] server.RefreshPresenceScope: resolving presence scope: %s", err.Error())
+		return
+	}
+
+	s.rwmutex.Lock()
+	defer s.rwmutex.Unlock()
+	// the user subscribed again in the meantime
+	if s.userPresenceScopes[userName] != current {
+		return
+	}
+	if resolved == nil {
+		delete(s.userPresenceScopes, userName)
+		return
+	}
+	s.userPresenceScopes[userName] = resolved
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	rateLimiterPruneSize = 10_000
+)
+
+// rateLimiter allows a fixed number of events per key in every interval
+type rateLimiter struct {
+	mutex    sync.Mutex
+	limit    int
+	interval time.Duration
+	windows  map[string]*rateWindow
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+func newRateLimiter(limit int, interval time.Duration) *rateLimiter {
+	return &rateLimiter{
+		limit:    limit,
+		interval: interval,
+		windows:  make(map[string]*rateWindow),
+	}
+}
+
+func (r *rateLimiter) Allow(key string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	// drop finished windows so idle keys do not pile up
+	if len(r.windows) >= rateLimiterPruneSize {
+		for eachKey, eachWindow := range r.windows {
+			if now.Sub(eachWindow.start) >= r.interval {
+				delete(r.windows, eachKey)
+			}
+		}
+	}
+
+	window, exists := r.windows[key]
+	if !exists || now.Sub(window.start) >= r.interval {
+		r.windows[key] = &rateWindow{start: now, count: 1}
+		return true
+	}
+	if window.count >= r.limit {
+		return false
+	}
+	window.count++
+	return true
+}
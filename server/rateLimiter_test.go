@@ -0,0 +1,35 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	limiter := newRateLimiter(2, time.Hour)
+
+	if !limiter.Allow("user1") || !limiter.Allow("user1") {
+		t.Error("events within the limit are not allowed")
+	}
+	if limiter.Allow("user1") {
+		t.Error("event over the limit is allowed")
+	}
+	if !limiter.Allow("user2") {
+		t.Error("limit of one key is applied to another key")
+	}
+}
+
+func TestRateLimiterWindowReset(t *testing.T) {
+	limiter := newRateLimiter(1, time.Millisecond*10)
+
+	if !limiter.Allow("user1") {
+		t.Error("first event is not allowed")
+	}
+	if limiter.Allow("user1") {
+		t.Error("event over the limit is allowed")
+	}
+	time.Sleep(time.Millisecond * 20)
+	if !limiter.Allow("user1") {
+		t.Error("event in a new window is not allowed")
+	}
+}
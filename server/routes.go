@@ -2,53 +2,117 @@ package server
 
 import (
 	"context"
-	"encoding/json"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 
+	"socialite/cache"
 	"socialite/database"
+	"socialite/metrics"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func (s *Server) AddRoutes() {
 	// health routes
 	s.engine.Any("/health", s.Health)
 	s.engine.Any("/liveness", s.Health)
+	// prometheus metrics
+	s.engine.GET("/metrics", gin.WrapH(promhttp.HandlerFor(s.metrics, promhttp.HandlerOpts{})))
 
 	// auth routes
 	authGroup := s.engine.Group("/auth")
 	authGroup.POST("/login", s.Login)
 	authGroup.POST("/register", s.Register)
 
+	// webhook admin routes, secured with the admin token instead
+	webhooksGroup := s.engine.Group("/admin/webhooks", s.AdminMiddleware())
+	webhooksGroup.GET("/", s.GetWebhooks)                                // get webhook subscriptions
+	webhooksGroup.POST("/", s.CreateWebhook)                             // create webhook subscription
+	webhooksGroup.GET("/dead_letters", s.GetWebhookDeadLetters)          // get undelivered events
+	webhooksGroup.DELETE("/:webhook_id", s.DeleteWebhook)                // delete webhook subscription
+	webhooksGroup.GET("/:webhook_id/deliveries", s.GetWebhookDeliveries) // get delivery attempts
+
 	// all routes below are secured with a middleware
 	securedRoutes := s.engine.Group("/")
-	securedRoutes.Use(AuthMiddleware())
+	securedRoutes.Use(s.AuthMiddleware())
+
+	// users routes
+	usersGroup := securedRoutes.Group("/users")
+	usersGroup.GET("/me", s.GetMyProfile)                  // get own profile
+	usersGroup.PATCH("/me", s.UpdateMyProfile)             // update own profile
+	usersGroup.DELETE("/me", s.DeleteMyAccount)            // delete own account
+	usersGroup.GET("/me/export", s.ExportMyData)           // export own data
+	usersGroup.GET("/search", s.SearchUsers)               // search users by name
+	usersGroup.GET("/:user_id", s.GetUserProfile)          // get user profile
+	usersGroup.GET("/:user_id/mutual", s.GetMutualFriends) // get mutual friends with user
 
 	// friends routes
 	friendsGroup := securedRoutes.Group("/friends")
-	friendsGroup.GET("/", s.GetFriends)              // get all friends
-	friendsGroup.DELETE("/:user_id", s.RemoveFriend) // remove friend
+	friendsGroup.GET("/", s.GetFriends)                      // get all friends
+	friendsGroup.GET("/suggestions", s.GetFriendSuggestions) // get friend suggestions
+	friendsGroup.PATCH("/:user_id", s.UpdateFriend)          // update friend nickname and favorite
+	friendsGroup.DELETE("/:user_id", s.RemoveFriend)         // remove friend
+
+	// friend groups routes
+	friendGroupsGroup := friendsGroup.Group("/groups")
+	friendGroupsGroup.GET("/", s.GetFriendGroups)                                    // get friend groups
+	friendGroupsGroup.POST("/", s.CreateFriendGroup)                                 // create friend group
+	friendGroupsGroup.PATCH("/:group_id", s.RenameFriendGroup)                       // rename friend group
+	friendGroupsGroup.DELETE("/:group_id", s.DeleteFriendGroup)                      // delete friend group
+	friendGroupsGroup.PUT("/:group_id/members/:user_id", s.AddFriendToGroup)         // add friend to group
+	friendGroupsGroup.DELETE("/:group_id/members/:user_id", s.RemoveFriendFromGroup) // remove friend from group
 
 	// friend requests group
 	friendRequestsGroup := friendsGroup.Group("/requests")
 	friendRequestsGroup.GET("/", s.GetFriendRequests)                      // get pending friend request
+	friendRequestsGroup.GET("/sent", s.GetSentFriendRequests)              // get sent friend requests
+	friendRequestsGroup.DELETE("/:request_id", s.CancelFriendRequest)      // cancel sent friend request
 	friendRequestsGroup.POST("/user/:user_id", s.SendFriendRequest)        // send friend request
 	friendRequestsGroup.POST("/:request_id/accept", s.AcceptFriendRequest) // accept friend request
 	friendRequestsGroup.POST("/:request_id/reject", s.RejectFriendRequest) // reject friend request
 
 	// party routes
 	partyGroup := securedRoutes.Group("/party")
-	partyGroup.POST("/", s.CreateParty)             // create party
-	partyGroup.GET("/created", s.GetCreatedParties) // get created party
+	partyGroup.POST("/", s.CreateParty)               // create party
+	partyGroup.GET("/", s.GetUserParties)             // get parties with membership status
+	partyGroup.GET("/created", s.GetCreatedParties)   // get created party
+	partyGroup.GET("/invites", s.GetPartyInvitations) // get pending party invitations
 
 	// each party group
 	eachPartyGroup := partyGroup.Group("/:party_id")
-	eachPartyGroup.POST("/invite", s.InviteUserToParty)            // invite party
-	eachPartyGroup.POST("/join", s.JoinParty)                      // join party
-	eachPartyGroup.POST("/leave", s.LeaveParty)                    // leave party
-	eachPartyGroup.DELETE("/user/:user_id", s.RemoveUserFromParty) // remove user from party
+	eachPartyGroup.GET("", s.GetPartyDetails)                           // get party details
+	eachPartyGroup.POST("/invite", s.InviteUserToParty)                 // invite party
+	eachPartyGroup.POST("/join", s.JoinParty)                           // join party
+	eachPartyGroup.POST("/leave", s.LeaveParty)                         // leave party
+	eachPartyGroup.POST("/decline", s.DeclinePartyInvitation)           // decline party invitation
+	eachPartyGroup.GET("/messages", s.GetPartyMessages)                 // get party chat messages
+	eachPartyGroup.DELETE("/user/:user_id", s.RemoveUserFromParty)      // remove user from party
+	eachPartyGroup.DELETE("/invites/:user_id", s.CancelPartyInvitation) // cancel party invitation
+
+	// blocks routes
+	blocksGroup := securedRoutes.Group("/blocks")
+	blocksGroup.GET("/", s.GetBlockedUsers)        // get blocked users
+	blocksGroup.POST("/:user_id", s.BlockUser)     // block user
+	blocksGroup.DELETE("/:user_id", s.UnblockUser) // unblock user
+
+	// direct messages routes
+	messagesGroup := securedRoutes.Group("/messages")
+	messagesGroup.GET("/unread", s.GetUnreadDirectMessages)    // get unread message counts
+	messagesGroup.GET("/:user_id", s.GetDirectMessages)        // get conversation with user
+	messagesGroup.POST("/:user_id", s.SendDirectMessage)       // send message to user
+	messagesGroup.POST("/:user_id/read", s.ReadDirectMessages) // mark messages from user as read
+
+	// notifications routes
+	notificationsGroup := securedRoutes.Group("/notifications")
+	notificationsGroup.GET("/", s.GetNotifications)                           // get notifications with unread count
+	notificationsGroup.POST("/read", s.MarkAllNotificationsRead)              // mark all notifications as read
+	notificationsGroup.POST("/:notification_id/read", s.MarkNotificationRead) // mark notification as read
+
+	// event stream route, for clients that cannot use websockets
+	securedRoutes.GET("/events", s.EventStream)
 
 	// websocket group
 	websocketGroup := securedRoutes.Group("/ws")
@@ -57,15 +121,33 @@ func (s *Server) AddRoutes() {
 }
 
 func (s *Server) AddMiddlewares() {
+	// preflight requests are aborted by the cors middleware, they are traced and counted before that
+	s.engine.Use(TracingMiddleware())
+	s.engine.Use(MetricsMiddleware())
 	s.engine.Use(CORSMiddleware())
 }
 
+// MetricsMiddleware counts and times the requests by route, requests matching no route share one label
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.HttpRequests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		metrics.HttpRequestDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
 func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Content-Type", "application/json")
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Max-Age", "86400")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE, UPDATE")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, PATCH, DELETE, UPDATE")
 		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-Max")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
 
@@ -78,7 +160,7 @@ func CORSMiddleware() gin.HandlerFunc {
 	}
 }
 
-func AuthMiddleware() gin.HandlerFunc {
+func (s *Server) AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// check for auth token in header
 		authToken := c.GetHeader("Authorization")
@@ -89,37 +171,99 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 
 		// create user instance from auth token and add to context
-		userInstance, err := database.NewUser(authToken)
+		userInstance, err := database.NewExistingUser(authToken)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, GeneralResponse{Message: err.Error()})
 			c.Abort()
 			return
 		}
+
+		// sessions of deleted users are revoked, whichever server deleted them
+		isActive, err := s.IsUserActive(c, userInstance.Name)
+		if err != nil {
+			log.Printf("[ERROR] checking if user %s is active : %s", userInstance.Name, err.Error())
+			c.JSON(http.StatusInternalServerError, Err_SomethingWrong)
+			c.Abort()
+			return
+		}
+		if !isActive {
+			c.JSON(http.StatusUnauthorized, Err_UserRevoked)
+			c.Abort()
+			return
+		}
 		c.Set(Header_AuthUserKey, userInstance)
 		c.Next()
 	}
 }
 
 func (s *Server) StartCrons(ctx context.Context) {
-	go s.MonitorOnlineUsers(ctx)
-	go s.UpdateUserFriendsListCron(ctx)
-	go s.UpdatePartyMembersCron(ctx)
+	// the webhook dispatcher outlives the crons, it delivers the events flushed from the outbox on shutdown
+	s.webhookSubscription = s.events.Subscribe(eventsBufferSize)
+	webhooksCtx, stopWebhooks := context.WithCancel(ctx)
+	s.stopWebhooks = stopWebhooks
+	go func() {
+		defer close(s.webhooksDone)
+		s.webhooks.Run(webhooksCtx, s.webhookSubscription)
+	}()
+
+	ctx, s.stopCrons = context.WithCancel(ctx)
+	s.startCron(ctx, s.PublishOutboxEventsCron)
+	s.startCron(ctx, s.PurgePublishedOutboxEventsCron)
+	s.startCron(ctx, s.MonitorOnlineUsers)
+	s.startCron(ctx, s.UpdateUserFriendsListCron)
+	s.startCron(ctx, s.UpdatePartyMembersCron)
+	s.startCron(ctx, s.PurgeExpiredPartyInvitationsCron)
+	s.startCron(ctx, s.CloseRevokedConnectionsCron)
+	if s.userDeletionGracePeriod > 0 {
+		s.startCron(ctx, s.PurgeDeletedUsersCron)
+	}
+}
+
+// startCron runs the cron until its context is done, Shutdown waits for it to return
+func (s *Server) startCron(ctx context.Context, cron func(context.Context)) {
+	s.crons.Add(1)
+	go func() {
+		defer s.crons.Done()
+		cron(ctx)
+	}()
+}
+
+// runCron runs the cron once, timing the run and counting it when it fails
+func runCron(name string, run func() error) error {
+	start := time.Now()
+	err := run()
+	metrics.CronDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.CronFailures.WithLabelValues(name).Inc()
+	}
+	return err
+}
+
+// sleep waits for the duration between two runs of a cron, false once the context is done
+func sleep(ctx context.Context, duration time.Duration) bool {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
 }
 
 func (s *Server) UpdateUserFriendsListCron(ctx context.Context) {
 	log.Printf("[INFO] starting cron for updating user's friends list in cache")
 	// update user friends list in cache
-	err := s.UpdateUserFriendsList(ctx)
+	err := runCron("update_user_friends_list", func() error { return s.UpdateUserFriendsList(ctx) })
 	if err != nil {
 		log.Printf("[ERROR] updating user friends list in cache : %s", err.Error())
 	}
 
 	for {
-		time.Sleep(time.Minute)
-		if ctx.Err() != nil {
+		if !sleep(ctx, time.Minute) {
 			return
 		}
-		err = s.UpdateUserFriendsList(ctx)
+		err = runCron("update_user_friends_list", func() error { return s.UpdateUserFriendsList(ctx) })
 		if err != nil {
 			log.Printf("[ERROR] updating user friends list in cache : %s", err.Error())
 		}
@@ -137,7 +281,7 @@ func (s *Server) UpdateUserFriendsList(ctx context.Context) error {
 		for userName, friendsList := range friendsMap {
 			err = s.cache.PutUserFriendsList(ctx, userName, friendsList)
 			if err != nil {
-				return nil
+				return err
 			}
 		}
 	}
@@ -146,7 +290,13 @@ func (s *Server) UpdateUserFriendsList(ctx context.Context) error {
 
 func (s *Server) MonitorOnlineUsers(ctx context.Context) {
 	log.Printf("[INFO] starting cron for monitoring user's online status")
-	for userName := range s.userOnlineStatus {
+	for {
+		var userName string
+		select {
+		case <-ctx.Done():
+			return
+		case userName = <-s.userOnlineStatus:
+		}
 		// put user as online in cache
 		go s.cache.PutUserOnline(ctx, userName)
 		// handle user's online status
@@ -165,34 +315,38 @@ func (s *Server) HandleUserOnlineStatus(ctx context.Context, userName string) {
 		MsgType:  MessageType_FriendsOnline,
 		UserName: userName,
 	}
-	respJson, err := json.Marshal(resp)
-	if err != nil {
-		log.Printf("[ERROR] marshalling websocket status message : %s", err.Error())
-		return
-	}
+	// presence is not kept for replay, the next heartbeat of the user tells it again
+	event := &cache.UserEvent{Type: MessageType_FriendsOnline, Message: &resp}
 
 	s.rwmutex.RLock()
+	recipients := make([]string, 0, len(friendsList))
 	for _, friendName := range friendsList {
-		userChan, exist := s.userWebsocketChannels[friendName]
-		if !exist || userChan == nil {
+		if !s.isUserConnected(friendName) {
+			continue
+		}
+		// the friend only follows a favorites or group scope
+		if scope := s.userPresenceScopes[friendName]; scope != nil && !scope.friends[userName] {
 			continue
 		}
-		userChan <- respJson
+		recipients = append(recipients, friendName)
 	}
 	s.rwmutex.RUnlock()
+
+	for _, eachRecipient := range recipients {
+		s.deliverToUser(eachRecipient, event)
+	}
 }
 
 func (s *Server) UpdatePartyMembersCron(ctx context.Context) {
-	err := s.UpdatePartyMembers(ctx)
+	err := runCron("update_party_members", func() error { return s.UpdatePartyMembers(ctx) })
 	if err != nil {
 		log.Printf("[ERROR] updating party members list in cache : %s", err.Error())
 	}
 	for {
-		time.Sleep(time.Minute)
-		if ctx.Err() != nil {
+		if !sleep(ctx, time.Minute) {
 			return
 		}
-		err = s.UpdatePartyMembers(ctx)
+		err = runCron("update_party_members", func() error { return s.UpdatePartyMembers(ctx) })
 		if err != nil {
 			log.Printf("[ERROR] updating party members list in cache : %s", err.Error())
 		}
@@ -212,3 +366,87 @@ func (s *Server) UpdatePartyMembers(ctx context.Context) error {
 	}
 	return nil
 }
+
+// PublishOutboxEventsCron moves the events written by database mutations to the bus,
+// the outbox is drained batch by batch and polled again once empty
+func (s *Server) PublishOutboxEventsCron(ctx context.Context) {
+	log.Printf("[INFO] starting cron for publishing outbox events")
+	for {
+		published, err := s.db.PublishOutboxEvents(ctx, database.OutboxBatchSize, s.events.PublishWait)
+		if err != nil {
+			log.Printf("[ERROR] publishing outbox events : %s", err.Error())
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil || published < database.OutboxBatchSize {
+			if !sleep(ctx, outboxPollInterval) {
+				return
+			}
+		}
+	}
+}
+
+func (s *Server) PurgePublishedOutboxEventsCron(ctx context.Context) {
+	log.Printf("[INFO] starting cron for purging published outbox events")
+	for {
+		purged, err := s.db.DeletePublishedOutboxEvents(ctx, time.Now().Add(-outboxRetention))
+		if err != nil {
+			log.Printf("[ERROR] purging published outbox events : %s", err.Error())
+		} else if purged > 0 {
+			log.Print("[INFO] purged published outbox events : ", purged)
+		}
+		if !sleep(ctx, time.Hour) {
+			return
+		}
+	}
+}
+
+func (s *Server) PurgeExpiredPartyInvitationsCron(ctx context.Context) {
+	log.Printf("[INFO] starting cron for purging expired party invitations")
+	for {
+		err := s.PurgeExpiredPartyInvitations(ctx)
+		if err != nil {
+			log.Printf("[ERROR] purging expired party invitations : %s", err.Error())
+		}
+		if !sleep(ctx, time.Minute) {
+			return
+		}
+	}
+}
+
+func (s *Server) PurgeExpiredPartyInvitations(ctx context.Context) error {
+	purged, err := s.db.DeleteExpiredPartyInvitations(ctx, time.Now().Add(-s.partyInviteExpiry))
+	if err != nil {
+		return err
+	}
+	if purged > 0 {
+		log.Print("[INFO] purged expired party invitations : ", purged)
+	}
+	return nil
+}
+
+func (s *Server) PurgeDeletedUsersCron(ctx context.Context) {
+	log.Printf("[INFO] starting cron for purging deleted users")
+	for {
+		err := s.PurgeDeletedUsers(ctx)
+		if err != nil {
+			log.Printf("[ERROR] purging deleted users : %s", err.Error())
+		}
+		if !sleep(ctx, time.Minute) {
+			return
+		}
+	}
+}
+
+// PurgeDeletedUsers hard deletes the users whose grace period has passed
+func (s *Server) PurgeDeletedUsers(ctx context.Context) error {
+	purged, err := s.db.PurgeDeletedUsers(ctx, time.Now().Add(-s.userDeletionGracePeriod))
+	if err != nil {
+		return err
+	}
+	if len(purged) > 0 {
+		log.Print("[INFO] purged deleted users : ", len(purged))
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"socialite/metrics"
+
+	"github.com/gorilla/websocket"
+)
+
+type SlowConsumerPolicy string
+
+const (
+	// SlowConsumerPolicy_DropOldest drops the oldest queued message to make room
+	SlowConsumerPolicy_DropOldest SlowConsumerPolicy = "drop_oldest"
+	// SlowConsumerPolicy_Coalesce replaces the queued presence update about the same user, or drops the oldest message
+	SlowConsumerPolicy_Coalesce SlowConsumerPolicy = "coalesce"
+	// SlowConsumerPolicy_Disconnect closes the connection, the client reconnects and resumes
+	SlowConsumerPolicy_Disconnect SlowConsumerPolicy = "disconnect"
+)
+
+// sendQueue holds the messages waiting for the writer of a connection, pushing never waits on the writer,
+// once size messages are waiting the slow consumer policy applies
+type sendQueue[T any] struct {
+	mutex  sync.Mutex
+	items  []T
+	size   int
+	policy SlowConsumerPolicy
+	// connection type of the metrics
+	kind string
+	// coalesceKey returns the key of presence updates, a newer update replaces a queued one with the same key
+	coalesceKey func(T) string
+	// ready has a value while messages are waiting
+	ready chan struct{}
+	// closed once the disconnect policy applied, later messages are discarded
+	closed bool
+}
+
+func newSendQueue[T any](kind string, size int, policy SlowConsumerPolicy, coalesceKey func(T) string) *sendQueue[T] {
+	return &sendQueue[T]{
+		items:       make([]T, 0, size),
+		size:        size,
+		policy:      policy,
+		kind:        kind,
+		coalesceKey: coalesceKey,
+		ready:       make(chan struct{}, 1),
+	}
+}
+
+// push queues the item, it returns false once, when the queue is full and the connection has to be closed
+func (q *sendQueue[T]) push(item T) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.closed {
+		return true
+	}
+	if len(q.items) >= q.size {
+		if q.policy == SlowConsumerPolicy_Disconnect {
+			q.closed = true
+			metrics.MessagesDropped.WithLabelValues(q.kind, metrics.Drop_Disconnected).Inc()
+			return false
+		}
+		if q.policy == SlowConsumerPolicy_Coalesce {
+			if i := q.coalesceIndex(item); i >= 0 {
+				q.items[i] = item
+				metrics.MessagesDropped.WithLabelValues(q.kind, metrics.Drop_Coalesced).Inc()
+				return true
+			}
+		}
+		var zero T
+		q.items[0] = zero
+		q.items = q.items[1:]
+		metrics.MessagesDropped.WithLabelValues(q.kind, metrics.Drop_Dropped).Inc()
+	}
+	q.items = append(q.items, item)
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// coalesceIndex returns the index of the queued presence update with the same key as the item, or -1
+func (q *sendQueue[T]) coalesceIndex(item T) int {
+	if q.coalesceKey == nil {
+		return -1
+	}
+	key := q.coalesceKey(item)
+	if key == "" {
+		return -1
+	}
+	for i, eachItem := range q.items {
+		if q.coalesceKey(eachItem) == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// popAll takes every waiting item, in the order they were pushed
+func (q *sendQueue[T]) popAll() []T {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	items := q.items
+	q.items = make([]T, 0, q.size)
+	return items
+}
+
+// closeSlowConsumer closes the websocket whose client cannot keep up, it may reconnect and resume later
+func closeSlowConsumer(ws *websocket.Conn) {
+	closeMsg := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too slow to keep up")
+	ws.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+	ws.Close()
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"testing"
+)
+
+func TestSendQueue(t *testing.T) {
+	// strings starting with a dash are presence updates about the rest of the string
+	coalesceKey := func(item string) string {
+		if len(item) > 1 && item[0] == '-' {
+			return item[1:]
+		}
+		return ""
+	}
+
+	testCases := []struct {
+		name       string
+		policy     SlowConsumerPolicy
+		pushed     []string
+		expected   []string
+		disconnect bool
+	}{
+		{
+			name:     "under the size",
+			policy:   SlowConsumerPolicy_Disconnect,
+			pushed:   []string{"a", "b"},
+			expected: []string{"a", "b"},
+		},
+		{
+			name:     "drop oldest",
+			policy:   SlowConsumerPolicy_DropOldest,
+			pushed:   []string{"-user1", "a", "b", "-user1"},
+			expected: []string{"a", "b", "-user1"},
+		},
+		{
+			name:     "coalesce presence",
+			policy:   SlowConsumerPolicy_Coalesce,
+			pushed:   []string{"a", "-user1", "b", "-user1"},
+			expected: []string{"a", "-user1", "b"},
+		},
+		{
+			name:     "coalesce without presence",
+			policy:   SlowConsumerPolicy_Coalesce,
+			pushed:   []string{"a", "-user1", "b", "-user2"},
+			expected: []string{"-user1", "b", "-user2"},
+		},
+		{
+			name:       "disconnect",
+			policy:     SlowConsumerPolicy_Disconnect,
+			pushed:     []string{"a", "b", "c", "d", "e"},
+			expected:   []string{"a", "b", "c"},
+			disconnect: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			queue := newSendQueue("test", 3, testCase.policy, coalesceKey)
+			disconnects := 0
+			for _, eachItem := range testCase.pushed {
+				if !queue.push(eachItem) {
+					disconnects++
+				}
+			}
+			if testCase.disconnect && disconnects != 1 {
+				t.Errorf("disconnected %d times, expected once", disconnects)
+			}
+			if !testCase.disconnect && disconnects != 0 {
+				t.Errorf("disconnected %d times", disconnects)
+			}
+			select {
+			case <-queue.ready:
+			default:
+				t.Error("queue is not ready")
+			}
+			items := queue.popAll()
+			if len(items) != len(testCase.expected) {
+				t.Fatalf("queue has %v, expected %v", items, testCase.expected)
+			}
+			for i := range items {
+				if items[i] != testCase.expected[i] {
+					t.Fatalf("queue has %v, expected %v", items, testCase.expected)
+				}
+			}
+		})
+	}
+}
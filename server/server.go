@@ -6,15 +6,25 @@ import (
 	"log"
 	"net/http"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"socialite/cache"
 	"socialite/cache/state"
+	cachetracing "socialite/cache/tracing"
 	"socialite/config"
 	"socialite/database"
 	"socialite/database/postgres"
+	dbtracing "socialite/database/tracing"
+	"socialite/events"
+	"socialite/metrics"
+	"socialite/telemetry"
+	"socialite/validation"
+	"socialite/webhook"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 type Server struct {
@@ -26,15 +36,56 @@ type Server struct {
 	tlsCertPath string
 	tlsKeyPath  string
 
+	// party settings
+	partyInviteExpiry      time.Duration
+	partyReadyCheckTimeout time.Duration
+	partyChatMaxLength     int
+	partyChatHistorySize   int
+	partyChatLimiter       *rateLimiter
+
+	// user settings
+	userDeletionGracePeriod time.Duration
+	userEventsBufferSize    int
+
+	// websocket settings
+	websocketPingInterval       time.Duration
+	websocketPongTimeout        time.Duration
+	websocketWriteTimeout       time.Duration
+	websocketSendQueueSize      int
+	websocketSlowConsumerPolicy SlowConsumerPolicy
+
 	// connections
 	db       database.Database
 	cache    cache.Cache
 	upgrader websocket.Upgrader
 
+	// events
+	events            *events.Bus
+	webhooks          *webhook.Dispatcher
+	webhookAdminToken string
+
+	// telemetry
+	metrics     *prometheus.Registry
+	stopTracing func(context.Context) error
+
+	// shutdown
+	httpServer          *http.Server
+	draining            atomic.Bool
+	stopCrons           context.CancelFunc
+	crons               sync.WaitGroup
+	webhookSubscription *events.Subscription
+	stopWebhooks        context.CancelFunc
+	webhooksDone        chan struct{}
+
 	// internal variables
 	rwmutex               sync.RWMutex
 	userOnlineStatus      chan string
-	userWebsocketChannels map[string]chan []byte
+	userWebsocketConns    map[string]*statusConnection
+	userEventStreams      map[string]map[*eventStream]bool
+	userPresenceScopes    map[string]*presenceScope
+	partyRwmutex          sync.RWMutex
+	partyWebsocketConns   map[string]map[*partyConnection]bool
+	partyReadyCheckTimers map[string]*time.Timer
 }
 
 func New(ctx context.Context, cfg *config.Config) *Server {
@@ -43,6 +94,14 @@ func New(ctx context.Context, cfg *config.Config) *Server {
 	}
 
 	ginEngine := gin.Default()
+	// handlers pass the gin context on, so it has to carry the span of the request
+	ginEngine.ContextWithFallback = true
+	stopTracing := telemetry.Start(ctx, &cfg.Tracing, cfg.Server.ServiceName)
+
+	err := validation.Configure(&cfg.Validation)
+	if err != nil {
+		log.Fatal("[ERROR] configuring validation rules: ", err.Error())
+	}
 
 	var dbCnn database.Database
 	if cfg.Database.Type == "postgres" {
@@ -57,16 +116,50 @@ func New(ctx context.Context, cfg *config.Config) *Server {
 	} else {
 		log.Fatal("[ERROR] cache type is not supported: ", cfg.Cache.Type)
 	}
+	// traced whatever the backend
+	tracedDb := dbtracing.New(dbCnn)
+	tracedCache := cachetracing.New(cacheConn)
 
-	return &Server{
-		engine:      ginEngine,
+	s := &Server{
+		engine: ginEngine,
+		httpServer: &http.Server{
+			Addr:    fmt.Sprintf(":%d", cfg.Server.Port),
+			Handler: ginEngine,
+		},
 		port:        cfg.Server.Port,
 		name:        cfg.Server.ServiceName,
 		tls:         cfg.Server.TLS,
 		tlsCertPath: cfg.Server.CertPath,
 		tlsKeyPath:  cfg.Server.KeyPath,
-		db:          dbCnn,
-		cache:       cacheConn,
+
+		partyInviteExpiry:      time.Second * time.Duration(cfg.Party.InviteExpiry),
+		partyReadyCheckTimeout: time.Second * time.Duration(cfg.Party.ReadyCheckTimeout),
+		partyChatMaxLength:     cfg.Party.ChatMaxLength,
+		partyChatHistorySize:   cfg.Party.ChatHistorySize,
+		partyChatLimiter: newRateLimiter(
+			cfg.Party.ChatRateLimit,
+			time.Second*time.Duration(cfg.Party.ChatRateInterval),
+		),
+
+		userDeletionGracePeriod: time.Second * time.Duration(cfg.User.DeletionGracePeriod),
+		userEventsBufferSize:    cfg.Cache.UserEventsBufferSize,
+
+		websocketPingInterval:       time.Second * time.Duration(cfg.Websocket.PingInterval),
+		websocketPongTimeout:        time.Second * time.Duration(cfg.Websocket.PongTimeout),
+		websocketWriteTimeout:       time.Second * time.Duration(cfg.Websocket.WriteTimeout),
+		websocketSendQueueSize:      cfg.Websocket.SendQueueSize,
+		websocketSlowConsumerPolicy: SlowConsumerPolicy(cfg.Websocket.SlowConsumerPolicy),
+
+		db:    tracedDb,
+		cache: tracedCache,
+
+		events:            events.NewBus(),
+		webhooks:          webhook.New(tracedDb, &cfg.Webhook),
+		webhookAdminToken: cfg.Webhook.AdminToken,
+		webhooksDone:      make(chan struct{}),
+
+		stopTracing: stopTracing,
+
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
@@ -76,16 +169,37 @@ func New(ctx context.Context, cfg *config.Config) *Server {
 		},
 		rwmutex:               sync.RWMutex{},
 		userOnlineStatus:      make(chan string, 1_000),
-		userWebsocketChannels: make(map[string]chan []byte, 1_000),
+		userWebsocketConns:    make(map[string]*statusConnection, 1_000),
+		userEventStreams:      make(map[string]map[*eventStream]bool, 1_000),
+		userPresenceScopes:    make(map[string]*presenceScope, 1_000),
+		partyRwmutex:          sync.RWMutex{},
+		partyWebsocketConns:   make(map[string]map[*partyConnection]bool, 1_000),
+		partyReadyCheckTimers: make(map[string]*time.Timer),
 	}
+
+	// the pool and cache stats are served when the backends keep them
+	serverCollectors := []prometheus.Collector{metrics.NewOnlineUsersGauge(s.countConnectedUsers)}
+	if pool, ok := dbCnn.(metrics.PoolStater); ok {
+		serverCollectors = append(serverCollectors, metrics.NewPoolCollector(pool))
+	}
+	if cacheStats, ok := cacheConn.(metrics.CacheStater); ok {
+		serverCollectors = append(serverCollectors, metrics.NewCacheCollector(cacheStats))
+	}
+	s.metrics = metrics.NewRegistry(serverCollectors...)
+	return s
 }
 
 func (s *Server) Start() error {
 	log.Printf("[INFO] starting server for %s on port %d with tls %t", s.name, s.port, s.tls)
-	// start the server
-	port := fmt.Sprintf(":%d", s.port)
+	// start the server, it returns once shut down
+	var err error
 	if s.tls {
-		return s.engine.RunTLS(port, s.tlsCertPath, s.tlsKeyPath)
+		err = s.httpServer.ListenAndServeTLS(s.tlsCertPath, s.tlsKeyPath)
+	} else {
+		err = s.httpServer.ListenAndServe()
+	}
+	if err == http.ErrServerClosed {
+		return nil
 	}
-	return s.engine.Run(port)
+	return err
 }
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"socialite/database"
+
+	"github.com/gorilla/websocket"
+)
+
+// Shutdown drains the server: it stops accepting requests, tells every websocket and event stream to reconnect,
+// waits for the requests in flight and the crons, flushes the outbox to the webhooks, then closes the database and cache
+// and exports the spans left.
+// The steps still waiting when the context is done are given up on
+func (s *Server) Shutdown(ctx context.Context) error {
+	log.Printf("[INFO] draining server for %s", s.name)
+	s.draining.Store(true)
+
+	// the listener closes right away, the hijacked websockets are not waited for so they are closed here
+	httpShutdown := make(chan error, 1)
+	go func() {
+		httpShutdown <- s.httpServer.Shutdown(ctx)
+	}()
+	s.CloseAllConnections()
+	var errs []error
+	err := <-httpShutdown
+	if err != nil {
+		errs = append(errs, fmt.Errorf("shutting down http server : %s", err.Error()))
+	}
+
+	if s.stopCrons != nil {
+		s.stopCrons()
+		err = waitContext(ctx, s.crons.Wait)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("waiting for crons : %s", err.Error()))
+		}
+
+		// events committed after the last run of the outbox cron are delivered before leaving
+		err = s.FlushOutboxEvents(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("flushing outbox events : %s", err.Error()))
+		}
+		s.webhookSubscription.Close()
+		err = waitContext(ctx, func() {
+			<-s.webhooksDone
+			s.webhooks.Wait()
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("waiting for webhook deliveries : %s", err.Error()))
+		}
+		// the deliveries still retrying give up and keep their events as dead letters before the database closes
+		s.stopWebhooks()
+		<-s.webhooksDone
+		s.webhooks.Wait()
+	}
+
+	s.db.Close()
+	s.cache.Close()
+	// the spans of the shutdown are exported too
+	if s.stopTracing != nil {
+		err = s.stopTracing(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("stopping tracing : %s", err.Error()))
+		}
+	}
+	log.Printf("[INFO] server for %s is drained", s.name)
+	return errors.Join(errs...)
+}
+
+// CloseAllConnections closes every status and party websocket with a going away code and the reconnect hint,
+// the event streams end with a reconnect event
+func (s *Server) CloseAllConnections() {
+	closeMsg := websocket.FormatCloseMessage(websocket.CloseGoingAway, MessageType_Reconnect)
+
+	conns := make([]*websocket.Conn, 0)
+	s.rwmutex.RLock()
+	for _, eachConn := range s.userWebsocketConns {
+		conns = append(conns, eachConn.ws)
+	}
+	streams := make([]*eventStream, 0)
+	for _, eachUserStreams := range s.userEventStreams {
+		for eachStream := range eachUserStreams {
+			streams = append(streams, eachStream)
+		}
+	}
+	s.rwmutex.RUnlock()
+	s.partyRwmutex.RLock()
+	for _, eachPartyConns := range s.partyWebsocketConns {
+		for eachConn := range eachPartyConns {
+			conns = append(conns, eachConn.ws)
+		}
+	}
+	s.partyRwmutex.RUnlock()
+
+	log.Printf("[INFO] closing %d websockets and %d event streams", len(conns), len(streams))
+	for _, eachStream := range streams {
+		eachStream.close()
+	}
+	// clients not reading would hold the others back for the write timeout
+	var wg sync.WaitGroup
+	for _, eachConn := range conns {
+		wg.Add(1)
+		go func(conn *websocket.Conn) {
+			defer wg.Done()
+			conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+			conn.Close()
+		}(eachConn)
+	}
+	wg.Wait()
+}
+
+// FlushOutboxEvents publishes the events left in the outbox, batch by batch until it is empty
+func (s *Server) FlushOutboxEvents(ctx context.Context) error {
+	for {
+		published, err := s.db.PublishOutboxEvents(ctx, database.OutboxBatchSize, s.events.PublishWait)
+		if err != nil {
+			return err
+		}
+		if published < database.OutboxBatchSize {
+			return nil
+		}
+	}
+}
+
+// waitContext waits for wait to return, or for the context to be done
+func waitContext(ctx context.Context, wait func()) error {
+	done := make(chan struct{})
+	go func() {
+		wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"socialite/cache/state"
+	"socialite/config"
+	"socialite/database"
+	"socialite/events"
+	"socialite/webhook"
+
+	"github.com/gorilla/websocket"
+)
+
+// shutdownDatabase serves the methods used by a shutdown, the rest are not implemented
+type shutdownDatabase struct {
+	database.Database
+	outbox []*events.Event
+	closed bool
+}
+
+func (db *shutdownDatabase) PublishOutboxEvents(ctx context.Context, limit int, publish func(context.Context, *events.Event) error) (int, error) {
+	batch := db.outbox[:min(limit, len(db.outbox))]
+	for _, eachEvent := range batch {
+		err := publish(ctx, eachEvent)
+		if err != nil {
+			return 0, err
+		}
+	}
+	db.outbox = db.outbox[len(batch):]
+	return len(batch), nil
+}
+
+func (db *shutdownDatabase) GetWebhookSubscriptionsForEvent(ctx context.Context, eventType string) ([]*database.WebhookSubscription, error) {
+	return nil, nil
+}
+
+func (db *shutdownDatabase) Close() {
+	db.closed = true
+}
+
+func TestShutdown(t *testing.T) {
+	db := &shutdownDatabase{}
+	for i := 0; i < database.OutboxBatchSize+1; i++ {
+		db.outbox = append(db.outbox, events.New(events.Type_PartyCreated, "user1"))
+	}
+	s := &Server{
+		httpServer:         &http.Server{},
+		db:                 db,
+		cache:              state.New(context.Background(), &config.CacheConfig{Type: "state", UserEventsExpiry: 300, UserEventsBufferSize: 10}),
+		events:             events.NewBus(),
+		webhooks:           webhook.New(db, &config.WebhookConfig{Timeout: 1, MaxAttempts: 1, RetryBackoff: 1, RetryMaxBackoff: 1}),
+		webhooksDone:       make(chan struct{}),
+		userWebsocketConns: make(map[string]*statusConnection),
+		userEventStreams:   make(map[string]map[*eventStream]bool),
+	}
+	// started like StartCrons does, without the crons
+	_, s.stopCrons = context.WithCancel(context.Background())
+	s.webhookSubscription = s.events.Subscribe(eventsBufferSize)
+	webhooksCtx, stopWebhooks := context.WithCancel(context.Background())
+	s.stopWebhooks = stopWebhooks
+	go func() {
+		defer close(s.webhooksDone)
+		s.webhooks.Run(webhooksCtx, s.webhookSubscription)
+	}()
+	received := s.events.Subscribe(database.OutboxBatchSize * 2)
+
+	// a client connected to the status websocket is told to reconnect
+	closeErrs := make(chan error, 1)
+	wsServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		s.rwmutex.Lock()
+		s.userWebsocketConns["user1"] = newStatusConnection(conn, 10, SlowConsumerPolicy_Coalesce)
+		s.rwmutex.Unlock()
+	}))
+	defer wsServer.Close()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(wsServer.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	go func() {
+		_, _, err := conn.ReadMessage()
+		closeErrs <- err
+	}()
+	for {
+		s.rwmutex.RLock()
+		registered := s.userWebsocketConns["user1"] != nil
+		s.rwmutex.RUnlock()
+		if registered {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancelCtx()
+	err = s.Shutdown(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	closeErr := <-closeErrs
+	if !websocket.IsCloseError(closeErr, websocket.CloseGoingAway) || !strings.Contains(closeErr.Error(), MessageType_Reconnect) {
+		t.Errorf("websocket is closed with %v, expected going away and the reconnect hint", closeErr)
+	}
+	if len(db.outbox) != 0 || len(received.Events) != database.OutboxBatchSize+1 {
+		t.Errorf("%d events left in the outbox and %d published", len(db.outbox), len(received.Events))
+	}
+	if !db.closed {
+		t.Error("database is not closed")
+	}
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"socialite/cache"
+	"socialite/metrics"
+
+	"github.com/gorilla/websocket"
+)
+
+// statusResume lets the writer of a status websocket resume without writing a message twice,
+// it remembers the messages written before the resume message, which the replay skips,
+// and the last one replayed, which the later deliveries of replayed messages are skipped by
+type statusResume struct {
+	done         bool
+	maxWritten   int
+	writtenSeqs  map[int64]bool
+	replayedUpTo int64
+}
+
+func newStatusResume(maxWritten int) *statusResume {
+	return &statusResume{
+		maxWritten:  maxWritten,
+		writtenSeqs: make(map[int64]bool),
+	}
+}
+
+// replayed tells if the event was already written by the replay
+func (r *statusResume) replayed(event *cache.UserEvent) bool {
+	return event.Seq != 0 && event.Seq <= r.replayedUpTo
+}
+
+// written remembers the event until the resume, the replay never goes back further than the buffer size
+func (r *statusResume) written(event *cache.UserEvent) {
+	if r.done || event.Seq == 0 || len(r.writtenSeqs) >= r.maxWritten {
+		return
+	}
+	r.writtenSeqs[event.Seq] = true
+}
+
+// ResumeStatusWebsocket writes the messages kept for the user after seq, preceded by a resync message when some of them are gone,
+// then a resume message with the last sequence number the client has. It returns false once the connection is broken
+func (s *Server) ResumeStatusWebsocket(ctx context.Context, conn *websocket.Conn, userName string, seq int64, resume *statusResume) bool {
+	if resume.done {
+		return writeStatusMessage(conn, &WebsocketStatusOutgoingMessage{MsgType: MessageType_Error, Message: Err_AlreadyResumed.Message})
+	}
+	resume.done = true
+
+	missed, complete, err := s.cache.GetUserEventsAfter(ctx, userName, seq)
+	if err != nil {
+		log.Printf("[ERROR] getting events of user %s from cache : %s", userName, err.Error())
+	}
+	// the client has to fetch its state again, the messages it missed are gone
+	if err != nil || !complete {
+		if !writeStatusMessage(conn, &WebsocketStatusOutgoingMessage{MsgType: MessageType_Resync}) {
+			return false
+		}
+	}
+
+	lastSeq := seq
+	for _, eachEvent := range missed {
+		if !resume.writtenSeqs[eachEvent.Seq] && !writeStatusEvent(conn, eachEvent) {
+			return false
+		}
+		lastSeq = max(lastSeq, eachEvent.Seq)
+	}
+	resume.replayedUpTo = lastSeq
+	resume.writtenSeqs = nil
+
+	return writeStatusMessage(conn, &WebsocketStatusOutgoingMessage{MsgType: MessageType_Resume, Seq: lastSeq})
+}
+
+// writeStatusMessage writes a message that is not kept for replay, false once the connection is broken
+func writeStatusMessage(conn *websocket.Conn, msg *WebsocketStatusOutgoingMessage) bool {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("[ERROR] marshaling websocket message : %s", err.Error())
+		return true
+	}
+	err = conn.WriteMessage(websocket.TextMessage, data)
+	if err != nil {
+		log.Printf("[ERROR] writing message to websocket : %s", err.Error())
+		return false
+	}
+	metrics.MessagesSent.WithLabelValues(metrics.Connection_Status).Inc()
+	return true
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"socialite/cache"
+	"socialite/cache/state"
+	"socialite/config"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestResumeStatusWebsocket(t *testing.T) {
+	s := &Server{
+		cache: state.New(context.Background(), &config.CacheConfig{Type: "state", UserEventsExpiry: 300, UserEventsBufferSize: 10}),
+	}
+	events := make([]*cache.UserEvent, 0, 3)
+	for i := 0; i < 3; i++ {
+		event := &cache.UserEvent{Type: MessageType_Notification, Message: &WebsocketStatusOutgoingMessage{MsgType: MessageType_Notification}}
+		s.cache.PutUserEvent(context.Background(), "user1", event)
+		events = append(events, event)
+	}
+
+	// the second message was written live before the client resumed after the first one
+	resume := newStatusResume(10)
+	resume.written(events[1])
+	received := make(chan []*WebsocketStatusOutgoingMessage, 1)
+	upgrader := websocket.Upgrader{}
+	wsServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		s.ResumeStatusWebsocket(context.Background(), conn, "user1", 1, resume)
+		s.ResumeStatusWebsocket(context.Background(), conn, "user1", 1, resume)
+	}))
+	defer wsServer.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(wsServer.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	go func() {
+		msgs := make([]*WebsocketStatusOutgoingMessage, 0)
+		for len(msgs) < 3 {
+			msg := &WebsocketStatusOutgoingMessage{}
+			if err := conn.ReadJSON(msg); err != nil {
+				break
+			}
+			msgs = append(msgs, msg)
+		}
+		received <- msgs
+	}()
+
+	msgs := <-received
+	if len(msgs) != 3 {
+		t.Fatalf("received %d messages, expected 3", len(msgs))
+	}
+	if msgs[0].MsgType != MessageType_Notification || msgs[0].Seq != 3 {
+		t.Errorf("replayed %s %d, expected only the third message", msgs[0].MsgType, msgs[0].Seq)
+	}
+	if msgs[1].MsgType != MessageType_Resume || msgs[1].Seq != 3 {
+		t.Errorf("resumed at %s %d, expected resume 3", msgs[1].MsgType, msgs[1].Seq)
+	}
+	if msgs[2].MsgType != MessageType_Error {
+		t.Errorf("second resume is %s, expected an error", msgs[2].MsgType)
+	}
+	if !resume.replayed(events[2]) {
+		t.Error("live delivery of a replayed message is not skipped")
+	}
+}
+
+func TestMarshalUserEvent(t *testing.T) {
+	msg := &WebsocketStatusOutgoingMessage{MsgType: MessageType_DirectMessage, UserName: "user2"}
+	data, err := marshalUserEvent(&cache.UserEvent{Seq: 4, Message: msg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := &WebsocketStatusOutgoingMessage{}
+	json.Unmarshal(data, decoded)
+	if decoded.Seq != 4 || decoded.UserName != "user2" {
+		t.Errorf("marshaled %s", data)
+	}
+	if msg.Seq != 0 {
+		t.Error("the kept message is changed")
+	}
+}
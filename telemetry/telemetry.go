@@ -0,0 +1,58 @@
+package telemetry
+
+import (
+	"context"
+	"log"
+
+	"socialite/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+const (
+	Exporter_None   = "none"
+	Exporter_Stdout = "stdout"
+	Exporter_Otlp   = "otlp"
+)
+
+// Start sets the global tracer provider exporting the spans of the service, and the w3c trace context propagator
+// so traces continue from the traceparent header of callers. The returned function exports the spans left and stops
+func Start(ctx context.Context, cfg *config.TracingConfig, serviceName string) func(context.Context) error {
+	if cfg == nil {
+		log.Fatal("[ERROR] telemetry.Start: config is nil")
+	}
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case Exporter_None:
+		// the global tracer provider does nothing until set
+		return func(context.Context) error { return nil }
+	case Exporter_Stdout:
+		exporter, err = stdouttrace.New()
+	case Exporter_Otlp:
+		exporter, err = otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.OtlpEndpoint))
+	default:
+		log.Fatal("[ERROR] telemetry.Start: tracing exporter is not supported: ", cfg.Exporter)
+	}
+	if err != nil {
+		log.Fatal("[ERROR] telemetry.Start: creating tracing exporter: ", err.Error())
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName))),
+		// callers that sampled a trace get it continued whatever the ratio
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	log.Printf("[INFO] exporting traces to %s", cfg.Exporter)
+	return provider.Shutdown
+}
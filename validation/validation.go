@@ -0,0 +1,224 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"socialite/config"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	Err_NameEmpty      = errors.New("name is empty")
+	Err_NameCharacters = errors.New("name has characters which are not allowed")
+	Err_NameScripts    = errors.New("name mixes letters from different scripts")
+	Err_NameConfusable = errors.New("name can be confused with a latin name")
+	Err_NameReserved   = errors.New("name is reserved")
+)
+
+// Rules are the checks applied to user and party names
+type Rules struct {
+	minLength    int
+	maxLength    int
+	allowedChars *regexp.Regexp
+	// reserved names are keyed by their skeleton, so look-alikes are reserved too
+	reserved map[string]bool
+}
+
+// default rules are used until Configure is called, e.g. in tests
+var (
+	rulesMutex   sync.RWMutex
+	defaultRules = mustNewRules(&config.ValidationConfig{
+		NameMinLength:    1,
+		NameMaxLength:    64,
+		NameAllowedChars: "a-z0-9_-",
+	})
+)
+
+func NewRules(cfg *config.ValidationConfig) (*Rules, error) {
+	if cfg == nil {
+		return nil, errors.New("validation config is nil")
+	}
+	if cfg.NameMinLength <= 0 || cfg.NameMaxLength < cfg.NameMinLength {
+		return nil, errors.New("name length limits are invalid")
+	}
+	allowedChars, err := regexp.Compile("^[" + cfg.NameAllowedChars + "]+$")
+	if err != nil {
+		return nil, fmt.Errorf("compiling allowed characters: %s", err.Error())
+	}
+	rules := &Rules{
+		minLength:    cfg.NameMinLength,
+		maxLength:    cfg.NameMaxLength,
+		allowedChars: allowedChars,
+		reserved:     make(map[string]bool, len(cfg.ReservedNames)),
+	}
+	for _, eachName := range cfg.ReservedNames {
+		eachName = normalize(eachName)
+		if eachName != "" {
+			rules.reserved[Skeleton(eachName)] = true
+		}
+	}
+	return rules, nil
+}
+
+func mustNewRules(cfg *config.ValidationConfig) *Rules {
+	rules, err := NewRules(cfg)
+	if err != nil {
+		panic(err)
+	}
+	return rules
+}
+
+// Configure replaces the rules used by the package level functions
+func Configure(cfg *config.ValidationConfig) error {
+	rules, err := NewRules(cfg)
+	if err != nil {
+		return err
+	}
+	rulesMutex.Lock()
+	defer rulesMutex.Unlock()
+	defaultRules = rules
+	return nil
+}
+
+func currentRules() *Rules {
+	rulesMutex.RLock()
+	defer rulesMutex.RUnlock()
+	return defaultRules
+}
+
+// Name normalizes an existing name, e.g. from a path param or the auth token. Names created before the rules
+// changed must still be found, so only the names no route can carry are rejected
+func Name(name string) (string, error) {
+	return currentRules().Name(name)
+}
+
+// NewName normalizes the name of a user or party which is being created and checks it against every rule
+func NewName(name string) (string, error) {
+	return currentRules().NewName(name)
+}
+
+func (r *Rules) Name(name string) (string, error) {
+	name = normalize(name)
+	if name == "" {
+		return "", Err_NameEmpty
+	}
+	if name == "." || name == ".." || strings.ContainsAny(name, "/\\?#%") {
+		return "", Err_NameCharacters
+	}
+	for _, eachRune := range name {
+		if unicode.IsControl(eachRune) {
+			return "", Err_NameCharacters
+		}
+	}
+	return name, nil
+}
+
+func (r *Rules) NewName(name string) (string, error) {
+	name = normalize(name)
+	if name == "" {
+		return "", Err_NameEmpty
+	}
+	length := utf8.RuneCountInString(name)
+	if length < r.minLength || length > r.maxLength {
+		return "", fmt.Errorf("name must be between %d and %d characters", r.minLength, r.maxLength)
+	}
+	if !r.allowedChars.MatchString(name) {
+		return "", Err_NameCharacters
+	}
+	if mixesScripts(name) {
+		return "", Err_NameScripts
+	}
+	if isConfusable(name) {
+		return "", Err_NameConfusable
+	}
+	if r.reserved[Skeleton(name)] {
+		return "", Err_NameReserved
+	}
+	return name, nil
+}
+
+// normalize folds compatibility forms, e.g. full width letters, and lowercases the name
+func normalize(name string) string {
+	return strings.ToLower(norm.NFKC.String(strings.TrimSpace(name)))
+}
+
+// scripts which are checked for mixing, other letters are not restricted
+var scripts = map[string]*unicode.RangeTable{
+	"Latin":    unicode.Latin,
+	"Cyrillic": unicode.Cyrillic,
+	"Greek":    unicode.Greek,
+	"Armenian": unicode.Armenian,
+	"Hebrew":   unicode.Hebrew,
+	"Arabic":   unicode.Arabic,
+	"Cherokee": unicode.Cherokee,
+}
+
+// mixesScripts tells if the letters of the name come from more than one script
+func mixesScripts(name string) bool {
+	found := ""
+	for _, eachRune := range name {
+		if !unicode.IsLetter(eachRune) {
+			continue
+		}
+		for scriptName, script := range scripts {
+			if !unicode.Is(script, eachRune) {
+				continue
+			}
+			if found != "" && found != scriptName {
+				return true
+			}
+			found = scriptName
+		}
+	}
+	return false
+}
+
+// isConfusable tells if a non latin name looks entirely like a latin one
+func isConfusable(name string) bool {
+	for _, eachRune := range name {
+		if eachRune > unicode.MaxASCII {
+			skeleton := Skeleton(name)
+			for _, eachSkeletonRune := range skeleton {
+				if eachSkeletonRune > unicode.MaxASCII {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// confusables maps characters to the latin character they look like
+var confusables = map[rune]rune{
+	// digits
+	'0': 'o', '1': 'l',
+	// cyrillic
+	'а': 'a', 'с': 'c', 'ԁ': 'd', 'е': 'e', 'һ': 'h', 'і': 'i', 'ј': 'j', 'к': 'k', 'ӏ': 'l',
+	'о': 'o', 'р': 'p', 'ԛ': 'q', 'ѕ': 's', 'у': 'y', 'ԝ': 'w', 'х': 'x', 'ү': 'y',
+	// greek
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p',
+	'τ': 't', 'υ': 'u', 'χ': 'x', 'ω': 'w',
+	// latin look-alikes
+	'ı': 'i', 'ɡ': 'g', 'ɑ': 'a',
+}
+
+// Skeleton maps the confusable characters of a normalized name to latin ones,
+// names with the same skeleton look the same
+func Skeleton(name string) string {
+	var skeleton strings.Builder
+	for _, eachRune := range name {
+		if mapped, exists := confusables[eachRune]; exists {
+			eachRune = mapped
+		}
+		skeleton.WriteRune(eachRune)
+	}
+	return skeleton.String()
+}
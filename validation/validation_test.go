@@ -0,0 +1,94 @@
+package validation_test
+
+import (
+	"strings"
+	"testing"
+
+	"socialite/config"
+	"socialite/validation"
+)
+
+var rules *validation.Rules
+
+func init() {
+	var err error
+	rules, err = validation.NewRules(&config.ValidationConfig{
+		NameMinLength:    3,
+		NameMaxLength:    16,
+		NameAllowedChars: `\p{L}0-9_-`,
+		ReservedNames:    []string{"admin", "System", "root"},
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func TestNameNormalization(t *testing.T) {
+	names := map[string]string{
+		"User1":       "user1",
+		"  user_1  ":  "user_1",
+		"ｕｓｅｒ１":       "user1",
+		"josé":        "josé",
+		"παίκτης":     "παίκτης",
+		"player-one_": "player-one_",
+	}
+	for name, expected := range names {
+		normalized, err := rules.Name(name)
+		if err != nil {
+			t.Errorf("%q is rejected : %s", name, err.Error())
+			continue
+		}
+		if normalized != expected {
+			t.Errorf("%q is normalized to %q, expected %q", name, normalized, expected)
+		}
+	}
+}
+
+func TestNameRejected(t *testing.T) {
+	names := map[string]string{
+		"empty":          "",
+		"too short":      "ab",
+		"too long":       strings.Repeat("a", 17),
+		"slash":          "user/1",
+		"space":          "user 1",
+		"mixed scripts":  "pаypal",
+		"confusable":     "раура",
+		"control":        "user\x00",
+		"path traversal": "..",
+	}
+	for reason, name := range names {
+		if _, err := rules.NewName(name); err == nil {
+			t.Errorf("%s name %q is accepted", reason, name)
+		}
+	}
+}
+
+func TestNameLookup(t *testing.T) {
+	// names created before the rules are still found
+	for _, name := range []string{"jo", "john.doe", "john doe", "pаypal", strings.Repeat("a", 17)} {
+		if _, err := rules.Name(name); err != nil {
+			t.Errorf("looking up %q is rejected : %s", name, err.Error())
+		}
+	}
+	for _, name := range []string{"", "user/1", "..", "user\x00", "user?1"} {
+		if _, err := rules.Name(name); err == nil {
+			t.Errorf("looking up %q is accepted", name)
+		}
+	}
+}
+
+func TestNewNameReserved(t *testing.T) {
+	for _, name := range []string{"admin", "ADMIN", "system", "аdmin", "sуstem", "r00t"} {
+		if _, err := rules.NewName(name); err == nil {
+			t.Errorf("reserved name %q is accepted", name)
+		}
+	}
+
+	// existing reserved names can still be looked up
+	if _, err := rules.Name("admin"); err != nil {
+		t.Errorf("looking up reserved name is rejected : %s", err.Error())
+	}
+	if _, err := rules.NewName("administrator"); err != nil {
+		t.Errorf("name containing a reserved name is rejected : %s", err.Error())
+	}
+}
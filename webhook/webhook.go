@@ -0,0 +1,233 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"socialite/config"
+	"socialite/database"
+	"socialite/events"
+)
+
+const (
+	Header_Event     = "X-Socialite-Event"
+	Header_Delivery  = "X-Socialite-Delivery"
+	Header_Timestamp = "X-Socialite-Timestamp"
+	Header_Signature = "X-Socialite-Signature"
+)
+
+// Store is the part of database.Database the dispatcher needs
+type Store interface {
+	GetWebhookSubscriptionsForEvent(ctx context.Context, eventType string) ([]*database.WebhookSubscription, error)
+	PutWebhookDelivery(ctx context.Context, delivery *database.WebhookDelivery) error
+	IsWebhookDelivered(ctx context.Context, subscriptionId int32, eventId string) (bool, error)
+	PutWebhookDeadLetter(ctx context.Context, deadLetter *database.WebhookDeadLetter) error
+}
+
+// Dispatcher posts the events of the bus to the subscriptions of their type,
+// retrying failed posts with exponential backoff before giving up on them as dead letters
+type Dispatcher struct {
+	store       Store
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	deliveries  sync.WaitGroup
+}
+
+func New(store Store, cfg *config.WebhookConfig) *Dispatcher {
+	return &Dispatcher{
+		store:       store,
+		client:      &http.Client{Timeout: time.Second * time.Duration(cfg.Timeout)},
+		maxAttempts: cfg.MaxAttempts,
+		backoff:     time.Second * time.Duration(cfg.RetryBackoff),
+		maxBackoff:  time.Second * time.Duration(cfg.RetryMaxBackoff),
+	}
+}
+
+// Run dispatches the events of the subscription until the context is done or the subscription is closed
+func (d *Dispatcher) Run(ctx context.Context, subscription *events.Subscription) {
+	log.Printf("[INFO] starting webhook dispatcher")
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-subscription.Events:
+			if !ok {
+				return
+			}
+			d.Dispatch(ctx, event)
+		}
+	}
+}
+
+// Dispatch starts delivering the event to each of its subscriptions, without waiting for them
+func (d *Dispatcher) Dispatch(ctx context.Context, event *events.Event) {
+	subscriptions, err := d.store.GetWebhookSubscriptionsForEvent(ctx, string(event.Type))
+	if err != nil {
+		log.Printf("[ERROR] webhook.Dispatch: getting subscriptions for %s: %s", event.Type, err.Error())
+		return
+	}
+	if len(subscriptions) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("[ERROR] webhook.Dispatch: marshaling event %s: %s", event.Id, err.Error())
+		return
+	}
+	for _, eachSubscription := range subscriptions {
+		d.deliveries.Add(1)
+		go func(subscription *database.WebhookSubscription) {
+			defer d.deliveries.Done()
+			d.Deliver(ctx, subscription, event, payload)
+		}(eachSubscription)
+	}
+}
+
+// Wait blocks until the deliveries in progress are done
+func (d *Dispatcher) Wait() {
+	d.deliveries.Wait()
+}
+
+// Deliver posts the payload until it is accepted, every attempt is logged
+// and the payload becomes a dead letter once the attempts run out.
+// Events are published at least once, so the ones the subscription already accepted are skipped
+func (d *Dispatcher) Deliver(ctx context.Context, subscription *database.WebhookSubscription, event *events.Event, payload []byte) bool {
+	delivered, err := d.store.IsWebhookDelivered(ctx, subscription.Id, event.Id)
+	if err != nil {
+		log.Printf("[ERROR] webhook.Deliver: checking delivery of event %s to subscription %d: %s", event.Id, subscription.Id, err.Error())
+	}
+	if delivered {
+		log.Printf("[INFO] webhook.Deliver: event %s is already delivered to subscription %d", event.Id, subscription.Id)
+		return true
+	}
+
+	// the attempts are logged even when the dispatcher is stopped, so no event is lost on shutdown
+	storeCtx := context.WithoutCancel(ctx)
+	var lastErr string
+	attempt := 1
+attempts:
+	for ; attempt <= d.maxAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				log.Printf("[ERROR] webhook.Deliver: stopped retrying event %s to subscription %d: %s", event.Id, subscription.Id, ctx.Err().Error())
+				attempt--
+				break attempts
+			case <-time.After(d.retryDelay(attempt)):
+			}
+		}
+
+		start := time.Now()
+		statusCode, err := d.post(ctx, subscription, event, payload)
+		delivery := &database.WebhookDelivery{
+			SubscriptionId: subscription.Id,
+			EventId:        event.Id,
+			EventType:      string(event.Type),
+			Attempt:        attempt,
+			StatusCode:     statusCode,
+			DurationMs:     time.Since(start).Milliseconds(),
+			CreatedAt:      start,
+		}
+		if err != nil {
+			delivery.Error = err.Error()
+		}
+		if putErr := d.store.PutWebhookDelivery(storeCtx, delivery); putErr != nil && putErr != database.Err_DuplicatePrimaryKey {
+			log.Printf("[ERROR] webhook.Deliver: putting delivery: %s", putErr.Error())
+		}
+
+		if err == nil {
+			return true
+		}
+		lastErr = err.Error()
+		if !retryable(statusCode) {
+			break attempts
+		}
+	}
+	attempt = min(attempt, d.maxAttempts)
+
+	deadLetter := &database.WebhookDeadLetter{
+		SubscriptionId: subscription.Id,
+		EventId:        event.Id,
+		EventType:      string(event.Type),
+		Payload:        payload,
+		Attempts:       attempt,
+		Error:          lastErr,
+		CreatedAt:      time.Now(),
+	}
+	if err := d.store.PutWebhookDeadLetter(storeCtx, deadLetter); err != nil {
+		log.Printf("[ERROR] webhook.Deliver: putting dead letter: %s", err.Error())
+	}
+	return false
+}
+
+// post sends one signed request, the status code is zero when there was no response
+func (d *Dispatcher) post(ctx context.Context, subscription *database.WebhookSubscription, event *events.Event, payload []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, fmt.Errorf("creating request: %s", err.Error())
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "socialite-webhook")
+	req.Header.Set(Header_Event, string(event.Type))
+	req.Header.Set(Header_Delivery, event.Id)
+	req.Header.Set(Header_Timestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(Header_Signature, Sign(subscription.Secret, timestamp, payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("posting event: %s", err.Error())
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// retryDelay doubles the backoff after each failed attempt, up to the max backoff
+func (d *Dispatcher) retryDelay(attempt int) time.Duration {
+	delay := d.backoff
+	for i := 2; i < attempt && delay < d.maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, d.maxBackoff)
+}
+
+// retryable tells if a failed attempt may succeed later, other client errors never will
+func retryable(statusCode int) bool {
+	if statusCode == 0 || statusCode >= 500 {
+		return true
+	}
+	return statusCode == http.StatusRequestTimeout || statusCode == http.StatusTooManyRequests
+}
+
+// Sign returns the signature header value, an hmac sha256 of the timestamp and the payload joined by a dot
+func Sign(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature header value in constant time, for receivers written in go
+func Verify(secret string, timestamp int64, payload []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, payload)), []byte(signature))
+}
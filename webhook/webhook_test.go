@@ -0,0 +1,210 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"socialite/config"
+	"socialite/database"
+	"socialite/events"
+)
+
+// memoryStore keeps the deliveries and dead letters in memory
+type memoryStore struct {
+	mutex         sync.Mutex
+	subscriptions []*database.WebhookSubscription
+	deliveries    []*database.WebhookDelivery
+	deadLetters   []*database.WebhookDeadLetter
+}
+
+func (s *memoryStore) GetWebhookSubscriptionsForEvent(ctx context.Context, eventType string) ([]*database.WebhookSubscription, error) {
+	return s.subscriptions, nil
+}
+
+func (s *memoryStore) PutWebhookDelivery(ctx context.Context, delivery *database.WebhookDelivery) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.deliveries = append(s.deliveries, delivery)
+	return nil
+}
+
+func (s *memoryStore) IsWebhookDelivered(ctx context.Context, subscriptionId int32, eventId string) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, eachDelivery := range s.deliveries {
+		if eachDelivery.SubscriptionId == subscriptionId && eachDelivery.EventId == eventId && eachDelivery.Error == "" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *memoryStore) PutWebhookDeadLetter(ctx context.Context, deadLetter *database.WebhookDeadLetter) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.deadLetters = append(s.deadLetters, deadLetter)
+	return nil
+}
+
+// newTestDispatcher retries right away so tests do not wait on the backoff
+func newTestDispatcher(store Store, maxAttempts int) *Dispatcher {
+	dispatcher := New(store, &config.WebhookConfig{Timeout: 1, MaxAttempts: maxAttempts, RetryBackoff: 1, RetryMaxBackoff: 1})
+	dispatcher.backoff = time.Millisecond
+	dispatcher.maxBackoff = time.Millisecond
+	return dispatcher
+}
+
+func TestDeliverRetriesUntilAccepted(t *testing.T) {
+	secret := "0123456789abcdef"
+	var mutex sync.Mutex
+	received := 0
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(Header_Timestamp), 10, 64)
+		if !Verify(secret, timestamp, payload, r.Header.Get(Header_Signature)) {
+			t.Error("signature does not verify")
+		}
+		if r.Header.Get(Header_Event) != string(events.Type_FriendRequestSent) {
+			t.Errorf("event header is %q", r.Header.Get(Header_Event))
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		received++
+		if received < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	store := &memoryStore{
+		subscriptions: []*database.WebhookSubscription{{Id: 1, Url: receiver.URL, Secret: secret, Active: true}},
+	}
+	dispatcher := newTestDispatcher(store, 5)
+	dispatcher.Dispatch(context.Background(), events.New(events.Type_FriendRequestSent, "user1"))
+	dispatcher.Wait()
+
+	if len(store.deliveries) != 3 {
+		t.Errorf("logged %d deliveries, expected 3", len(store.deliveries))
+	}
+	if len(store.deadLetters) != 0 {
+		t.Errorf("accepted event is a dead letter")
+	}
+}
+
+func TestDeliverSkipsDeliveredEvent(t *testing.T) {
+	var mutex sync.Mutex
+	received := 0
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		received++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	store := &memoryStore{
+		subscriptions: []*database.WebhookSubscription{{Id: 1, Url: receiver.URL, Secret: "0123456789abcdef", Active: true}},
+	}
+	dispatcher := newTestDispatcher(store, 3)
+	// the outbox publishes an event again when it fails to mark it published
+	event := events.New(events.Type_FriendRemoved, "user1")
+	dispatcher.Dispatch(context.Background(), event)
+	dispatcher.Wait()
+	dispatcher.Dispatch(context.Background(), event)
+	dispatcher.Wait()
+
+	if received != 1 {
+		t.Errorf("event posted %d times, expected once", received)
+	}
+}
+
+func TestDeliverDeadLetter(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	store := &memoryStore{
+		subscriptions: []*database.WebhookSubscription{{Id: 1, Url: receiver.URL, Secret: "0123456789abcdef", Active: true}},
+	}
+	dispatcher := newTestDispatcher(store, 3)
+	dispatcher.Dispatch(context.Background(), events.New(events.Type_PartyCreated, "user1"))
+	dispatcher.Wait()
+
+	if len(store.deliveries) != 3 {
+		t.Errorf("logged %d deliveries, expected 3", len(store.deliveries))
+	}
+	if len(store.deadLetters) != 1 {
+		t.Fatalf("%d dead letters, expected 1", len(store.deadLetters))
+	}
+	if store.deadLetters[0].Attempts != 3 {
+		t.Errorf("dead letter has %d attempts, expected 3", store.deadLetters[0].Attempts)
+	}
+}
+
+func TestDeliverStoppedKeepsDeadLetter(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	store := &memoryStore{
+		subscriptions: []*database.WebhookSubscription{{Id: 1, Url: receiver.URL, Secret: "0123456789abcdef", Active: true}},
+	}
+	dispatcher := newTestDispatcher(store, 5)
+	dispatcher.backoff = time.Minute
+	dispatcher.maxBackoff = time.Minute
+	ctx, cancel := context.WithCancel(context.Background())
+	dispatcher.Dispatch(ctx, events.New(events.Type_PartyCreated, "user1"))
+	// stopped while waiting to retry, like on shutdown
+	time.Sleep(time.Millisecond * 50)
+	cancel()
+	dispatcher.Wait()
+
+	if len(store.deliveries) != 1 {
+		t.Errorf("logged %d deliveries, expected 1", len(store.deliveries))
+	}
+	if len(store.deadLetters) != 1 || store.deadLetters[0].Attempts != 1 {
+		t.Errorf("stopped delivery is not a dead letter after one attempt")
+	}
+}
+
+func TestDeliverClientErrorIsNotRetried(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer receiver.Close()
+
+	store := &memoryStore{
+		subscriptions: []*database.WebhookSubscription{{Id: 1, Url: receiver.URL, Secret: "0123456789abcdef", Active: true}},
+	}
+	dispatcher := newTestDispatcher(store, 5)
+	dispatcher.Dispatch(context.Background(), events.New(events.Type_PartyCreated, "user1"))
+	dispatcher.Wait()
+
+	if len(store.deliveries) != 1 {
+		t.Errorf("logged %d deliveries, expected 1", len(store.deliveries))
+	}
+	if len(store.deadLetters) != 1 || store.deadLetters[0].Attempts != 1 {
+		t.Errorf("client error is not a dead letter after one attempt")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	dispatcher := New(&memoryStore{}, &config.WebhookConfig{Timeout: 1, MaxAttempts: 10, RetryBackoff: 1, RetryMaxBackoff: 5})
+	expected := map[int]time.Duration{2: time.Second, 3: 2 * time.Second, 4: 4 * time.Second, 5: 5 * time.Second, 9: 5 * time.Second}
+	for attempt, delay := range expected {
+		if got := dispatcher.retryDelay(attempt); got != delay {
+			t.Errorf("delay before attempt %d is %s, expected %s", attempt, got, delay)
+		}
+	}
+}